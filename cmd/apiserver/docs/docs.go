@@ -15,6 +15,68 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/diff": {
+            "get": {
+                "description": "Compare the scores of two rounds: new and removed links, the largest score and rank changes\nwith the metric which caused each of them, and anomalies, metrics of a link which went to zero,\ndropped by dropShare or more, or grew by riseFactor or more. Anomalies are usually collection bugs.\nupdated_since is never an anomaly. Every list has at most top links.\nNOTE: Both rounds are read in full, this is slow for large rounds",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Compare two rounds",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Old round, default the round before to",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "New round, default the latest round",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Score series: default, ossf or trending",
+                        "name": "model",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Number of links in each list",
+                        "name": "top",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "default": 0.9,
+                        "description": "Share of a drop which is an anomaly",
+                        "name": "dropShare",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "default": 10,
+                        "description": "Growth factor which is an anomaly, 0 disables it",
+                        "name": "riseFactor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.RoundDiffDTO"
+                        }
+                    }
+                }
+            }
+        },
         "/histories": {
             "get": {
                 "description": "Get score histories by git link",
@@ -44,6 +106,13 @@ const docTemplate = `{
                         "description": "Take count",
                         "name": "take",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Score series: default, ossf or trending",
+                        "name": "model",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -109,7 +178,7 @@ const docTemplate = `{
         },
         "/rankings": {
             "get": {
-                "description": "Get ranking results, optionally including all details",
+                "description": "Get ranking results, optionally including all details\nConfidence is the share of the inputs of a score which are present and fresh, in [0, 1],\nuse minConfidence to hide scores based on little data. Rankings are not renumbered.\nmodel picks the score series: default is the current scoring model, ossf the original\nOpenSSF criticality score formula with scores in [0, 1], trending ranks links by how fast\ntheir contributors, distribution and language ecosystem dependents grow.",
                 "consumes": [
                     "application/json"
                 ],
@@ -135,6 +204,19 @@ const docTemplate = `{
                         "description": "Include details",
                         "name": "detail",
                         "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum confidence",
+                        "name": "minConfidence",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Score series: default, ossf or trending",
+                        "name": "model",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -176,6 +258,13 @@ const docTemplate = `{
                         "description": "Take count",
                         "name": "take",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "default",
+                        "description": "Score series: default, ossf or trending",
+                        "name": "model",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -190,7 +279,7 @@ const docTemplate = `{
         },
         "/results/{scoreid}": {
             "get": {
-                "description": "Get score results, including all details by scoreid",
+                "description": "Get score results, including all details by scoreid\nThe breakdown lists the normalized value, weight and contribution of every metric,\nan empty metric means the contribution of the whole category to the score.\nEvery dist detail has the weight of the distribution and what it added to the dist impact and pagerank",
                 "consumes": [
                     "application/json"
                 ],
@@ -326,6 +415,89 @@ const docTemplate = `{
                 }
             }
         },
+        "model.AnomalyDTO": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "metric": {
+                    "type": "string"
+                },
+                "newContribution": {
+                    "type": "number"
+                },
+                "newValue": {
+                    "type": "number"
+                },
+                "oldContribution": {
+                    "type": "number"
+                },
+                "oldValue": {
+                    "type": "number"
+                }
+            }
+        },
+        "model.LinkChangeDTO": {
+            "type": "object",
+            "properties": {
+                "cause": {
+                    "description": "Metric which changed the contribution to the score the most, null if\neither score has no breakdown",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.MetricChangeDTO"
+                        }
+                    ]
+                },
+                "link": {
+                    "type": "string"
+                },
+                "newRank": {
+                    "type": "integer"
+                },
+                "newScore": {
+                    "type": "number"
+                },
+                "oldRank": {
+                    "type": "integer"
+                },
+                "oldScore": {
+                    "type": "number"
+                }
+            }
+        },
+        "model.MetricChangeDTO": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "metric": {
+                    "type": "string"
+                },
+                "newContribution": {
+                    "type": "number"
+                },
+                "newValue": {
+                    "type": "number"
+                },
+                "oldContribution": {
+                    "type": "number"
+                },
+                "oldValue": {
+                    "type": "number"
+                }
+            }
+        },
         "model.PageDTO-model_RankingResultDTO": {
             "type": "object",
             "properties": {
@@ -369,6 +541,16 @@ const docTemplate = `{
         "model.RankingResultDTO": {
             "type": "object",
             "properties": {
+                "breakdown": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ResultBreakdownDTO"
+                    }
+                },
+                "confidence": {
+                    "description": "Share of the inputs present and fresh, in [0, 1], null for scores\ncalculated before confidence was recorded",
+                    "type": "number"
+                },
                 "distDetail": {
                     "type": "array",
                     "items": {
@@ -399,6 +581,10 @@ const docTemplate = `{
                 "link": {
                     "type": "string"
                 },
+                "model": {
+                    "description": "Score series the score belongs to, such as default or ossf",
+                    "type": "string"
+                },
                 "ranking": {
                     "type": "integer"
                 },
@@ -413,9 +599,42 @@ const docTemplate = `{
                 }
             }
         },
+        "model.ResultBreakdownDTO": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "contribution": {
+                    "type": "number"
+                },
+                "metric": {
+                    "type": "string"
+                },
+                "normalized": {
+                    "type": "number"
+                },
+                "value": {
+                    "type": "number"
+                },
+                "weight": {
+                    "type": "number"
+                }
+            }
+        },
         "model.ResultDTO": {
             "type": "object",
             "properties": {
+                "breakdown": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ResultBreakdownDTO"
+                    }
+                },
+                "confidence": {
+                    "description": "Share of the inputs present and fresh, in [0, 1], null for scores\ncalculated before confidence was recorded",
+                    "type": "number"
+                },
                 "distDetail": {
                     "type": "array",
                     "items": {
@@ -446,6 +665,10 @@ const docTemplate = `{
                 "link": {
                     "type": "string"
                 },
+                "model": {
+                    "description": "Score series the score belongs to, such as default or ossf",
+                    "type": "string"
+                },
                 "score": {
                     "type": "number"
                 },
@@ -463,17 +686,35 @@ const docTemplate = `{
                 "count": {
                     "type": "integer"
                 },
+                "defaultInstall": {
+                    "description": "Whether a package of the link is installed by default in the\ndistribution, null if not collected",
+                    "type": "boolean"
+                },
                 "impact": {
                     "type": "number"
                 },
+                "impactContribution": {
+                    "type": "number"
+                },
+                "kind": {
+                    "description": "Kind of the dependencies: runtime, build, test or optional",
+                    "type": "string"
+                },
                 "pageRank": {
                     "type": "number"
                 },
+                "pageRankContribution": {
+                    "type": "number"
+                },
                 "type": {
                     "type": "integer"
                 },
                 "updateTime": {
                     "type": "string"
+                },
+                "weight": {
+                    "description": "Weight of the distribution and what it added to the dist impact and\npagerank of the score, null for scores calculated before weights were\nrecorded",
+                    "type": "number"
                 }
             }
         },
@@ -528,6 +769,65 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "model.RoundDiffDTO": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.LinkChangeDTO"
+                    }
+                },
+                "addedCount": {
+                    "type": "integer"
+                },
+                "anomalies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AnomalyDTO"
+                    }
+                },
+                "anomalyCount": {
+                    "type": "integer"
+                },
+                "kendallTau": {
+                    "type": "number"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "newRound": {
+                    "type": "integer"
+                },
+                "oldRound": {
+                    "type": "integer"
+                },
+                "rankChanges": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.LinkChangeDTO"
+                    }
+                },
+                "removed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.LinkChangeDTO"
+                    }
+                },
+                "removedCount": {
+                    "type": "integer"
+                },
+                "scoreChanges": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.LinkChangeDTO"
+                    }
+                },
+                "spearman": {
+                    "type": "number"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"slices"
+
+	"github.com/HUSTSecLab/criticality_score/cmd/apiserver/internal/model"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/score"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Compare two rounds
+// @Description Compare the scores of two rounds: new and removed links, the largest score and rank changes
+// @Description with the metric which caused each of them, and anomalies, metrics of a link which went to zero,
+// @Description dropped by dropShare or more, or grew by riseFactor or more. Anomalies are usually collection bugs.
+// @Description updated_since is never an anomaly. Every list has at most top links.
+// @Description NOTE: Both rounds are read in full, this is slow for large rounds
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.RoundDiffDTO
+// @Router /diff [get]
+// @Param from query int false "Old round, default the round before to"
+// @Param to query int false "New round, default the latest round"
+// @Param model query string false "Score series: default, ossf or trending" default(default)
+// @Param top query int false "Number of links in each list" default(50)
+// @Param dropShare query number false "Share of a drop which is an anomaly" default(0.9)
+// @Param riseFactor query number false "Growth factor which is an anomaly, 0 disables it" default(10)
+func diffHandler(c *gin.Context) {
+	ac := storage.GetDefaultAppDatabaseContext()
+	defaults := score.DefaultDiffOptions()
+
+	type query struct {
+		From       int     `form:"from"`
+		To         int     `form:"to"`
+		Model      string  `form:"model"`
+		Top        int     `form:"top"`
+		DropShare  float64 `form:"dropShare"`
+		RiseFactor float64 `form:"riseFactor"`
+	}
+
+	var q query = query{
+		Model:      score.SeriesDefault,
+		Top:        defaults.Top,
+		DropShare:  defaults.DropShare,
+		RiseFactor: defaults.RiseFactor,
+	}
+
+	if err := c.ShouldBindQuery(&q); err != nil || !slices.Contains(score.AllSeries, q.Model) {
+		c.JSON(400, "Invalid query parameters")
+		return
+	}
+
+	if q.Top > 1000 {
+		q.Top = 1000
+	}
+
+	if q.To == 0 {
+		round, err := repository.NewScoreRepository(ac).GetRound()
+		if err != nil {
+			logger.Error("Error occurred when querying round", err)
+			c.JSON(500, "Error occurred when querying round")
+			return
+		}
+		q.To = round
+	}
+	if q.From == 0 {
+		q.From = q.To - 1
+	}
+
+	diff, err := score.DiffRounds(ac, q.Model, q.From, q.To, score.DiffOptions{
+		Top:           q.Top,
+		DropShare:     q.DropShare,
+		RiseFactor:    q.RiseFactor,
+		IgnoreMetrics: defaults.IgnoreMetrics,
+	})
+	if err != nil {
+		logger.Error("Error occurred when comparing rounds", err)
+		c.JSON(500, "Error occurred when comparing rounds")
+		return
+	}
+
+	c.JSON(200, model.RoundDiffToDTO(diff))
+}
+
+func registDiff(e gin.IRouter) {
+	e.GET("/diff", diffHandler)
+}
@@ -4,4 +4,5 @@ import "github.com/gin-gonic/gin"
 
 func Regist(e gin.IRouter) {
 	registResult(e)
+	registDiff(e)
 }
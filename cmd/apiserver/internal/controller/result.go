@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"iter"
 	"net/http"
 	"slices"
 	"strconv"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/HUSTSecLab/criticality_score/cmd/apiserver/internal/model"
 	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/score"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
@@ -26,6 +28,7 @@ import (
 // @Param q query string true "Search query"
 // @Param start query int false "Skip count"
 // @Param take query int false "Take count"
+// @Param model query string false "Score series: default, ossf or trending" default(default)
 func resultsHandler(c *gin.Context) {
 	r := repository.NewResultRepository(storage.GetDefaultAppDatabaseContext())
 
@@ -33,14 +36,16 @@ func resultsHandler(c *gin.Context) {
 		Search string `form:"q"`
 		Skip   int    `form:"start"`
 		Take   int    `form:"take"`
+		Model  string `form:"model"`
 	}
 
 	var q query = query{
-		Skip: 0,
-		Take: 100,
+		Skip:  0,
+		Take:  100,
+		Model: score.SeriesDefault,
 	}
 
-	if err := c.ShouldBindQuery(&q); err != nil {
+	if err := c.ShouldBindQuery(&q); err != nil || !slices.Contains(score.AllSeries, q.Model) {
 		c.JSON(400, "Invalid query parameters")
 		return
 	}
@@ -60,7 +65,7 @@ func resultsHandler(c *gin.Context) {
 		return
 	}
 
-	result, err := r.QueryByLink(q.Search, q.Skip, q.Take)
+	result, err := r.QueryByLink(q.Model, q.Search, q.Skip, q.Take)
 	if err != nil {
 		c.JSON(500, "Error occurred when querying results")
 		return
@@ -84,30 +89,34 @@ func resultsHandler(c *gin.Context) {
 // @Param link query string true "Git link"
 // @Param start query int false "Skip count"
 // @Param take query int false "Take count"
+// @Param model query string false "Score series: default, ossf or trending" default(default)
 func historiesHandler(c *gin.Context) {
 	r := repository.NewResultRepository(storage.GetDefaultAppDatabaseContext())
 
 	type query struct {
-		Link string `form:"link"`
-		Skip int    `form:"start"`
-		Take int    `form:"take"`
+		Link  string `form:"link"`
+		Skip  int    `form:"start"`
+		Take  int    `form:"take"`
+		Model string `form:"model"`
 	}
 
-	var q query
+	var q query = query{
+		Model: score.SeriesDefault,
+	}
 
-	if err := c.ShouldBindQuery(&q); err != nil {
+	if err := c.ShouldBindQuery(&q); err != nil || !slices.Contains(score.AllSeries, q.Model) {
 		c.JSON(400, "Invalid query parameters")
 		return
 	}
 
-	cnt, err := r.CountHistoriesByLink(q.Link)
+	cnt, err := r.CountHistoriesByLink(q.Model, q.Link)
 	if err != nil {
 		logger.Error("Error occurred when counting histories", err)
 		c.JSON(500, "Error occurred when counting histories")
 		return
 	}
 
-	histories, err := r.QueryHistoriesByLink(q.Link, q.Skip, q.Take)
+	histories, err := r.QueryHistoriesByLink(q.Model, q.Link, q.Skip, q.Take)
 
 	if err != nil {
 		logger.Error("Error occurred when querying histories", err)
@@ -126,6 +135,9 @@ func historiesHandler(c *gin.Context) {
 
 // @Summary Get score results
 // @Description Get score results, including all details by scoreid
+// @Description The breakdown lists the normalized value, weight and contribution of every metric,
+// @Description an empty metric means the contribution of the whole category to the score.
+// @Description Every dist detail has the weight of the distribution and what it added to the dist impact and pagerank
 // @Accept json
 // @Produce json
 // @Success 200 {object} model.ResultDTO
@@ -170,6 +182,13 @@ func resultHandler(c *gin.Context) {
 		return
 	}
 
+	breakdown, err := r.QueryBreakdownByScoreID(scoreid)
+	if err != nil {
+		logger.Error("Error occurred when querying score breakdown", err)
+		c.JSON(500, "Error occurred when querying score breakdown")
+		return
+	}
+
 	ret := model.ResultDOToDTO(result)
 	ret.GitDetail = lo.Map(slices.Collect(gitDetails), func(v *repository.ResultGitDetail, i int) model.ResultGitMetadataDTO {
 		return *model.ResultGitDetailDOToDTO(v)
@@ -180,12 +199,20 @@ func resultHandler(c *gin.Context) {
 	ret.DistDetail = lo.Map(slices.Collect(distDetails), func(v *repository.ResultDistDetail, i int) model.ResultDistDetailDTO {
 		return *model.ResultDistDetailDOToDTO(v)
 	})
+	ret.Breakdown = lo.Map(slices.Collect(breakdown), func(v *repository.ScoreBreakdown, i int) model.ResultBreakdownDTO {
+		return *model.ResultBreakdownDOToDTO(v)
+	})
 
 	c.JSON(200, ret)
 }
 
 // @Summary Get ranking results
 // @Description Get ranking results, optionally including all details
+// @Description Confidence is the share of the inputs of a score which are present and fresh, in [0, 1],
+// @Description use minConfidence to hide scores based on little data. Rankings are not renumbered.
+// @Description model picks the score series: default is the current scoring model, ossf the original
+// @Description OpenSSF criticality score formula with scores in [0, 1], trending ranks links by how fast
+// @Description their contributors, distribution and language ecosystem dependents grow.
 // @Accept json
 // @Produce json
 // @Success 200 {object} model.PageDTO[model.RankingResultDTO]
@@ -193,21 +220,26 @@ func resultHandler(c *gin.Context) {
 // @Param start query int false "Skip count"
 // @Param take query int false "Take count"
 // @Param detail query bool false "Include details"
+// @Param minConfidence query number false "Minimum confidence"
+// @Param model query string false "Score series: default, ossf or trending" default(default)
 func rankingHandler(c *gin.Context) {
 	r := repository.NewResultRepository(storage.GetDefaultAppDatabaseContext())
 	type query struct {
-		Skip   int  `form:"start"`
-		Take   int  `form:"take"`
-		Detail bool `form:"detail"`
+		Skip          int      `form:"start"`
+		Take          int      `form:"take"`
+		Detail        bool     `form:"detail"`
+		MinConfidence *float64 `form:"minConfidence"`
+		Model         string   `form:"model"`
 	}
 
 	var q query = query{
 		Skip:   0,
 		Take:   100,
 		Detail: false,
+		Model:  score.SeriesDefault,
 	}
 
-	if err := c.ShouldBindQuery(&q); err != nil {
+	if err := c.ShouldBindQuery(&q); err != nil || !slices.Contains(score.AllSeries, q.Model) {
 		c.JSON(400, "Invalid query parameters")
 		return
 	}
@@ -216,7 +248,13 @@ func rankingHandler(c *gin.Context) {
 		q.Take = 1000
 	}
 
-	rankingCache, err := r.QueryRankingCache(q.Skip, q.Take)
+	var rankingCache iter.Seq[*repository.RankingResult]
+	var err error
+	if q.MinConfidence != nil {
+		rankingCache, err = r.QueryRankingCacheByConfidence(q.Model, *q.MinConfidence, q.Skip, q.Take)
+	} else {
+		rankingCache, err = r.QueryRankingCache(q.Model, q.Skip, q.Take)
+	}
 
 	if err != nil {
 		logger.Error("Error occurred when querying ranking cache", err)
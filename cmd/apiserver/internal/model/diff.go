@@ -0,0 +1,105 @@
+package model
+
+import (
+	scores "github.com/HUSTSecLab/criticality_score/pkg/score"
+	"github.com/samber/lo"
+)
+
+type MetricChangeDTO struct {
+	Category        string  `json:"category"`
+	Metric          string  `json:"metric"`
+	OldValue        float64 `json:"oldValue"`
+	NewValue        float64 `json:"newValue"`
+	OldContribution float64 `json:"oldContribution"`
+	NewContribution float64 `json:"newContribution"`
+}
+
+type LinkChangeDTO struct {
+	GitLink  string  `json:"link"`
+	OldRank  int     `json:"oldRank"`
+	NewRank  int     `json:"newRank"`
+	OldScore float64 `json:"oldScore"`
+	NewScore float64 `json:"newScore"`
+	// Metric which changed the contribution to the score the most, null if
+	// either score has no breakdown
+	Cause *MetricChangeDTO `json:"cause"`
+}
+
+type AnomalyDTO struct {
+	GitLink     string `json:"link"`
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	MetricChangeDTO
+}
+
+type RoundDiffDTO struct {
+	Model        string          `json:"model"`
+	OldRound     int             `json:"oldRound"`
+	NewRound     int             `json:"newRound"`
+	Added        []LinkChangeDTO `json:"added"`
+	AddedCount   int             `json:"addedCount"`
+	Removed      []LinkChangeDTO `json:"removed"`
+	RemovedCount int             `json:"removedCount"`
+	ScoreChanges []LinkChangeDTO `json:"scoreChanges"`
+	RankChanges  []LinkChangeDTO `json:"rankChanges"`
+	Anomalies    []AnomalyDTO    `json:"anomalies"`
+	AnomalyCount int             `json:"anomalyCount"`
+	KendallTau   float64         `json:"kendallTau"`
+	Spearman     float64         `json:"spearman"`
+}
+
+func MetricChangeToDTO(c *scores.MetricChange) *MetricChangeDTO {
+	if c == nil {
+		return nil
+	}
+	return &MetricChangeDTO{
+		Category:        c.Category,
+		Metric:          c.Metric,
+		OldValue:        c.OldValue,
+		NewValue:        c.NewValue,
+		OldContribution: c.OldContribution,
+		NewContribution: c.NewContribution,
+	}
+}
+
+func RankChangeToDTO(c *scores.RankChange, cause *scores.MetricChange) *LinkChangeDTO {
+	return &LinkChangeDTO{
+		GitLink:  c.Link,
+		OldRank:  c.OldRank,
+		NewRank:  c.NewRank,
+		OldScore: c.OldScore,
+		NewScore: c.NewScore,
+		Cause:    MetricChangeToDTO(cause),
+	}
+}
+
+func RoundDiffToDTO(d *scores.RoundDiff) *RoundDiffDTO {
+	rankChange := func(c *scores.RankChange, _ int) LinkChangeDTO {
+		return *RankChangeToDTO(c, nil)
+	}
+	linkChange := func(c *scores.LinkChange, _ int) LinkChangeDTO {
+		return *RankChangeToDTO(c.RankChange, c.Cause)
+	}
+	return &RoundDiffDTO{
+		Model:        d.Series,
+		OldRound:     d.OldRound,
+		NewRound:     d.NewRound,
+		Added:        lo.Map(d.Added, rankChange),
+		AddedCount:   d.AddedCount,
+		Removed:      lo.Map(d.Removed, rankChange),
+		RemovedCount: d.RemovedCount,
+		ScoreChanges: lo.Map(d.ScoreChanges, linkChange),
+		RankChanges:  lo.Map(d.RankChanges, linkChange),
+		Anomalies: lo.Map(d.Anomalies, func(a *scores.Anomaly, _ int) AnomalyDTO {
+			return AnomalyDTO{
+				GitLink:         a.Link,
+				Kind:            a.Kind,
+				Description:     a.Describe(),
+				MetricChangeDTO: *MetricChangeToDTO(a.MetricChange),
+			}
+		}),
+		AnomalyCount: d.AnomalyCount,
+		KendallTau:   d.KendallTau,
+		Spearman:     d.Spearman,
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
 )
 
 type ResultGitMetadataDTO struct {
@@ -30,6 +31,26 @@ type ResultDistDetailDTO struct {
 	Impact     *float64   `json:"impact"`
 	PageRank   *float64   `json:"pageRank"`
 	UpdateTime *time.Time `json:"updateTime"`
+	// Weight of the distribution and what it added to the dist impact and
+	// pagerank of the score, null for scores calculated before weights were
+	// recorded
+	Weight               *float64 `json:"weight"`
+	ImpactContribution   *float64 `json:"impactContribution"`
+	PageRankContribution *float64 `json:"pageRankContribution"`
+	// Whether a package of the link is installed by default in the
+	// distribution, null if not collected
+	DefaultInstall *bool `json:"defaultInstall"`
+	// Kind of the dependencies: runtime, build, test or optional
+	Kind *string `json:"kind"`
+}
+
+type ResultBreakdownDTO struct {
+	Category     string   `json:"category"`
+	Metric       string   `json:"metric"`
+	Value        *float64 `json:"value"`
+	Normalized   *float64 `json:"normalized"`
+	Weight       *float64 `json:"weight"`
+	Contribution *float64 `json:"contribution"`
 }
 
 type ResultDTO struct {
@@ -39,10 +60,16 @@ type ResultDTO struct {
 	GitDetail   []ResultGitMetadataDTO `json:"gitDetail"`
 	LangDetail  []ResultLangDetailDTO  `json:"langDetail"`
 	DistDetail  []ResultDistDetailDTO  `json:"distDetail"`
+	Breakdown   []ResultBreakdownDTO   `json:"breakdown"`
 	DistroScore *float64               `json:"distroScore"`
 	LangScore   *float64               `json:"langScore"`
 	Score       *float64               `json:"score"`
 	UpdateTime  *time.Time             `json:"updateTime"`
+	// Share of the inputs present and fresh, in [0, 1], null for scores
+	// calculated before confidence was recorded
+	Confidence *float64 `json:"confidence"`
+	// Score series the score belongs to, such as default or ossf
+	Model *string `json:"model"`
 }
 
 type RankingResultDTO struct {
@@ -59,6 +86,8 @@ func ResultDOToDTO(r *repository.Result) *ResultDTO {
 		LangScore:   *r.LangScore,
 		Score:       *r.Score,
 		UpdateTime:  *r.UpdateTime,
+		Confidence:  lo.FromPtr(r.Confidence),
+		Model:       lo.FromPtr(r.Series),
 	}
 }
 
@@ -91,6 +120,23 @@ func ResultDistDetailDOToDTO(r *repository.ResultDistDetail) *ResultDistDetailDT
 		Impact:     *r.Impact,
 		PageRank:   *r.PageRank,
 		UpdateTime: *r.UpdateTime,
+
+		Weight:               *r.Weight,
+		ImpactContribution:   *r.ImpactContribution,
+		PageRankContribution: *r.PageRankContribution,
+		DefaultInstall:       *r.DefaultInstall,
+		Kind:                 *r.Kind,
+	}
+}
+
+func ResultBreakdownDOToDTO(r *repository.ScoreBreakdown) *ResultBreakdownDTO {
+	return &ResultBreakdownDTO{
+		Category:     *r.Category,
+		Metric:       *r.Metric,
+		Value:        r.Value,
+		Normalized:   r.Normalized,
+		Weight:       r.Weight,
+		Contribution: r.Contribution,
 	}
 }
 
@@ -104,6 +150,8 @@ func RankingDOToDTO(r *repository.RankingResult) *RankingResultDTO {
 			LangScore:  r.LangScore,
 			Score:      r.Score,
 			UpdateTime: r.UpdateTime,
+			Confidence: r.Confidence,
+			Series:     r.Series,
 		}),
 		Ranking: *r.Ranking,
 	}
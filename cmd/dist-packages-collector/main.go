@@ -1,109 +1,133 @@
-package main
-
-import (
-	"sync"
-
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/alpine"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/archlinux"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/aur"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/centos"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/debian"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/deepin"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/fedora"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/gentoo"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/homebrew"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/nix"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/ubuntu"
-	"github.com/HUSTSecLab/criticality_score/pkg/config"
-	"github.com/spf13/pflag"
-)
-
-var (
-	flagType    = pflag.String("type", "", "type of the distribution")
-	flagGenDot  = pflag.String("gendot", "", "output dot file")
-	workerCount = pflag.Int("worker", 1, "number of workers")
-	batchSize   = pflag.Int("batch", 1000, "batch size")
-	downloadDir = pflag.String("downloadDir", "./download", "download directory")
-)
-
-func main() {
-	config.RegistCommonFlags(pflag.CommandLine)
-	config.ParseFlags(pflag.CommandLine)
-
-	if *flagType == "" {
-		var wg sync.WaitGroup
-		wg.Add(11)
-
-		go func() {
-			defer wg.Done()
-			archlinux.NewArchLinuxCollector().Collect(*flagGenDot)
-		}()
-		go func() {
-			defer wg.Done()
-			debian.NewDebianCollector().Collect(*flagGenDot)
-		}()
-		go func() {
-			defer wg.Done()
-			deepin.NewDeepinCollector().Collect(*flagGenDot)
-		}()
-		go func() {
-			defer wg.Done()
-			ubuntu.NewUbuntuCollector().Collect(*flagGenDot)
-		}()
-		// go func() {
-		// 	defer wg.Done()
-		// 	nix.NewNixCollector().Collect(*workerCount, *batchSize, *flagGenDot)
-		// }()
-		go func() {
-			defer wg.Done()
-			homebrew.NewHomebrewCollector().Collect(*flagGenDot, *downloadDir)
-		}()
-		// go func() {
-		// 	defer wg.Done()
-		// 	gentoo.NewGentooCollector().Collect(*flagGenDot)
-		// }()
-		go func() {
-			defer wg.Done()
-			fedora.NewFedoraCollector().Collect(*flagGenDot)
-		}()
-		go func() {
-			defer wg.Done()
-			centos.NewCentosCollector().Collect(*flagGenDot)
-		}()
-		go func() {
-			defer wg.Done()
-			alpine.NewAlpineCollector().Collect(*flagGenDot)
-		}()
-		go func() {
-			defer wg.Done()
-			aur.NewAurCollector().Collect(*flagGenDot)
-		}()
-
-		wg.Wait()
-	} else {
-		switch *flagType {
-		case "archlinux":
-			archlinux.NewArchLinuxCollector().Collect(*flagGenDot)
-		case "debian":
-			debian.NewDebianCollector().Collect(*flagGenDot)
-		case "deepin":
-			deepin.NewDeepinCollector().Collect(*flagGenDot)
-		case "ubuntu":
-			ubuntu.NewUbuntuCollector().Collect(*flagGenDot)
-		case "nix":
-			nix.NewNixCollector().Collect(*workerCount, *batchSize, *flagGenDot)
-		case "homebrew":
-			homebrew.NewHomebrewCollector().Collect(*flagGenDot, *downloadDir)
-		case "gentoo":
-			gentoo.NewGentooCollector().Collect(*flagGenDot, *downloadDir)
-		case "fedora":
-			fedora.NewFedoraCollector().Collect(*flagGenDot)
-		case "centos":
-			centos.NewCentosCollector().Collect(*flagGenDot)
-		case "alpine":
-			alpine.NewAlpineCollector().Collect(*flagGenDot)
-		case "aur":
-			aur.NewAurCollector().Collect(*flagGenDot)
-		}
-	}
-}
+package main
+
+import (
+	"sync"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/alpine"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/archlinux"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/aur"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/centos"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/debian"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/deepin"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/fedora"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/gentoo"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/homebrew"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/nix"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/rpm"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/ubuntu"
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagType    = pflag.String("type", "", "type of the distribution")
+	flagGenDot  = pflag.String("gendot", "", "output file of the dependency graph")
+	workerCount = pflag.Int("worker", 1, "number of workers")
+	batchSize   = pflag.Int("batch", 1000, "batch size")
+	downloadDir = pflag.String("downloadDir", "./download", "download directory")
+	flagMirror  = pflag.String("mirror", "", "mirror of the distribution given by --type: base URL, file:// URL or local directory, overrides sources.<type>.mirror of the config file")
+	// by the extension of --gendot if not set
+	flagGraphFormat = pflag.String("graph-format", "", "format of the dependency graph: dot, graphml, gexf or json")
+)
+
+func main() {
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+
+	if *flagMirror != "" && *flagType == "" {
+		logger.Fatalf("--mirror requires --type")
+	}
+
+	graphFormat := graph.FormatOf(*flagGenDot)
+	if *flagGraphFormat != "" {
+		var err error
+		if graphFormat, err = graph.ParseFormat(*flagGraphFormat); err != nil {
+			logger.Fatalf("%v", err)
+		}
+	}
+
+	if *flagType == "" {
+		var wg sync.WaitGroup
+		wg.Add(7)
+
+		go func() {
+			defer wg.Done()
+			archlinux.NewArchLinuxCollector("").Collect(*flagGenDot, graphFormat)
+		}()
+		go func() {
+			defer wg.Done()
+			debian.NewDebianCollector("").Collect(*flagGenDot, graphFormat)
+		}()
+		go func() {
+			defer wg.Done()
+			deepin.NewDeepinCollector("").Collect(*flagGenDot, graphFormat)
+		}()
+		go func() {
+			defer wg.Done()
+			ubuntu.NewUbuntuCollector("").Collect(*flagGenDot, graphFormat)
+		}()
+		// go func() {
+		// 	defer wg.Done()
+		// 	nix.NewNixCollector().Collect(*workerCount, *batchSize, *flagGenDot, graphFormat)
+		// }()
+		go func() {
+			defer wg.Done()
+			homebrew.NewHomebrewCollector("").Collect(*flagGenDot, graphFormat, *downloadDir)
+		}()
+		// go func() {
+		// 	defer wg.Done()
+		// 	gentoo.NewGentooCollector("").Collect(*flagGenDot, graphFormat)
+		// }()
+		for _, name := range rpm.Names() {
+			d, _ := rpm.LookupDistribution(name, "")
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rpm.NewRPMCollector(d).Collect(*flagGenDot, graphFormat)
+			}()
+		}
+		go func() {
+			defer wg.Done()
+			alpine.NewAlpineCollector("").Collect(*flagGenDot, graphFormat)
+		}()
+		go func() {
+			defer wg.Done()
+			aur.NewAurCollector("").Collect(*flagGenDot, graphFormat)
+		}()
+
+		wg.Wait()
+	} else {
+		switch *flagType {
+		case "archlinux":
+			archlinux.NewArchLinuxCollector(*flagMirror).Collect(*flagGenDot, graphFormat)
+		case "debian":
+			debian.NewDebianCollector(*flagMirror).Collect(*flagGenDot, graphFormat)
+		case "deepin":
+			deepin.NewDeepinCollector(*flagMirror).Collect(*flagGenDot, graphFormat)
+		case "ubuntu":
+			ubuntu.NewUbuntuCollector(*flagMirror).Collect(*flagGenDot, graphFormat)
+		case "nix":
+			nix.NewNixCollector().Collect(*workerCount, *batchSize, *flagGenDot, graphFormat)
+		case "homebrew":
+			homebrew.NewHomebrewCollector(*flagMirror).Collect(*flagGenDot, graphFormat, *downloadDir)
+		case "gentoo":
+			gentoo.NewGentooCollector(*flagMirror).Collect(*flagGenDot, graphFormat, *downloadDir)
+		case "fedora":
+			fedora.NewFedoraCollector(*flagMirror).Collect(*flagGenDot, graphFormat)
+		case "centos":
+			centos.NewCentosCollector(*flagMirror).Collect(*flagGenDot, graphFormat)
+		case "alpine":
+			alpine.NewAlpineCollector(*flagMirror).Collect(*flagGenDot, graphFormat)
+		case "aur":
+			aur.NewAurCollector(*flagMirror).Collect(*flagGenDot, graphFormat)
+		default:
+			d, ok := rpm.LookupDistribution(*flagType, *flagMirror)
+			if !ok {
+				logger.Fatalf("Unknown distribution type %s", *flagType)
+			}
+			rpm.NewRPMCollector(d).Collect(*flagGenDot, graphFormat)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// This tool reports the history kept in the distribution snapshots: the
+// packages added and removed between two snapshots of a distribution, or
+// the dependents of a git link in every snapshot.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	scores "github.com/HUSTSecLab/criticality_score/pkg/score"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	_ "github.com/lib/pq"
+	"github.com/samber/lo"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagType   = pflag.Int("type", -1, "distribution type of the snapshots, e.g. 0 for debian, required unless --from and --to or --link are set")
+	flagFrom   = pflag.Int64("from", 0, "old snapshot id, default the snapshot before --to")
+	flagTo     = pflag.Int64("to", 0, "new snapshot id, default the latest snapshot of --type")
+	flagLink   = pflag.String("link", "", "report the dependents of the git link in every snapshot instead")
+	flagFormat = pflag.String("format", "markdown", "report format: markdown, json")
+	flagOutput = pflag.String("output", "", "report file, default stdout")
+)
+
+type snapshot struct {
+	ID           int64     `json:"id"`
+	Type         int       `json:"type"`
+	Distribution string    `json:"distribution"`
+	CreateTime   time.Time `json:"createTime"`
+	PackageCount int       `json:"packageCount"`
+}
+
+type snapshotPackage struct {
+	Package       string `json:"package"`
+	SourcePackage string `json:"sourcePackage,omitempty"`
+	Version       string `json:"version,omitempty"`
+	GitLink       string `json:"gitLink,omitempty"`
+}
+
+// snapshotDiff are the packages added and removed between two snapshots
+type snapshotDiff struct {
+	From    snapshot          `json:"from"`
+	To      snapshot          `json:"to"`
+	Added   []snapshotPackage `json:"added"`
+	Removed []snapshotPackage `json:"removed"`
+}
+
+// dependentHistory are the dependents of a link in a snapshot
+type dependentHistory struct {
+	Snapshot     snapshot `json:"snapshot"`
+	DependsCount int      `json:"dependsCount"`
+	PageRank     float64  `json:"pageRank"`
+	PackageCount int      `json:"packageCount"`
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "This tool reports the packages added and removed between two distribution snapshots, or the dependents of a git link in every snapshot.\n")
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	ac := storage.GetDefaultAppDatabaseContext()
+	repo := repository.NewDistSnapshotRepository(ac)
+
+	if *flagFormat != "markdown" && *flagFormat != "json" {
+		logger.Fatalf("Unknown format %s", *flagFormat)
+	}
+
+	var report any
+	var writeReport func(w io.Writer) error
+	if *flagLink != "" {
+		history := dependents(repo, *flagLink)
+		report = history
+		writeReport = func(w io.Writer) error {
+			return writeHistoryMarkdown(w, *flagLink, history)
+		}
+	} else {
+		diff := diffSnapshots(repo)
+		logger.Infof("Snapshot %d to %d: %d added, %d removed", diff.From.ID, diff.To.ID, len(diff.Added), len(diff.Removed))
+		report = diff
+		writeReport = func(w io.Writer) error {
+			return writeDiffMarkdown(w, diff)
+		}
+	}
+
+	out := os.Stdout
+	if *flagOutput != "" {
+		var err error
+		out, err = os.Create(*flagOutput)
+		if err != nil {
+			logger.Fatalf("Failed to create %s: %v", *flagOutput, err)
+		}
+		defer out.Close()
+	}
+	var err error
+	if *flagFormat == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = writeReport(out)
+	}
+	if err != nil {
+		logger.Fatalf("Failed to write report: %v", err)
+	}
+}
+
+// diffSnapshots compares the snapshots of --from and --to, by default the
+// last two snapshots of --type.
+func diffSnapshots(repo repository.DistSnapshotRepository) *snapshotDiff {
+	var to *repository.DistSnapshot
+	if *flagTo != 0 {
+		to = getSnapshot(repo, *flagTo)
+	}
+	distType := repository.DistType(*flagType)
+	if to != nil {
+		distType = *to.Type
+	} else if *flagType < 0 {
+		logger.Fatalf("--type or --to is required")
+	}
+
+	var from *repository.DistSnapshot
+	if *flagFrom != 0 {
+		from = getSnapshot(repo, *flagFrom)
+	}
+	if from == nil || to == nil {
+		snapshotsIter, err := repo.QueryByType(distType)
+		if err != nil {
+			logger.Fatalf("Failed to fetch snapshots: %v", err)
+		}
+		snapshots := slices.Collect(snapshotsIter)
+		if to == nil {
+			if len(snapshots) == 0 {
+				logger.Fatalf("Distribution type %d has no snapshot", distType)
+			}
+			to = snapshots[len(snapshots)-1]
+		}
+		if from == nil {
+			// the snapshot before to, snapshots are sorted oldest first
+			i := slices.IndexFunc(snapshots, func(s *repository.DistSnapshot) bool { return *s.ID == *to.ID })
+			if i < 1 {
+				logger.Fatalf("Snapshot %d has no snapshot before it", *to.ID)
+			}
+			from = snapshots[i-1]
+		}
+	}
+	if *from.Type != *to.Type {
+		logger.Fatalf("Snapshots %d and %d are of different distributions", *from.ID, *to.ID)
+	}
+
+	added, err := repo.QueryAdded(*from.ID, *to.ID)
+	if err != nil {
+		logger.Fatalf("Failed to fetch added packages: %v", err)
+	}
+	removed, err := repo.QueryRemoved(*from.ID, *to.ID)
+	if err != nil {
+		logger.Fatalf("Failed to fetch removed packages: %v", err)
+	}
+	diff := &snapshotDiff{From: toSnapshot(from), To: toSnapshot(to), Added: []snapshotPackage{}, Removed: []snapshotPackage{}}
+	for p := range added {
+		diff.Added = append(diff.Added, toPackage(p))
+	}
+	for p := range removed {
+		diff.Removed = append(diff.Removed, toPackage(p))
+	}
+	return diff
+}
+
+func dependents(repo repository.DistSnapshotRepository, link string) []dependentHistory {
+	historyIter, err := repo.QueryDependentHistory(link)
+	if err != nil {
+		logger.Fatalf("Failed to fetch the dependent history of %s: %v", link, err)
+	}
+	history := []dependentHistory{}
+	for h := range historyIter {
+		history = append(history, dependentHistory{
+			Snapshot: snapshot{
+				ID:           lo.FromPtr(h.SnapshotID),
+				Type:         int(lo.FromPtr(h.Type)),
+				Distribution: scores.DistName(lo.FromPtr(h.Type)),
+				CreateTime:   lo.FromPtr(h.CreateTime),
+			},
+			DependsCount: lo.FromPtr(h.DependsCount),
+			PageRank:     lo.FromPtr(h.PageRank),
+			PackageCount: lo.FromPtr(h.PackageCount),
+		})
+	}
+	return history
+}
+
+func getSnapshot(repo repository.DistSnapshotRepository, id int64) *repository.DistSnapshot {
+	s, err := repo.GetByID(id)
+	if err != nil {
+		logger.Fatalf("Failed to fetch snapshot %d: %v", id, err)
+	}
+	if s == nil {
+		logger.Fatalf("Snapshot %d does not exist", id)
+	}
+	return s
+}
+
+func toSnapshot(s *repository.DistSnapshot) snapshot {
+	return snapshot{
+		ID:           lo.FromPtr(s.ID),
+		Type:         int(lo.FromPtr(s.Type)),
+		Distribution: scores.DistName(lo.FromPtr(s.Type)),
+		CreateTime:   lo.FromPtr(s.CreateTime),
+		PackageCount: lo.FromPtr(s.PackageCount),
+	}
+}
+
+func toPackage(p *repository.DistSnapshotPackage) snapshotPackage {
+	return snapshotPackage{
+		Package:       lo.FromPtr(p.Package),
+		SourcePackage: lo.FromPtr(p.SourcePackage),
+		Version:       lo.FromPtr(p.Version),
+		GitLink:       lo.FromPtr(p.GitLink),
+	}
+}
+
+func describe(s snapshot) string {
+	return fmt.Sprintf("%s snapshot %d of %s (%d packages)", s.Distribution, s.ID, s.CreateTime.Format(time.RFC3339), s.PackageCount)
+}
+
+func writeDiffMarkdown(w io.Writer, d *snapshotDiff) error {
+	p := func(format string, args ...any) {
+		fmt.Fprintf(w, format+"\n", args...)
+	}
+	p("# Snapshot %d to %d", d.From.ID, d.To.ID)
+	p("")
+	p("- From: %s", describe(d.From))
+	p("- To: %s", describe(d.To))
+
+	packages := func(title string, packages []snapshotPackage) {
+		p("")
+		p("## %s (%d)", title, len(packages))
+		p("")
+		if len(packages) == 0 {
+			p("None.")
+			return
+		}
+		p("| Package | Source | Version | Git link |")
+		p("| --- | --- | --- | --- |")
+		for _, pkg := range packages {
+			p("| %s | %s | %s | %s |", pkg.Package, pkg.SourcePackage, pkg.Version, pkg.GitLink)
+		}
+	}
+	packages("Added packages", d.Added)
+	packages("Removed packages", d.Removed)
+	return nil
+}
+
+func writeHistoryMarkdown(w io.Writer, link string, history []dependentHistory) error {
+	p := func(format string, args ...any) {
+		fmt.Fprintf(w, format+"\n", args...)
+	}
+	p("# Dependents of %s", link)
+	p("")
+	if len(history) == 0 {
+		p("No snapshot has a package of the link.")
+		return nil
+	}
+	p("| Distribution | Snapshot | Time | Packages | Dependents | PageRank |")
+	p("| --- | --- | --- | --- | --- | --- |")
+	for _, h := range history {
+		p("| %s | %d | %s | %d | %d | %g |", h.Snapshot.Distribution, h.Snapshot.ID,
+			h.Snapshot.CreateTime.Format(time.RFC3339), h.PackageCount, h.DependsCount, h.PageRank)
+	}
+	return nil
+}
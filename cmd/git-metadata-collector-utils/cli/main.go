@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	collector "github.com/HUSTSecLab/criticality_score/pkg/gitfile/collector"
 	git "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/git"
@@ -16,6 +17,8 @@ import (
 	gogit "github.com/go-git/go-git/v5"
 )
 
+var flagAsOf = flag.String("as-of", "", "reference time in RFC 3339, commits after it are ignored, default now")
+
 func main() {
 	logger.ConfigAsCommandLineTool()
 	flag.Usage = func() {
@@ -30,6 +33,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	asOf := time.Now()
+	if *flagAsOf != "" {
+		t, err := time.Parse(time.RFC3339, *flagAsOf)
+		if err != nil {
+			logger.Fatalf("Invalid reference time %s: %v", *flagAsOf, err)
+		}
+		asOf = t
+	}
+
 	inputs := []string{}
 	for i := 0; i < flag.NArg(); i++ {
 		inputs = append(inputs, flag.Arg(i))
@@ -63,7 +75,7 @@ func main() {
 				}
 			}
 
-			repo, err := git.ParseRepo(r)
+			repo, err := git.ParseRepo(r, asOf)
 			if err != nil {
 				logger.Panicf("[%d] Parsing %s Failed", index, input)
 			}
@@ -70,7 +70,7 @@ func Collect(gitLink string) {
 		recordFail(err)
 		return
 	}
-	repo, err := git.ParseRepo(r)
+	repo, err := git.ParseRepo(r, time.Now())
 	if err != nil {
 		recordFail(err)
 		return
@@ -4,6 +4,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/config"
 	collector "github.com/HUSTSecLab/criticality_score/pkg/gitfile/collector"
@@ -21,6 +22,7 @@ import (
 var (
 	flagUpdateDB   = pflag.Bool("update-db", false, "Whether to update the database")
 	flagUpdateLink = pflag.String("update-link", "", "Which link to update")
+	flagModel      = pflag.String("model", "", "Scoring model file in yaml or json format, use the built-in model if not set")
 )
 
 func main() {
@@ -38,6 +40,14 @@ func main() {
 	updateDB := *flagUpdateDB
 	link := *flagUpdateLink
 
+	if *flagModel != "" {
+		model, err := scores.LoadModel(*flagModel)
+		if err != nil {
+			logger.Fatalf("Failed to load scoring model: %v", err)
+		}
+		scores.SetModel(model)
+	}
+
 	logger.Infof("Collecting %s", link)
 	r := &gogit.Repository{}
 	var err error
@@ -47,7 +57,7 @@ func main() {
 		logger.Panicf("Collecting %s Failed", u.URL)
 	}
 
-	repo, err := git.ParseRepo(r)
+	repo, err := git.ParseRepo(r, time.Now())
 	if err != nil {
 		logger.Panicf("Parsing %s Failed", link)
 	}
@@ -67,6 +77,7 @@ func main() {
 	gitMetadataScore := scores.NewGitMetadataScore()
 	gitMetadataScore.CalculateGitMetadataScore(gitMetadata[link])
 
+	scores.UpdatePackageList(ac)
 	distScore := scores.FetchDistMetadataSingle(ac, link)
 	distScore[link].CalculateDistScore()
 	langEcoScore := scores.FetchLangEcoMetadataSingle(ac, link)
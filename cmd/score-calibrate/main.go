@@ -0,0 +1,272 @@
+// This tool fits the weights of a scoring model to a labeled list of
+// critical projects, without writing any score to the database.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	scores "github.com/HUSTSecLab/criticality_score/pkg/score"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	_ "github.com/lib/pq"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagLabels     = pflag.String("labels", "", "csv file of git links and labels (1 for critical, 0 for not critical)")
+	flagModel      = pflag.String("model", "", "base scoring model file in yaml or json format, use the built-in model if not set")
+	flagOutput     = pflag.String("output", "calibrated-model.json", "calibrated model file in json format")
+	flagName       = pflag.String("name", "", "name of the calibrated model, default the base model name with -calibrated")
+	flagReport     = pflag.String("report", "", "report file, default stdout")
+	flagHoldout    = pflag.Float64("holdout", 0.2, "share of the links held out of the fit to evaluate the models")
+	flagL2         = pflag.Float64("l2", 1, "l2 regularization of the weights")
+	flagIterations = pflag.Int("iterations", 50, "maximum number of newton steps")
+	flagK          = pflag.IntSlice("k", []int{10, 100, 1000}, "k of the reported precision@k")
+	flagBatch      = pflag.Int("batch", 1000, "number of links scored at a time")
+)
+
+// sample is a labeled link with its features under the base model
+type sample struct {
+	link      string
+	label     bool
+	holdout   bool
+	features  []float64
+	baseScore float64
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "This tool fits the weights of a scoring model to a labeled csv of git links.\n")
+		fmt.Fprintf(os.Stderr, "Nothing is written to the database.\n")
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	ac := storage.GetDefaultAppDatabaseContext()
+
+	if *flagLabels == "" {
+		logger.Fatalf("--labels is required")
+	}
+	if *flagHoldout < 0 || *flagHoldout >= 1 {
+		logger.Fatalf("--holdout must be in [0, 1)")
+	}
+	labels, err := readLabels(*flagLabels)
+	if err != nil {
+		logger.Fatalf("Failed to read labels: %v", err)
+	}
+	// without any negative label, every other link counts as not critical
+	implicitNegatives := true
+	for _, label := range labels {
+		if !label {
+			implicitNegatives = false
+		}
+	}
+	logger.Infof("Read %d labeled links", len(labels))
+
+	base := scores.CurrentModel()
+	if *flagModel != "" {
+		base, err = scores.LoadModel(*flagModel)
+		if err != nil {
+			logger.Fatalf("Failed to load scoring model: %v", err)
+		}
+		scores.SetModel(base)
+	}
+	features := base.Features()
+
+	scores.SetAsOf(time.Now())
+	scores.UpdatePackageList(ac)
+	var samples []*sample
+	scores.StreamScores(ac, *flagBatch, 0, nil, func(linkScores map[string]*scores.LinkScore) {
+		for link, linkScore := range linkScores {
+			label, ok := labels[link]
+			if !ok && !implicitNegatives {
+				continue
+			}
+			samples = append(samples, &sample{
+				link:      link,
+				label:     label,
+				holdout:   isHoldout(link, *flagHoldout),
+				features:  linkScore.FeatureValues(features),
+				baseScore: linkScore.Score,
+			})
+		}
+	})
+
+	var x [][]float64
+	var y []bool
+	found := 0
+	for _, s := range samples {
+		if _, ok := labels[s.link]; ok {
+			found++
+		}
+		if !s.holdout {
+			x = append(x, s.features)
+			y = append(y, s.label)
+		}
+	}
+	if found < len(labels) {
+		logger.Warnf("%d labeled links have no git metrics and are ignored", len(labels)-found)
+	}
+	logger.Infof("Fitting %d weights on %d links", len(features), len(x))
+	coefficients, _ := scores.FitLogistic(x, y, *flagL2, *flagIterations)
+	if coefficients == nil {
+		logger.Fatalf("No labeled link to fit")
+	}
+
+	calibrated := scores.CalibratedModel(base, features, coefficients)
+	calibrated.Name = *flagName
+	if calibrated.Name == "" {
+		calibrated.Name = base.Name + "-calibrated"
+	}
+	if err := writeModel(*flagOutput, calibrated); err != nil {
+		logger.Fatalf("Failed to write model: %v", err)
+	}
+	logger.Infof("Wrote calibrated model %s (hash %s) to %s", calibrated.Name, calibrated.Hash(), *flagOutput)
+
+	out := os.Stdout
+	if *flagReport != "" {
+		out, err = os.Create(*flagReport)
+		if err != nil {
+			logger.Fatalf("Failed to create %s: %v", *flagReport, err)
+		}
+		defer out.Close()
+	}
+	writeReport(out, samples, features, coefficients, base, calibrated)
+}
+
+// readLabels reads a csv of git links with an optional label column. A
+// missing label means critical. A header row is skipped.
+func readLabels(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	labels := make(map[string]bool)
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		link := strings.TrimSpace(record[0])
+		if link == "" || strings.HasPrefix(link, "#") {
+			continue
+		}
+		if len(record) < 2 {
+			labels[link] = true
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(record[1])) {
+		case "1", "true", "yes":
+			labels[link] = true
+		case "0", "false", "no":
+			labels[link] = false
+		default:
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid label %q", line, record[1])
+		}
+	}
+	return labels, nil
+}
+
+// isHoldout puts the share of the links in the holdout set, by a hash of
+// the link so the split is the same in every run.
+func isHoldout(link string, share float64) bool {
+	h := fnv.New32a()
+	h.Write([]byte(link))
+	return float64(h.Sum32()%10000) < share*10000
+}
+
+func writeModel(path string, m *scores.Model) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func writeReport(w io.Writer, samples []*sample, features []scores.Feature, coefficients []float64, base, calibrated *scores.Model) {
+	p := func(format string, args ...any) {
+		fmt.Fprintf(w, format+"\n", args...)
+	}
+	p("# Score calibration")
+	p("")
+	p("Base: model %s version %s (hash `%s`)", base.Name, base.Version, base.Hash())
+	p("")
+	p("Calibrated: model %s (hash `%s`)", calibrated.Name, calibrated.Hash())
+
+	p("")
+	p("## Evaluation")
+	p("")
+	p("| Set | Links | Critical | Metric | Base | Calibrated |")
+	p("| --- | --- | --- | --- | --- | --- |")
+	for _, set := range []struct {
+		name    string
+		holdout bool
+	}{{"fit", false}, {"holdout", true}} {
+		var baseScores, calibratedScores []float64
+		var labels []bool
+		positives := 0
+		for _, s := range samples {
+			if s.holdout != set.holdout {
+				continue
+			}
+			linear := 0.0
+			for i, v := range s.features {
+				linear += coefficients[i] * v
+			}
+			baseScores = append(baseScores, s.baseScore)
+			calibratedScores = append(calibratedScores, linear)
+			labels = append(labels, s.label)
+			if s.label {
+				positives++
+			}
+		}
+		if len(labels) == 0 {
+			continue
+		}
+		row := func(metric string, f func([]float64, []bool) float64) {
+			p("| %s | %d | %d | %s | %.4f | %.4f |", set.name, len(labels), positives, metric,
+				f(baseScores, labels), f(calibratedScores, labels))
+		}
+		row("AUC", scores.AUC)
+		for _, k := range *flagK {
+			row(fmt.Sprintf("Precision@%d", k), func(s []float64, l []bool) float64 {
+				return scores.PrecisionAtK(s, l, k)
+			})
+		}
+	}
+
+	p("")
+	p("## Weights")
+	p("")
+	p("| Category | Metric | Base weight | Coefficient | Calibrated weight |")
+	p("| --- | --- | --- | --- | --- |")
+	for i, f := range features {
+		p("| %s | %s | %.4f | %.4f | %.4f |", f.Category, f.Metric,
+			base.Metric(f.Category, f.Metric).Weight, coefficients[i], calibrated.Metric(f.Category, f.Metric).Weight)
+	}
+	for _, category := range []string{scores.CategoryGit, scores.CategoryDist, scores.CategoryLangEco} {
+		if cm, ok := calibrated.Categories[category]; ok {
+			p("| %s | | %.4f | | %.4f |", category, base.Categories[category].Weight, cm.Weight)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// This tool compares the scores of two rounds and reports the links whose
+// inputs changed abnormally.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	scores "github.com/HUSTSecLab/criticality_score/pkg/score"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	_ "github.com/lib/pq"
+	"github.com/samber/lo"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagFrom       = pflag.Int("from", 0, "old round, default the round before --to")
+	flagTo         = pflag.Int("to", 0, "new round, default the latest round")
+	flagSeries     = pflag.String("series", scores.SeriesDefault, "score series: default, ossf, trending")
+	flagTop        = pflag.Int("top", 50, "number of links in each list")
+	flagDropShare  = pflag.Float64("drop-share", 0.9, "a metric dropping by this share or more is an anomaly")
+	flagRiseFactor = pflag.Float64("rise-factor", 10, "a metric growing by this factor or more is an anomaly, 0 disables it")
+	flagIgnore     = pflag.StringSlice("ignore", []string{"updated_since"}, "metrics never reported as anomalies")
+	flagFormat     = pflag.String("format", "markdown", "report format: markdown, json")
+	flagOutput     = pflag.String("output", "", "report file, default stdout")
+	flagFail       = pflag.Bool("fail-on-anomaly", false, "exit with status 2 if any anomaly is found")
+)
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "This tool compares the scores of two rounds and reports abnormal metric changes.\n")
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	ac := storage.GetDefaultAppDatabaseContext()
+
+	if !lo.Contains(scores.AllSeries, *flagSeries) {
+		logger.Fatalf("Unknown series %s", *flagSeries)
+	}
+	if *flagFormat != "markdown" && *flagFormat != "json" {
+		logger.Fatalf("Unknown format %s", *flagFormat)
+	}
+	to := *flagTo
+	if to == 0 {
+		to = scores.GetRound(ac)
+	}
+	from := *flagFrom
+	if from == 0 {
+		from = to - 1
+	}
+
+	diff, err := scores.DiffRounds(ac, *flagSeries, from, to, scores.DiffOptions{
+		Top:           *flagTop,
+		DropShare:     *flagDropShare,
+		RiseFactor:    *flagRiseFactor,
+		IgnoreMetrics: *flagIgnore,
+	})
+	if err != nil {
+		logger.Fatalf("Failed to diff rounds %d and %d: %v", from, to, err)
+	}
+	logger.Infof("Round %d to %d: %d added, %d removed, %d anomalies, Kendall tau %.4f",
+		from, to, diff.AddedCount, diff.RemovedCount, diff.AnomalyCount, diff.KendallTau)
+
+	out := os.Stdout
+	if *flagOutput != "" {
+		out, err = os.Create(*flagOutput)
+		if err != nil {
+			logger.Fatalf("Failed to create %s: %v", *flagOutput, err)
+		}
+		defer out.Close()
+	}
+	if *flagFormat == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(diff)
+	} else {
+		err = writeMarkdown(out, diff)
+	}
+	if err != nil {
+		logger.Fatalf("Failed to write report: %v", err)
+	}
+
+	if *flagFail && diff.AnomalyCount > 0 {
+		os.Exit(2)
+	}
+}
+
+func writeMarkdown(w io.Writer, d *scores.RoundDiff) error {
+	p := func(format string, args ...any) {
+		fmt.Fprintf(w, format+"\n", args...)
+	}
+	p("# Round %d to %d (%s series)", d.OldRound, d.NewRound, d.Series)
+	p("")
+	p("| Metric | Value |")
+	p("| --- | --- |")
+	p("| Added links | %d |", d.AddedCount)
+	p("| Removed links | %d |", d.RemovedCount)
+	p("| Anomalies | %d |", d.AnomalyCount)
+	p("| Kendall tau | %.4f |", d.KendallTau)
+	p("| Spearman | %.4f |", d.Spearman)
+
+	section := func(title string, empty bool) bool {
+		p("")
+		p("## %s", title)
+		p("")
+		if empty {
+			p("None.")
+		}
+		return !empty
+	}
+
+	if section("Anomalies", len(d.Anomalies) == 0) {
+		p("| Link | Anomaly | Old value | New value |")
+		p("| --- | --- | --- | --- |")
+		for _, a := range d.Anomalies {
+			p("| %s | %s | %g | %g |", a.Link, a.Describe(), a.OldValue, a.NewValue)
+		}
+	}
+
+	changes := func(title string, changes []*scores.LinkChange) {
+		if !section(title, len(changes) == 0) {
+			return
+		}
+		p("| Link | Old rank | New rank | Old score | New score | Cause |")
+		p("| --- | --- | --- | --- | --- | --- |")
+		for _, c := range changes {
+			p("| %s | %d | %d | %.4f | %.4f | %s |", c.Link, c.OldRank, c.NewRank, c.OldScore, c.NewScore, cause(c.Cause))
+		}
+	}
+	changes("Largest score changes", d.ScoreChanges)
+	changes("Largest rank changes", d.RankChanges)
+
+	if section("Added links", len(d.Added) == 0) {
+		p("| Link | Rank | Score |")
+		p("| --- | --- | --- |")
+		for _, c := range d.Added {
+			p("| %s | %d | %.4f |", c.Link, c.NewRank, c.NewScore)
+		}
+	}
+	if section("Removed links", len(d.Removed) == 0) {
+		p("| Link | Old rank | Old score |")
+		p("| --- | --- | --- |")
+		for _, c := range d.Removed {
+			p("| %s | %d | %.4f |", c.Link, c.OldRank, c.OldScore)
+		}
+	}
+	return nil
+}
+
+// cause describes the metric which caused a change
+func cause(c *scores.MetricChange) string {
+	if c == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%s.%s %s -> %s (contribution %+.4f)", c.Category, c.Metric,
+		strconv.FormatFloat(c.OldValue, 'g', 6, 64), strconv.FormatFloat(c.NewValue, 'g', 6, 64), c.ContributionDelta())
+}
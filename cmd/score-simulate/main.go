@@ -0,0 +1,200 @@
+// This tool shows how a candidate scoring model would change the ranking,
+// without writing any score to the database.
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	scores "github.com/HUSTSecLab/criticality_score/pkg/score"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	_ "github.com/lib/pq"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagModel    = pflag.String("model", "", "candidate scoring model file in yaml or json format")
+	flagBaseline = pflag.String("baseline", "", "scoring model file the candidate is compared with, use the built-in model if not set")
+	flagAsOf     = pflag.String("as-of", "", "reference time in RFC 3339 or YYYY-MM-DD, ignore inputs updated after it and measure ages against it, default now")
+	flagTop      = pflag.Int("top", 100, "size of the top N used to measure churn")
+	flagMovers   = pflag.Int("movers", 20, "number of biggest movers to report, 0 means all")
+	flagFormat   = pflag.String("format", "markdown", "report format: markdown, csv")
+	flagOutput   = pflag.String("output", "", "report file, default stdout")
+	flagBatch    = pflag.Int("batch", 1000, "number of links scored at a time")
+)
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "This tool scores all links with a baseline and a candidate model in memory and compares the rankings.\n")
+		fmt.Fprintf(os.Stderr, "Nothing is written to the database.\n")
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	ac := storage.GetDefaultAppDatabaseContext()
+
+	if *flagModel == "" {
+		logger.Fatalf("--model is required")
+	}
+	if *flagFormat != "markdown" && *flagFormat != "csv" {
+		logger.Fatalf("Unknown format %s", *flagFormat)
+	}
+	model, err := scores.LoadModel(*flagModel)
+	if err != nil {
+		logger.Fatalf("Failed to load scoring model: %v", err)
+	}
+	baseline := scores.DefaultModel()
+	if *flagBaseline != "" {
+		baseline, err = scores.LoadModel(*flagBaseline)
+		if err != nil {
+			logger.Fatalf("Failed to load baseline model: %v", err)
+		}
+	}
+	if *flagAsOf != "" {
+		t, err := scores.ParseAsOf(*flagAsOf)
+		if err != nil {
+			logger.Fatalf("Failed to parse --as-of: %v", err)
+		}
+		scores.SetAsOf(t)
+		logger.Infof("Scoring as of %s", t.Format(time.RFC3339))
+	} else {
+		scores.SetAsOf(time.Now())
+	}
+	if summary := scores.GetRoundSummary(ac, scores.SeriesDefault, scores.GetRound(ac)); summary != nil && summary.ModelHash != baseline.Hash() {
+		logger.Warnf("Round %d was calculated with model hash %s, not with the baseline model", summary.Round, summary.ModelHash)
+	}
+
+	logger.Infof("Scoring with baseline model %s (version %s, hash %s) and candidate model %s (version %s, hash %s)",
+		baseline.Name, baseline.Version, baseline.Hash(), model.Name, model.Version, model.Hash())
+	scores.UpdatePackageList(ac)
+	// both models score every batch, so they see the same inputs
+	ranking := []map[string]float64{make(map[string]float64), make(map[string]float64)}
+	scores.StreamModelScores(ac, *flagBatch, 0, []*scores.Model{baseline, model}, func(i int, linkScores map[string]*scores.LinkScore) {
+		for link, linkScore := range linkScores {
+			ranking[i][link] = linkScore.Score
+		}
+	})
+
+	comparison := scores.CompareRankings(ranking[0], ranking[1], *flagTop)
+	logger.Infof("Compared %d links: Kendall tau %.4f, Spearman %.4f, top %d churn %.2f%%",
+		len(comparison.Changes), comparison.KendallTau, comparison.Spearman, comparison.TopN, comparison.TopNChurn()*100)
+
+	out := os.Stdout
+	if *flagOutput != "" {
+		out, err = os.Create(*flagOutput)
+		if err != nil {
+			logger.Fatalf("Failed to create %s: %v", *flagOutput, err)
+		}
+		defer out.Close()
+	}
+
+	movers := comparison.Changes
+	if *flagMovers > 0 && len(movers) > *flagMovers {
+		movers = movers[:*flagMovers]
+	}
+	if *flagFormat == "csv" {
+		err = writeCSV(out, comparison, movers)
+	} else {
+		err = writeMarkdown(out, comparison, movers, baseline, model)
+	}
+	if err != nil {
+		logger.Fatalf("Failed to write report: %v", err)
+	}
+}
+
+func writeMarkdown(w io.Writer, c *scores.RankingComparison, movers []*scores.RankChange, baseline, model *scores.Model) error {
+	p := func(format string, args ...any) {
+		fmt.Fprintf(w, format+"\n", args...)
+	}
+	p("# Score simulation")
+	p("")
+	p("Baseline: model %s version %s (hash `%s`)", baseline.Name, baseline.Version, baseline.Hash())
+	p("")
+	p("Candidate: model %s version %s (hash `%s`)", model.Name, model.Version, model.Hash())
+	p("")
+	p("| Metric | Value |")
+	p("| --- | --- |")
+	p("| Links in both rankings | %d |", len(c.Changes))
+	p("| Links only in candidate | %d |", c.Added)
+	p("| Links only in baseline | %d |", c.Removed)
+	p("| Kendall tau | %.4f |", c.KendallTau)
+	p("| Spearman | %.4f |", c.Spearman)
+	p("| Top %d churn | %.2f%% |", c.TopN, c.TopNChurn()*100)
+
+	table := func(title string, changes []*scores.RankChange) {
+		p("")
+		p("## %s", title)
+		p("")
+		if len(changes) == 0 {
+			p("None.")
+			return
+		}
+		p("| Link | Baseline rank | Candidate rank | Change | Baseline score | Candidate score |")
+		p("| --- | --- | --- | --- | --- | --- |")
+		for _, change := range changes {
+			p("| %s | %s | %s | %s | %.4f | %.4f |", change.Link, rank(change.OldRank), rank(change.NewRank),
+				delta(change), change.OldScore, change.NewScore)
+		}
+	}
+	table("Biggest movers", movers)
+	table(fmt.Sprintf("Entered top %d", c.TopN), c.TopNEntered)
+	table(fmt.Sprintf("Left top %d", c.TopN), c.TopNLeft)
+	return nil
+}
+
+// writeCSV writes the biggest movers and the links entering or leaving the
+// top N, one row per link.
+func writeCSV(w io.Writer, c *scores.RankingComparison, movers []*scores.RankChange) error {
+	topN := make(map[string]string)
+	for _, change := range c.TopNEntered {
+		topN[change.Link] = "entered"
+	}
+	for _, change := range c.TopNLeft {
+		topN[change.Link] = "left"
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"link", "baseline_rank", "candidate_rank", "rank_change", "baseline_score", "candidate_score", "top_n"})
+	written := make(map[string]bool)
+	for _, changes := range [][]*scores.RankChange{movers, c.TopNEntered, c.TopNLeft} {
+		for _, change := range changes {
+			if written[change.Link] {
+				continue
+			}
+			written[change.Link] = true
+			cw.Write([]string{
+				change.Link,
+				strconv.Itoa(change.OldRank),
+				strconv.Itoa(change.NewRank),
+				delta(change),
+				strconv.FormatFloat(change.OldScore, 'f', -1, 64),
+				strconv.FormatFloat(change.NewScore, 'f', -1, 64),
+				topN[change.Link],
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func rank(r int) string {
+	if r == 0 {
+		return "-"
+	}
+	return strconv.Itoa(r)
+}
+
+func delta(change *scores.RankChange) string {
+	if change.OldRank == 0 || change.NewRank == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%+d", change.Delta())
+}
@@ -1,53 +1,155 @@
 package main
 
 import (
+	"time"
+
 	"github.com/HUSTSecLab/criticality_score/pkg/config"
 	"github.com/HUSTSecLab/criticality_score/pkg/logger"
 	scores "github.com/HUSTSecLab/criticality_score/pkg/score"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	_ "github.com/lib/pq"
+	"github.com/samber/lo"
 	"github.com/spf13/pflag"
 )
 
 var (
-	batchSize = pflag.Int("batch", 1000, "batch size")
-	calcType  = pflag.String("calc", "all", "calculation type: distro, git, langeco, all")
+	batchSize   = pflag.Int("batch", 1000, "batch size")
+	calcType    = pflag.String("calc", "all", "calculation type: distro, git, langeco, all")
+	modelFile   = pflag.String("model", "", "scoring model file in yaml or json format, use the built-in model if not set")
+	asOf        = pflag.String("as-of", "", "reference time in RFC 3339 or YYYY-MM-DD, ignore inputs updated after it and measure ages against it, default now")
+	incremental = pflag.Bool("incremental", false, "only recompute links whose inputs changed since the last round, carry forward the others")
+	ossf        = pflag.Bool("ossf", true, "also score the links with the original OpenSSF formula in the ossf series")
+	trending    = pflag.Bool("trending", true, "also score how fast the links grow in the trending series")
+	trendMonths = pflag.Int("trend-months", 6, "window of the trending series in months")
 )
 
 func main() {
 	config.RegistCommonFlags(pflag.CommandLine)
 	config.ParseFlags(pflag.CommandLine)
 	ac := storage.GetDefaultAppDatabaseContext()
+	if *modelFile != "" {
+		model, err := scores.LoadModel(*modelFile)
+		if err != nil {
+			logger.Fatalf("Failed to load scoring model: %v", err)
+		}
+		scores.SetModel(model)
+	}
+	if *asOf != "" {
+		t, err := scores.ParseAsOf(*asOf)
+		if err != nil {
+			logger.Fatalf("Failed to parse --as-of: %v", err)
+		}
+		if *incremental {
+			logger.Fatalf("--as-of cannot be used with --incremental")
+		}
+		scores.SetAsOf(t)
+		logger.Infof("Scoring as of %s", t.Format(time.RFC3339))
+	} else {
+		// every age in the round is measured against the same time
+		scores.SetAsOf(time.Now())
+	}
+	logger.Infof("Using scoring model %s (version %s, hash %s)", scores.CurrentModel().Name, scores.CurrentModel().Version, scores.CurrentModel().Hash())
+	if *trendMonths <= 0 {
+		logger.Fatalf("--trend-months must be positive")
+	}
+	scores.CurrentTrendModel().Months = *trendMonths
 	scores.UpdatePackageList(ac)
-	linksMap := scores.FetchGitLink(ac)
-	// linksMap := []string{"https://github.com/retep998/winapi-rs"}
-	gitMeticMap := scores.FetchGitMetrics(ac)
-	langEcoMetricMap := scores.FetchLangEcoMetadata(ac)
-	distMetricMap := scores.FetchDistMetadata(ac)
-	var gitMetadataScore = make(map[string]*scores.GitMetadataScore)
-
-	packageScore := make(map[string]*scores.LinkScore)
 	round := scores.GetRound(ac)
 
-	for _, link := range linksMap {
-		if _, ok := distMetricMap[link]; !ok {
-			distMetricMap[link] = scores.NewDistScore()
+	var changedLinks map[string]bool
+	var since time.Time
+	var keep func(link string) bool
+	if *incremental {
+		changedLinks, since = fetchChangedLinks(ac, round)
+	}
+	if changedLinks != nil {
+		keep = func(link string) bool {
+			return changedLinks[link]
 		}
-		distMetricMap[link].CalculateDistScore()
+		logger.Infof("Incremental round %d: %d links changed since round %d", round+1, len(changedLinks), round)
+	}
 
-		if _, ok := langEcoMetricMap[link]; !ok {
-			langEcoMetricMap[link] = scores.NewLangEcoScore()
+	// scores are written batch by batch, so memory is bounded by the batch size
+	scores.StreamScores(ac, *batchSize, round+1, keep, func(packageScore map[string]*scores.LinkScore) {
+		scores.UpdateScore(ac, packageScore)
+		if *ossf {
+			scores.UpdateOSSFScore(ac, packageScore)
+		}
+		if *trending {
+			scores.UpdateTrendScore(ac, scores.FetchTrends(ac, lo.Keys(packageScore), round+1))
+		}
+	})
+	if changedLinks == nil {
+		return
+	}
+	for _, series := range seriesList() {
+		if !scores.CarriesForward(series) {
+			continue
+		}
+		cnt := scores.CarryForwardScores(ac, series, round, round+1, changedLinks, since)
+		logger.Infof("Carried forward %d unchanged scores of series %s", cnt, series)
+	}
+	if *trending {
+		// the window moved, the trends of unchanged links are recomputed
+		for links := range scores.FetchLinkBatches(ac, *batchSize) {
+			unchanged := lo.Reject(links, func(link string, _ int) bool {
+				return changedLinks[link]
+			})
+			scores.UpdateTrendScore(ac, scores.FetchTrends(ac, unchanged, round+1))
 		}
-		langEcoMetricMap[link].CalculateLangEcoScore()
+	}
+}
+
+// seriesList returns the series scored in this round
+func seriesList() []string {
+	series := []string{scores.SeriesDefault}
+	if *ossf {
+		series = append(series, scores.SeriesOSSF)
+	}
+	if *trending {
+		series = append(series, scores.SeriesTrending)
+	}
+	return series
+}
 
-		gitMetadataScore[link] = scores.NewGitMetadataScore()
-		if _, ok := gitMeticMap[link]; !ok {
+// fetchChangedLinks returns the links changed since the round and the
+// start of the round, or nil if the round cannot be carried forward and
+// all links must be recomputed.
+func fetchChangedLinks(ac storage.AppDatabaseContext, round int) (map[string]bool, time.Time) {
+	model := scores.CurrentModel()
+	if model.UsesPopulation() {
+		logger.Warnf("Model %s normalizes against the whole round, recomputing all links", model.Name)
+		return nil, time.Time{}
+	}
+	if len(model.CustomMetrics) > 0 {
+		logger.Warnf("Model %s has custom metrics whose changes are not tracked, recomputing all links", model.Name)
+		return nil, time.Time{}
+	}
+	hashes := map[string]string{
+		scores.SeriesDefault: model.Hash(),
+		scores.SeriesOSSF:    scores.OSSFHash(),
+	}
+	var startTime time.Time
+	for _, series := range seriesList() {
+		if !scores.CarriesForward(series) {
 			continue
 		}
-		gitMetadataScore[link].CalculateGitMetadataScore(gitMeticMap[link])
-		packageScore[link] = scores.NewLinkScore(gitMetadataScore[link], distMetricMap[link], langEcoMetricMap[link], round+1)
-		packageScore[link].CalculateScore()
+		summary := scores.GetRoundSummary(ac, series, round)
+		if summary == nil {
+			logger.Warnf("Round %d has no scores of series %s, recomputing all links", round, series)
+			return nil, time.Time{}
+		}
+		if summary.ModelHash != hashes[series] {
+			logger.Warnf("Round %d of series %s was calculated with another model, recomputing all links", round, series)
+			return nil, time.Time{}
+		}
+		if startTime.IsZero() || summary.StartTime.Before(startTime) {
+			startTime = summary.StartTime
+		}
+	}
+	if scores.DistWeightsChanged(ac, round) {
+		logger.Warnf("Package counts of the distributions changed since round %d, recomputing all links", round)
+		return nil, time.Time{}
 	}
-	logger.Println("Updating database...")
-	scores.UpdateScore(ac, packageScore)
+	return scores.FetchChangedLinks(ac, startTime), startTime
 }
@@ -17,6 +17,7 @@ require (
 	github.com/google/licensecheck v0.3.1
 	github.com/hasura/go-graphql-client v0.13.1
 	github.com/imroc/req/v3 v3.49.1
+	github.com/klauspost/compress v1.17.11
 	github.com/lib/pq v1.10.9
 	github.com/ossf/scorecard/v4 v4.13.1
 	github.com/samber/lo v1.47.0
@@ -28,6 +29,7 @@ require (
 	github.com/swaggo/files v1.0.1
 	github.com/swaggo/gin-swagger v1.6.0
 	github.com/swaggo/swag v1.16.4
+	github.com/ulikunitz/xz v0.5.15
 	go.elastic.co/ecslogrus v1.0.0
 	golang.org/x/oauth2 v0.25.0
 	gopkg.in/go-extras/elogrus.v8 v8.0.1
@@ -83,7 +85,6 @@ require (
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/kevinburke/ssh_config v1.2.0 // indirect
-	github.com/klauspost/compress v1.17.11 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.7 // indirect
 	github.com/leodido/go-urn v1.4.0 // indirect
 	github.com/magefile/mage v1.9.0 // indirect
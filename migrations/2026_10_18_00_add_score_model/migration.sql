@@ -0,0 +1,4 @@
+ALTER TABLE scores
+ADD COLUMN model_name varchar,
+ADD COLUMN model_version varchar,
+ADD COLUMN model_hash varchar;
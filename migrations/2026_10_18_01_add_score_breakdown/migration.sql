@@ -0,0 +1,12 @@
+create table scores_breakdown
+(
+    score_id     int8 references scores (id),
+    category     varchar not null,
+    metric       varchar not null default '',
+    value        float8,
+    normalized   float8,
+    weight       float8,
+    contribution float8,
+
+    primary key (score_id, category, metric)
+);
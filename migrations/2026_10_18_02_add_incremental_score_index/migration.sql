@@ -0,0 +1,5 @@
+create index if not exists idx_scores_round on scores (round);
+
+create index if not exists idx_git_metrics_update_time on git_metrics (update_time);
+create index if not exists idx_distribution_dependencies_update_time on distribution_dependencies (update_time);
+create index if not exists idx_lang_ecosystems_update_time on lang_ecosystems (update_time);
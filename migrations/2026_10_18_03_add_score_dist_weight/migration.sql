@@ -0,0 +1,3 @@
+-- weight of the distribution in the dist score, null for older scores
+alter table scores_dist
+    add column weight float8;
@@ -0,0 +1,26 @@
+-- scores of the same link and round in different series are calculated
+-- with different formulas and ranked separately
+alter table scores
+    add column series varchar not null default 'default';
+
+create index if not exists idx_scores_series_round on scores (series, round);
+
+drop view if exists rankings;
+create view rankings as (
+    select *, rank() over (partition by series order by score desc nulls last) as ranking
+            from (select  s.git_link   as git_link,
+                        s.id          as score_id,
+                        s.dist_score  as dist_score,
+                        s.lang_score  as lang_score,
+                        s.git_score   as git_score,
+                        s.score       as score,
+                        s.update_time as update_time,
+                        s.confidence  as confidence,
+                        s.series      as series
+                        from scores s
+                where s.round = (select max(round) from scores m where m.series = s.series)) as t
+    order by series, score desc nulls last
+);
+
+drop table if exists rankings_cache;
+create table rankings_cache as select * from rankings;
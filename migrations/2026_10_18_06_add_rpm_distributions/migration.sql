@@ -0,0 +1,83 @@
+-- packages of the rpm based distributions collected from repomd.xml
+-- Rocky 11
+-- Alma 12
+-- OpenEuler 13
+-- OpenSUSE 14
+
+create table if not exists rocky_packages
+(
+    package         text not null primary key,
+    homepage        text,
+    description     text,
+    depends_count   bigint           default 1,
+    git_link        text,
+    page_rank       double precision default 0,
+    version         text,
+    link_confidence real
+);
+
+create index if not exists idx_rocky_packages_git_link on rocky_packages (git_link);
+
+create table if not exists alma_packages
+(
+    package         text not null primary key,
+    homepage        text,
+    description     text,
+    depends_count   bigint           default 1,
+    git_link        text,
+    page_rank       double precision default 0,
+    version         text,
+    link_confidence real
+);
+
+create index if not exists idx_alma_packages_git_link on alma_packages (git_link);
+
+create table if not exists openeuler_packages
+(
+    package         text not null primary key,
+    homepage        text,
+    description     text,
+    depends_count   bigint           default 1,
+    git_link        text,
+    page_rank       double precision default 0,
+    version         text,
+    link_confidence real
+);
+
+create index if not exists idx_openeuler_packages_git_link on openeuler_packages (git_link);
+
+create table if not exists opensuse_packages
+(
+    package         text not null primary key,
+    homepage        text,
+    description     text,
+    depends_count   bigint           default 1,
+    git_link        text,
+    page_rank       double precision default 0,
+    version         text,
+    link_confidence real
+);
+
+create index if not exists idx_opensuse_packages_git_link on opensuse_packages (git_link);
+
+create or replace view all_gitlinks as
+select git_link from (
+                         select distinct git_link from debian_packages
+                         union distinct select git_link from arch_packages
+                         union distinct select git_link from homebrew_packages
+                         union distinct select git_link from nix_packages
+                         union distinct select git_link from alpine_packages
+                         union distinct select git_link from centos_packages
+                         union distinct select git_link from aur_packages
+                         union distinct select git_link from deepin_packages
+                         union distinct select git_link from fedora_packages
+                         union distinct select git_link from gentoo_packages
+                         union distinct select git_link from ubuntu_packages
+                         union distinct select git_link from rocky_packages
+                         union distinct select git_link from alma_packages
+                         union distinct select git_link from openeuler_packages
+                         union distinct select git_link from opensuse_packages
+                         union distinct select git_link from github_links
+                         union distinct select git_link from gitlab_links
+                         union distinct select git_link from bitbucket_links) t
+where git_link is not null and git_link <> '' and git_link <> 'NA' and git_link <> 'NaN';
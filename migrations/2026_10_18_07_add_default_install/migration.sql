@@ -0,0 +1,37 @@
+-- packages installed by default, and the packages they depend on, marked
+-- by the collectors; deepin_packages already has the column
+alter table debian_packages
+    add column if not exists default_install integer default 0;
+alter table arch_packages
+    add column if not exists default_install integer default 0;
+alter table homebrew_packages
+    add column if not exists default_install integer default 0;
+alter table nix_packages
+    add column if not exists default_install integer default 0;
+alter table alpine_packages
+    add column if not exists default_install integer default 0;
+alter table centos_packages
+    add column if not exists default_install integer default 0;
+alter table aur_packages
+    add column if not exists default_install integer default 0;
+alter table deepin_packages
+    add column if not exists default_install integer default 0;
+alter table fedora_packages
+    add column if not exists default_install integer default 0;
+alter table gentoo_packages
+    add column if not exists default_install integer default 0;
+alter table ubuntu_packages
+    add column if not exists default_install integer default 0;
+alter table rocky_packages
+    add column if not exists default_install integer default 0;
+alter table alma_packages
+    add column if not exists default_install integer default 0;
+alter table openeuler_packages
+    add column if not exists default_install integer default 0;
+alter table opensuse_packages
+    add column if not exists default_install integer default 0;
+
+-- whether a package of the link is installed by default, null for rows
+-- collected before
+alter table distribution_dependencies
+    add column default_install boolean;
@@ -0,0 +1,4 @@
+-- distribution dependencies are computed per kind of dependency: runtime,
+-- build, test and optional; rows collected before are runtime
+alter table distribution_dependencies
+    add column kind varchar not null default 'runtime';
@@ -0,0 +1,30 @@
+-- every run of a distribution collector records a snapshot of the packages
+-- it read, the *_packages tables and distribution_dependencies only keep
+-- the latest values
+create table if not exists distribution_snapshots
+(
+    id            bigserial primary key,
+    type          integer   not null,
+    -- locations of the indexes read, one per line
+    source        text,
+    package_count integer,
+    create_time   timestamp not null default now()
+);
+
+create index if not exists idx_distribution_snapshots_type on distribution_snapshots (type, create_time);
+
+create table if not exists distribution_snapshot_packages
+(
+    snapshot_id     bigint not null references distribution_snapshots (id) on delete cascade,
+    package         text   not null,
+    source_package  text   not null,
+    version         text,
+    git_link        text,
+    depends_count   bigint,
+    page_rank       double precision,
+    impact          double precision,
+    default_install boolean,
+    primary key (snapshot_id, package)
+);
+
+create index if not exists idx_distribution_snapshot_packages_git_link on distribution_snapshot_packages (git_link, snapshot_id);
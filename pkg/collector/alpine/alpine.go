@@ -1,31 +1,38 @@
 package alpine
 
 import (
+	"io"
 	"log"
 	"strings"
 
 	collector "github.com/HUSTSecLab/criticality_score/pkg/collector/internal"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 )
 
 type AlpineCollector struct {
 	collector.CollecterInterface
+	Source collector.Source
 }
 
-func (ac *AlpineCollector) Collect(outputPath string) {
+func (ac *AlpineCollector) Collect(outputPath string, graphFormat graph.Format) {
 	adc := storage.GetDefaultAppDatabaseContext()
-	data := ac.GetPackageInfo(collector.AlpineURL)
-	ac.ParseInfo(data)
+	ac.ReadIndexes(ac.Source.URLs(), ac.ParseInfo)
+	// alpine-base is the base system every installation starts from
+	ac.MarkDefaultInstall("alpine-base")
+	ac.ReadDefaultList(ac.Source.Defaults)
+	ac.GetDefaultInstall()
 	ac.GetDep()
-	ac.PageRank(0.85, 20)
+	ac.PageRank(0.85, 100)
 	ac.GetDepCount()
 	ac.UpdateDistRepoCount(adc)
 	ac.CalculateDistImpact()
 	ac.UpdateOrInsertDatabase(adc)
 	ac.UpdateOrInsertDistDependencyDatabase(adc)
+	ac.RecordSnapshot(adc)
 	if outputPath != "" {
-		err := ac.GenerateDependencyGraph(outputPath)
+		err := ac.GenerateDependencyGraph(outputPath, graphFormat)
 		if err != nil {
 			log.Printf("Error generating dependency graph: %v\n", err)
 			return
@@ -33,45 +40,55 @@ func (ac *AlpineCollector) Collect(outputPath string) {
 	}
 }
 
-func (ac *AlpineCollector) ParseInfo(data string) {
-	entries := strings.Split(data, "\n\n")
-	for _, entry := range entries {
-		lines := strings.Split(entry, "\n")
-		var pkg collector.PackageInfo
-		for _, line := range lines {
-			if len(line) < 2 {
-				continue
-			}
-			switch line[0:2] {
-			case "P:":
-				pkg.Name = line[2:]
-			case "V:":
-				pkg.Version = line[2:]
-			case "D:":
-				depends := strings.Fields(line[2:])
-				for _, dep := range depends {
-					if idx := strings.Index(dep, ":"); idx != -1 {
-						dep = dep[idx+1:]
-					}
-					if idx := strings.Index(dep, "="); idx != -1 {
-						dep = dep[:idx]
-					}
-					pkg.DirectDepends = append(pkg.DirectDepends, dep)
-				}
-			case "T:":
-				pkg.Description = line[2:]
-			case "U:":
-				pkg.Homepage = line[2:]
-			}
-		}
+func (ac *AlpineCollector) ParseInfo(r io.Reader) error {
+	// entries are separated by empty lines
+	var pkg collector.PackageInfo
+	flush := func() {
 		if pkg.Name != "" {
 			ac.SetPkgInfo(pkg.Name, &pkg)
 		}
+		pkg = collector.PackageInfo{}
 	}
+	err := collector.ForEachLine(r, func(line string) {
+		if line == "" {
+			flush()
+			return
+		}
+		if len(line) < 2 {
+			return
+		}
+		switch line[0:2] {
+		case "P:":
+			pkg.Name = line[2:]
+		case "V:":
+			pkg.Version = line[2:]
+		case "D:":
+			// so:, cmd: and pc: dependencies are resolved through the p: field
+			// of the providing packages, ! marks a conflict
+			depends := strings.Fields(line[2:])
+			for _, dep := range depends {
+				if strings.HasPrefix(dep, "!") {
+					continue
+				}
+				pkg.DirectDepends = append(pkg.DirectDepends, collector.Capability(dep))
+			}
+		case "p:":
+			pkg.Provides = append(pkg.Provides, strings.Fields(line[2:])...)
+		case "o:":
+			pkg.Source = line[2:]
+		case "T:":
+			pkg.Description = line[2:]
+		case "U:":
+			pkg.Homepage = line[2:]
+		}
+	})
+	flush()
+	return err
 }
 
-func NewAlpineCollector() *AlpineCollector {
+func NewAlpineCollector(mirror string) *AlpineCollector {
 	return &AlpineCollector{
 		CollecterInterface: collector.NewCollector(repository.Alpine, repository.DistPackageTablePrefix("alpine")),
+		Source:             collector.LoadSource("alpine", collector.AlpineSource, mirror),
 	}
 }
@@ -1,31 +1,38 @@
 package archlinux
 
 import (
+	"io"
 	"log"
 	"strings"
 
 	collector "github.com/HUSTSecLab/criticality_score/pkg/collector/internal"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 )
 
 type ArchLinuxCollector struct {
 	collector.CollecterInterface
+	Source collector.Source
 }
 
-func (al *ArchLinuxCollector) Collect(outputPath string) {
+func (al *ArchLinuxCollector) Collect(outputPath string, graphFormat graph.Format) {
 	adc := storage.GetDefaultAppDatabaseContext()
-	data := al.GetPackageInfo(collector.ArchlinuxURL)
-	al.ParseInfo(data)
+	al.ReadIndexes(al.Source.URLs(), al.ParseInfo)
+	// the base meta package depends on the minimal installation
+	al.MarkDefaultInstall("base")
+	al.ReadDefaultList(al.Source.Defaults)
+	al.GetDefaultInstall()
 	al.GetDep()
-	al.PageRank(0.85, 20)
+	al.PageRank(0.85, 100)
 	al.GetDepCount()
 	al.UpdateDistRepoCount(adc)
 	al.CalculateDistImpact()
 	al.UpdateOrInsertDatabase(adc)
 	al.UpdateOrInsertDistDependencyDatabase(adc)
+	al.RecordSnapshot(adc)
 	if outputPath != "" {
-		err := al.GenerateDependencyGraph(outputPath)
+		err := al.GenerateDependencyGraph(outputPath, graphFormat)
 		if err != nil {
 			log.Printf("Error generating dependency graph: %v\n", err)
 			return
@@ -33,39 +40,67 @@ func (al *ArchLinuxCollector) Collect(outputPath string) {
 	}
 }
 
-func (al *ArchLinuxCollector) ParseInfo(data string) {
+func (al *ArchLinuxCollector) ParseInfo(r io.Reader) error {
 	var currentPkg *collector.PackageInfo
-	var depend bool
+	// list whose entries follow, such as %DEPENDS% or %PROVIDES%
+	var list string
+	// header whose value is on the next line
+	var field string
 
-	lines := strings.Split(data, "\n")
-	for idx, line := range lines {
-		switch {
-		case line == "%NAME%":
-			if currentPkg != nil {
-				al.SetPkgInfo(currentPkg.Name, currentPkg)
+	err := collector.ForEachLine(r, func(line string) {
+		if field != "" {
+			value := strings.TrimSpace(line)
+			switch {
+			case field == "%NAME%":
+				if currentPkg != nil {
+					al.SetPkgInfo(currentPkg.Name, currentPkg)
+				}
+				currentPkg = &collector.PackageInfo{Name: value}
+			case currentPkg == nil:
+			case field == "%DESC%":
+				currentPkg.Description = value
+			case field == "%VERSION%":
+				currentPkg.Version = value
+			case field == "%URL%":
+				currentPkg.Homepage = value
+			case field == "%BASE%":
+				currentPkg.Source = value
 			}
-			currentPkg = &collector.PackageInfo{Name: strings.TrimSpace(lines[idx+1])}
-		case line == "%DESC%":
-			currentPkg.Description = strings.TrimSpace(lines[idx+1])
-		case line == "%VERSION%":
-			currentPkg.Version = strings.TrimSpace(lines[idx+1])
-		case line == "%URL%":
-			currentPkg.Homepage = strings.TrimSpace(lines[idx+1])
-		case line == "%DEPENDS%":
-			depend = true
-		case depend && (strings.Contains(line, "%") && line != "%DEPENDS%"):
-			depend = false
-		case depend && line != "":
+			field = ""
+			return
+		}
+
+		switch {
+		case line == "%NAME%" || line == "%DESC%" || line == "%VERSION%" || line == "%URL%" || line == "%BASE%":
+			field = line
+			list = ""
+		case line == "%DEPENDS%" || line == "%PROVIDES%" || line == "%MAKEDEPENDS%" || line == "%CHECKDEPENDS%" || line == "%OPTDEPENDS%":
+			list = line
+		case list != "" && strings.Contains(line, "%"):
+			list = ""
+		case list == "" || line == "" || currentPkg == nil:
+		case list == "%DEPENDS%":
 			currentPkg.DirectDepends = append(currentPkg.DirectDepends, strings.TrimSpace(line))
+		case list == "%PROVIDES%":
+			currentPkg.Provides = append(currentPkg.Provides, strings.TrimSpace(line))
+		case list == "%MAKEDEPENDS%":
+			currentPkg.BuildDepends = append(currentPkg.BuildDepends, strings.TrimSpace(line))
+		case list == "%CHECKDEPENDS%":
+			currentPkg.TestDepends = append(currentPkg.TestDepends, strings.TrimSpace(line))
+		case list == "%OPTDEPENDS%":
+			// "pkg: reason", the reason is dropped by Capability
+			currentPkg.OptionalDepends = append(currentPkg.OptionalDepends, strings.TrimSpace(line))
 		}
-	}
+	})
 	if currentPkg != nil {
 		al.SetPkgInfo(currentPkg.Name, currentPkg)
 	}
+	return err
 }
 
-func NewArchLinuxCollector() *ArchLinuxCollector {
+func NewArchLinuxCollector(mirror string) *ArchLinuxCollector {
 	return &ArchLinuxCollector{
-		collector.NewCollector(repository.Arch, repository.DistPackageTablePrefix("arch")),
+		CollecterInterface: collector.NewCollector(repository.Arch, repository.DistPackageTablePrefix("arch")),
+		Source:             collector.LoadSource("archlinux", collector.ArchlinuxSource, mirror),
 	}
 }
@@ -1,34 +1,34 @@
 package aur
 
 import (
-	"bufio"
 	"encoding/json"
+	"io"
 	"log"
-	"net/http"
-	"strings"
 
 	collector "github.com/HUSTSecLab/criticality_score/pkg/collector/internal"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 )
 
 type AurCollector struct {
 	collector.CollecterInterface
+	Source collector.Source
 }
 
-func (ac *AurCollector) Collect(outputPath string) {
+func (ac *AurCollector) Collect(outputPath string, graphFormat graph.Format) {
 	adc := storage.GetDefaultAppDatabaseContext()
-	data := ac.GetPackageInfo(collector.AurURL)
-	ac.ParseInfo(data)
+	ac.ReadIndexes(ac.Source.URLs(), ac.ParseInfo)
 	ac.GetDep()
-	ac.PageRank(0.85, 20)
+	ac.PageRank(0.85, 100)
 	ac.GetDepCount()
 	ac.UpdateDistRepoCount(adc)
 	ac.CalculateDistImpact()
 	ac.UpdateOrInsertDatabase(adc)
 	ac.UpdateOrInsertDistDependencyDatabase(adc)
+	ac.RecordSnapshot(adc)
 	if outputPath != "" {
-		err := ac.GenerateDependencyGraph(outputPath)
+		err := ac.GenerateDependencyGraph(outputPath, graphFormat)
 		if err != nil {
 			log.Printf("Error generating dependency graph: %v\n", err)
 			return
@@ -36,39 +36,26 @@ func (ac *AurCollector) Collect(outputPath string) {
 	}
 }
 
-func (ac *AurCollector) ParseInfo(data string) error {
-	var packages []collector.PackageInfo
-	err := json.Unmarshal([]byte(data), &packages)
-	if err != nil {
+func (ac *AurCollector) ParseInfo(r io.Reader) error {
+	// the index is one json array, decode it package by package
+	decoder := json.NewDecoder(r)
+	if _, err := decoder.Token(); err != nil {
 		return err
 	}
-
-	for _, pkg := range packages {
+	for decoder.More() {
+		var pkg collector.PackageInfo
+		if err := decoder.Decode(&pkg); err != nil {
+			return err
+		}
 		ac.SetPkgInfo(pkg.Name, &pkg)
 	}
-	return nil
-}
-
-func (ac *AurCollector) GetPackageInfo(urls collector.PackageURL) string {
-	resp, err := http.Get(urls[0])
-	if err != nil {
-		log.Printf("Error making HTTP request: %v\n", err)
-	}
-	defer resp.Body.Close()
-
-	var result strings.Builder
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		result.WriteString(scanner.Text() + "\n")
-	}
-	if err := scanner.Err(); err != nil {
-		log.Printf("Error reading response body: %v\n", err)
-	}
-	return result.String()
+	_, err := decoder.Token()
+	return err
 }
 
-func NewAurCollector() *AurCollector {
+func NewAurCollector(mirror string) *AurCollector {
 	return &AurCollector{
-		collector.NewCollector(repository.Aur, repository.DistPackageTablePrefix("aur")),
+		CollecterInterface: collector.NewCollector(repository.Aur, repository.DistPackageTablePrefix("aur")),
+		Source:             collector.LoadSource("aur", collector.AurSource, mirror),
 	}
 }
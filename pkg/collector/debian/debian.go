@@ -2,31 +2,34 @@ package debian
 
 import (
 	"log"
-	"regexp"
-	"strings"
 
 	collector "github.com/HUSTSecLab/criticality_score/pkg/collector/internal"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 )
 
 type DebianCollector struct {
 	collector.CollecterInterface
+	Source collector.Source
 }
 
-func (dc *DebianCollector) Collect(outputPath string) {
+func (dc *DebianCollector) Collect(outputPath string, graphFormat graph.Format) {
 	adc := storage.GetDefaultAppDatabaseContext()
-	data := dc.GetPackageInfo(collector.DebianURL)
-	dc.ParseInfo(data)
+	dc.ReadIndexes(dc.Source.URLs(), collector.ParsePackages(dc))
+	dc.ReadIndexes(dc.Source.BuildURLs(), collector.ParseSources(dc))
+	dc.ReadDefaultList(dc.Source.Defaults)
+	dc.GetDefaultInstall()
 	dc.GetDep()
-	dc.PageRank(0.85, 20)
+	dc.PageRank(0.85, 100)
 	dc.GetDepCount()
 	dc.UpdateDistRepoCount(adc)
 	dc.CalculateDistImpact()
 	dc.UpdateOrInsertDatabase(adc)
 	dc.UpdateOrInsertDistDependencyDatabase(adc)
+	dc.RecordSnapshot(adc)
 	if outputPath != "" {
-		err := dc.GenerateDependencyGraph(outputPath)
+		err := dc.GenerateDependencyGraph(outputPath, graphFormat)
 		if err != nil {
 			log.Printf("Error generating dependency graph: %v\n", err)
 			return
@@ -34,47 +37,9 @@ func (dc *DebianCollector) Collect(outputPath string) {
 	}
 }
 
-func (dc *DebianCollector) ParseInfo(data string) {
-	var currentPkg *collector.PackageInfo
-	lines := strings.Split(data, "\n")
-	for _, line := range lines {
-		switch {
-		case strings.HasPrefix(line, "Package:"):
-			if currentPkg != nil {
-				dc.SetPkgInfo(currentPkg.Name, currentPkg)
-			}
-			currentPkg = &collector.PackageInfo{Name: strings.TrimSpace(strings.Split(line, ":")[1])}
-		case strings.Contains(line, "Version:"):
-			currentPkg.Version = strings.TrimSpace(strings.Split(line, ":")[1])
-		case strings.Contains(line, "Description:"):
-			currentPkg.Description = strings.TrimSpace(strings.Split(line, ":")[1])
-		case strings.Contains(line, "Homepage:"):
-			currentPkg.Homepage = strings.TrimSpace(strings.Split(line, ":")[1] + ":" + strings.Split(line, ":")[2])
-		case strings.Contains(line, "Depends:"):
-			depLine := strings.TrimPrefix(line, "Depends: ")
-			re := regexp.MustCompile(`[\w\-\.|]+(?:\s*\([^)]+\))?`)
-			matches := re.FindAllString(depLine, -1)
-
-			var cleanedDeps []string
-			for _, match := range matches {
-				for _, subPkg := range strings.Split(match, "|") {
-					subPkg = strings.TrimSpace(subPkg)
-					if idx := strings.Index(subPkg, " ("); idx != -1 {
-						subPkg = subPkg[:idx]
-					}
-					cleanedDeps = append(cleanedDeps, subPkg)
-				}
-			}
-			currentPkg.DirectDepends = cleanedDeps
-		}
-	}
-	if currentPkg != nil {
-		dc.SetPkgInfo(currentPkg.Name, currentPkg)
-	}
-}
-
-func NewDebianCollector() *DebianCollector {
+func NewDebianCollector(mirror string) *DebianCollector {
 	return &DebianCollector{
 		CollecterInterface: collector.NewCollector(repository.Debian, repository.DistPackageTablePrefix("debian")),
+		Source:             collector.LoadSource("debian", collector.DebianSource, mirror),
 	}
 }
@@ -11,15 +11,17 @@ import (
 	"strings"
 
 	collector "github.com/HUSTSecLab/criticality_score/pkg/collector/internal"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 )
 
 type GentooCollector struct {
 	collector.CollecterInterface
+	Source collector.Source
 }
 
-func (hc *GentooCollector) Collect(outputPath string, downloadDir string) {
+func (hc *GentooCollector) Collect(outputPath string, graphFormat graph.Format, downloadDir string) {
 	adc := storage.GetDefaultAppDatabaseContext()
 	err := hc.cloneGentooRepo(downloadDir)
 	if err != nil {
@@ -28,14 +30,15 @@ func (hc *GentooCollector) Collect(outputPath string, downloadDir string) {
 	}
 	hc.ParseInfo(outputPath)
 	hc.GetDep()
-	hc.PageRank(0.85, 20)
+	hc.PageRank(0.85, 100)
 	hc.GetDepCount()
 	hc.UpdateDistRepoCount(adc)
 	hc.CalculateDistImpact()
 	hc.UpdateOrInsertDatabase(adc)
 	hc.UpdateOrInsertDistDependencyDatabase(adc)
+	hc.RecordSnapshot(adc, hc.Source.Mirror)
 	if outputPath != "" {
-		err = hc.GenerateDependencyGraph(outputPath)
+		err = hc.GenerateDependencyGraph(outputPath, graphFormat)
 		if err != nil {
 			log.Printf("Error generating dependency graph: %v\n", err)
 			return
@@ -162,7 +165,7 @@ func (hc *GentooCollector) FetchAndParseEbuildFiles(directory string) error {
 }
 
 func (hc *GentooCollector) cloneGentooRepo(baseDirectory string) error {
-	repoURL := "https://github.com/gentoo/gentoo.git"
+	repoURL := hc.Source.Mirror
 	dir := filepath.Join(baseDirectory)
 
 	if _, err := os.Stat(dir); err == nil {
@@ -194,8 +197,9 @@ func (hc *GentooCollector) ParseInfo(outputPath string) {
 	fmt.Println("Fetched and parsed ebuild files successfully.")
 }
 
-func NewGentooCollector() *GentooCollector {
+func NewGentooCollector(mirror string) *GentooCollector {
 	return &GentooCollector{
 		CollecterInterface: collector.NewCollector(repository.Gentoo, repository.DistPackageTablePrefix("gentoo")),
+		Source:             collector.LoadSource("gentoo", collector.GentooSource, mirror),
 	}
 }
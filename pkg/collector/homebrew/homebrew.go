@@ -11,15 +11,17 @@ import (
 	"strings"
 
 	collector "github.com/HUSTSecLab/criticality_score/pkg/collector/internal"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 )
 
 type HomebrewCollector struct {
 	collector.CollecterInterface
+	Source collector.Source
 }
 
-func (hc *HomebrewCollector) Collect(outputPath string, downloadDir string) {
+func (hc *HomebrewCollector) Collect(outputPath string, graphFormat graph.Format, downloadDir string) {
 	adc := storage.GetDefaultAppDatabaseContext()
 	err := hc.CloneHomebrewRepo(downloadDir)
 	if err != nil {
@@ -28,14 +30,15 @@ func (hc *HomebrewCollector) Collect(outputPath string, downloadDir string) {
 	}
 	hc.ParseInfo(downloadDir)
 	hc.GetDep()
-	hc.PageRank(0.85, 20)
+	hc.PageRank(0.85, 100)
 	hc.GetDepCount()
 	hc.UpdateDistRepoCount(adc)
 	hc.CalculateDistImpact()
 	hc.UpdateOrInsertDatabase(adc)
 	hc.UpdateOrInsertDistDependencyDatabase(adc)
+	hc.RecordSnapshot(adc, hc.Source.Mirror)
 	if outputPath != "" {
-		err = hc.GenerateDependencyGraph(outputPath)
+		err = hc.GenerateDependencyGraph(outputPath, graphFormat)
 		if err != nil {
 			log.Printf("Error generating dependency graph: %v\n", err)
 			return
@@ -44,7 +47,7 @@ func (hc *HomebrewCollector) Collect(outputPath string, downloadDir string) {
 }
 
 func (hc *HomebrewCollector) CloneHomebrewRepo(dir string) error {
-	repoURL := "https://github.com/Homebrew/homebrew-core.git"
+	repoURL := hc.Source.Mirror
 
 	if _, err := os.Stat(dir); err == nil {
 		cmd := exec.Command("git", "-C", dir, "pull")
@@ -108,19 +111,30 @@ func (hc *HomebrewCollector) parseFormulaContent(content string, fileName string
 		pkgInfo.Homepage = match[1]
 	}
 
-	dependsRe := regexp.MustCompile(`depends_on\s+"([^"]+)"`)
+	// e.g. depends_on "cmake" => :build, or => [:build, :test]
+	dependsRe := regexp.MustCompile(`depends_on\s+"([^"]+)"(?:\s*=>\s*(\[[^\]]*\]|:\w+))?`)
 	dependsMatches := dependsRe.FindAllStringSubmatch(content, -1)
 	for _, match := range dependsMatches {
 		if len(match) > 1 {
-			pkgInfo.DirectDepends = append(pkgInfo.DirectDepends, match[1])
+			switch tags := match[2]; {
+			case strings.Contains(tags, ":build"):
+				pkgInfo.BuildDepends = append(pkgInfo.BuildDepends, match[1])
+			case strings.Contains(tags, ":test"):
+				pkgInfo.TestDepends = append(pkgInfo.TestDepends, match[1])
+			case strings.Contains(tags, ":optional"), strings.Contains(tags, ":recommended"):
+				pkgInfo.OptionalDepends = append(pkgInfo.OptionalDepends, match[1])
+			default:
+				pkgInfo.DirectDepends = append(pkgInfo.DirectDepends, match[1])
+			}
 		}
 	}
 
 	return pkgInfo
 }
 
-func NewHomebrewCollector() *HomebrewCollector {
+func NewHomebrewCollector(mirror string) *HomebrewCollector {
 	return &HomebrewCollector{
 		CollecterInterface: collector.NewCollector(repository.Homebrew, repository.DistPackageTablePrefix("homebrew")),
+		Source:             collector.LoadSource("homebrew", collector.HomebrewSource, mirror),
 	}
 }
@@ -1,35 +1,35 @@
 package collector
 
 import (
-	"archive/tar"
-	"bufio"
-	"compress/gzip"
-	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"os"
+	"sort"
 	"strings"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 	"github.com/samber/lo"
 )
 
 type CollecterInterface interface {
-	GetPackageInfo(urls PackageURL) string
+	ReadIndexes(urls PackageURL, parse func(r io.Reader) error)
 	UpdateOrInsertDatabase(ac storage.AppDatabaseContext)
 	UpdateOrInsertDistDependencyDatabase(ac storage.AppDatabaseContext)
-	GenerateDependencyGraph(outputPath string) error
-	GetAllDep(pkgName string, visited map[string]bool, deps []string) []string
+	GenerateDependencyGraph(outputPath string, format graph.Format) error
 	PageRank(d float64, iterations int)
-	ParseInfo(data string)
 	GetDepCount()
 	GetDep()
 	SetPkgInfo(pkgName string, pkgInfo *PackageInfo)
 	GetPkgInfo(pkgName string) *PackageInfo
+	Resolve(dep string) []string
+	MarkDefaultInstall(names ...string)
+	ReadDefaultList(location string)
+	GetDefaultInstall()
 	CalculateDistImpact()
 	UpdateDistRepoCount(ac storage.AppDatabaseContext)
+	RecordSnapshot(ac storage.AppDatabaseContext, sources ...string)
 }
 
 type Collecter struct {
@@ -37,11 +37,39 @@ type Collecter struct {
 	DistRepoCount          int
 	Type                   repository.DistType
 	DistPackageTablePrefix repository.DistPackageTablePrefix
+	// Provides maps a capability, such as a virtual package or a shared
+	// library, to the packages providing it
+	Provides map[string][]string
+	// sourceDeps and sources are the source package graph, built from
+	// PkgInfoMap when first needed, see sourceGraph
+	sourceDeps map[repository.DepKind]map[string][]string
+	sources    map[string][]string
+	// depGraph is the runtime graph of sourceDeps with its condensation,
+	// see dependencyGraph
+	depGraph     *graph.Graph
+	condensation *graph.Condensation
+	// KindMetrics are the metrics of each source package computed on the
+	// build, test and optional dependencies, the runtime ones are kept in
+	// PkgInfoMap
+	KindMetrics map[repository.DepKind]map[string]*DepMetrics
+	// indexes are the locations read by ReadIndexes, the source of the
+	// snapshot, see RecordSnapshot
+	indexes []string
+}
+
+// DepMetrics are the metrics of a source package computed on one kind of
+// dependency.
+type DepMetrics struct {
+	DependsCount int
+	PageRank     float64
+	Impact       float64
 }
 
 func NewCollector(Type repository.DistType, DistPackageTablePrefix repository.DistPackageTablePrefix) CollecterInterface {
 	return &Collecter{
 		PkgInfoMap:             make(map[string]PackageInfo),
+		Provides:               make(map[string][]string),
+		KindMetrics:            make(map[repository.DepKind]map[string]*DepMetrics),
 		Type:                   Type,
 		DistPackageTablePrefix: DistPackageTablePrefix,
 	}
@@ -63,214 +91,311 @@ func (cl *Collecter) UpdateOrInsertDatabase(ac storage.AppDatabaseContext) {
 	}
 }
 
-func (cl *Collecter) GenerateDependencyGraph(outputPath string) error {
+// GenerateDependencyGraph writes the dependency graph of the source
+// packages in the format, with an edge for each kind of dependency.
+func (cl *Collecter) GenerateDependencyGraph(outputPath string, format graph.Format) error {
 	file, err := os.Create(outputPath)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
-
-	writer := bufio.NewWriter(file)
-	writer.WriteString("digraph {\n")
-
-	packageIndices := make(map[string]int)
-	index := 0
-
-	for pkgName, pkgInfo := range cl.PkgInfoMap {
-		packageIndices[pkgName] = index
-		label := fmt.Sprintf("%s@%s", pkgName, pkgInfo.Description)
-		writer.WriteString(fmt.Sprintf("  %d [label=\"%s\"];\n", index, label))
-		index++
+	if err := cl.ExportGraph().Write(file, format); err != nil {
+		return err
 	}
+	return file.Close()
+}
 
-	for pkgName, pkgInfo := range cl.PkgInfoMap {
-		pkgIndex := packageIndices[pkgName]
-		for _, depName := range pkgInfo.IndirectDepends {
-			if depIndex, ok := packageIndices[depName]; ok {
-				writer.WriteString(fmt.Sprintf("  %d -> %d;\n", pkgIndex, depIndex))
+// ExportGraph returns the graph of the source packages with their metrics.
+// A source carries the version of its first binary and the first git link
+// found among its binaries.
+func (cl *Collecter) ExportGraph() *graph.Export {
+	sourceDeps, sources := cl.sourceGraph()
+	names := lo.Keys(sources)
+	sort.Strings(names)
+
+	export := &graph.Export{}
+	for _, source := range names {
+		binaries := sources[source]
+		first := cl.PkgInfoMap[binaries[0]]
+		node := graph.ExportNode{
+			ID:             source,
+			Version:        first.Version,
+			PageRank:       first.PageRank,
+			Impact:         first.Impact,
+			DependentCount: first.DependsCount,
+		}
+		for _, binary := range binaries {
+			if link := cl.PkgInfoMap[binary].Gitlink; link != "" && link != "NA" && link != "NaN" {
+				node.GitLink = link
+				break
 			}
 		}
+		export.Nodes = append(export.Nodes, node)
 	}
-
-	writer.WriteString("}\n")
-	writer.Flush()
-	return nil
+	for _, kind := range repository.DepKinds {
+		for _, source := range names {
+			for _, dep := range sourceDeps[kind][source] {
+				export.Edges = append(export.Edges, graph.ExportEdge{Source: source, Target: dep, Kind: string(kind)})
+			}
+		}
+	}
+	return export
 }
 
-func (cl *Collecter) GetAllDep(pkgName string, visited map[string]bool, deps []string) []string {
-	if visited[pkgName] {
-		return deps
+// Capability returns a dependency or a provide without its version
+// constraint or description, e.g. glibc for glibc>=2.38 and python for
+// "python: for the plugins".
+func Capability(dep string) string {
+	if idx := strings.Index(dep, ": "); idx != -1 {
+		dep = dep[:idx]
+	}
+	if idx := strings.IndexAny(dep, "<>="); idx != -1 {
+		dep = dep[:idx]
 	}
+	return strings.TrimSpace(dep)
+}
 
-	visited[pkgName] = true
-	deps = append(deps, pkgName)
+// Resolve returns the packages satisfying a dependency: the package of the
+// name if there is one, otherwise the packages providing it.
+func (cl *Collecter) Resolve(dep string) []string {
+	name := Capability(dep)
+	if _, ok := cl.PkgInfoMap[name]; ok {
+		return []string{name}
+	}
+	return cl.Provides[name]
+}
 
-	if pkg, ok := cl.PkgInfoMap[pkgName]; ok {
-		for _, depName := range pkg.DirectDepends {
-			deps = cl.GetAllDep(depName, visited, deps)
+// resolveDepends returns the packages the direct dependencies of the kind
+// of a package resolve to, each once and without the package itself.
+func (cl *Collecter) resolveDepends(pkgName string, kind repository.DepKind) []string {
+	pkg, ok := cl.PkgInfoMap[pkgName]
+	if !ok {
+		return nil
+	}
+	seen := map[string]bool{pkgName: true}
+	var resolved []string
+	for _, dep := range pkg.Depends(kind) {
+		for _, name := range cl.Resolve(dep) {
+			if !seen[name] {
+				seen[name] = true
+				resolved = append(resolved, name)
+			}
 		}
 	}
-	return deps
+	return resolved
 }
 
-func (cl *Collecter) PageRank(d float64, iterations int) {
-	ranks := make(map[string]float64)
-	N := float64(len(cl.PkgInfoMap))
-
-	for pkgName := range cl.PkgInfoMap {
-		ranks[pkgName] = 1.0 / N
+// sourceGraph returns the dependencies of each kind between source
+// packages, and the binary packages of each source. A source depends on the
+// sources of the packages its binaries depend on, so an upstream split into
+// many binaries counts once.
+func (cl *Collecter) sourceGraph() (map[repository.DepKind]map[string][]string, map[string][]string) {
+	if cl.sourceDeps != nil {
+		return cl.sourceDeps, cl.sources
 	}
 
-	for i := 0; i < iterations; i++ {
-		newRanks := make(map[string]float64)
-
-		for pkgName := range cl.PkgInfoMap {
-			newRanks[pkgName] = (1 - d) / N
-		}
+	cl.sources = make(map[string][]string)
+	for pkgName, pkgInfo := range cl.PkgInfoMap {
+		source := pkgInfo.SourceName()
+		cl.sources[source] = append(cl.sources[source], pkgName)
+	}
+	for _, binaries := range cl.sources {
+		sort.Strings(binaries)
+	}
 
-		for pkgName, pkgInfo := range cl.PkgInfoMap {
-			var depNum int
-			for _, depName := range pkgInfo.DirectDepends {
-				if _, exists := cl.PkgInfoMap[depName]; exists {
-					depNum++
+	cl.sourceDeps = make(map[repository.DepKind]map[string][]string, len(repository.DepKinds))
+	for _, kind := range repository.DepKinds {
+		kindDeps := make(map[string][]string, len(cl.sources))
+		for source, binaries := range cl.sources {
+			seen := map[string]bool{source: true}
+			var deps []string
+			for _, binary := range binaries {
+				for _, dep := range cl.resolveDepends(binary, kind) {
+					if depSource := cl.PkgInfoMap[dep].SourceName(); !seen[depSource] {
+						seen[depSource] = true
+						deps = append(deps, depSource)
+					}
 				}
 			}
-
-			share := ranks[pkgName] / float64(depNum)
-			for _, dep := range pkgInfo.DirectDepends {
-				if _, exists := cl.PkgInfoMap[dep]; exists {
-					newRanks[dep] += d * share
-				}
+			if len(deps) > 0 || kind == repository.DepKindRuntime {
+				kindDeps[source] = deps
 			}
 		}
-
-		ranks = newRanks
+		cl.sourceDeps[kind] = kindDeps
 	}
+	return cl.sourceDeps, cl.sources
+}
 
-	for pkgName, rank := range ranks {
-		pkgInfo := cl.PkgInfoMap[pkgName]
-		pkgInfo.PageRank = rank
-		cl.PkgInfoMap[pkgName] = pkgInfo
+// depKinds returns the kinds of dependency other than runtime the packages
+// have.
+func (cl *Collecter) depKinds() []repository.DepKind {
+	sourceDeps, _ := cl.sourceGraph()
+	var kinds []repository.DepKind
+	for _, kind := range repository.DepKinds[1:] {
+		if len(sourceDeps[kind]) > 0 {
+			kinds = append(kinds, kind)
+		}
 	}
+	return kinds
 }
 
-func (cl *Collecter) ParseInfo(pkgInfo string) {
-	log.Println("Parsing package info for", pkgInfo)
+// kindMetrics returns the metrics of the source computed on the kind of
+// dependency.
+func (cl *Collecter) kindMetrics(kind repository.DepKind, source string) *DepMetrics {
+	if cl.KindMetrics[kind] == nil {
+		cl.KindMetrics[kind] = make(map[string]*DepMetrics)
+	}
+	m, ok := cl.KindMetrics[kind][source]
+	if !ok {
+		m = &DepMetrics{}
+		cl.KindMetrics[kind][source] = m
+	}
+	return m
 }
 
-func (cl *Collecter) GetPackageInfo(urls PackageURL) string {
-	var result string
-
-	for _, url := range urls {
-		resp, err := http.Get(url)
-		if err != nil {
-			fmt.Println("Error making HTTP request:", err)
-			continue
+// PageRank ranks the source packages, every binary package gets the rank
+// of its source. The ranks are iterated until they converge, at most
+// maxIterations times. The rank of another kind of dependency is computed
+// on the dependencies of that kind together with the runtime ones, as a
+// build dependency is needed with everything it needs to run.
+func (cl *Collecter) PageRank(d float64, maxIterations int) {
+	g, _ := cl.dependencyGraph()
+	sourceDeps, sources := cl.sourceGraph()
+	opts := graph.PageRankOptions{Damping: d, MaxIterations: maxIterations}
+
+	for id, rank := range rankSources(g, opts, repository.DepKindRuntime) {
+		for _, pkgName := range sources[g.Name(id)] {
+			pkgInfo := cl.PkgInfoMap[pkgName]
+			pkgInfo.PageRank = rank
+			cl.PkgInfoMap[pkgName] = pkgInfo
 		}
-		defer resp.Body.Close()
-
-		switch {
-		case strings.HasSuffix(url, ".tar.gz"):
-			gzipReader, err := gzip.NewReader(resp.Body)
-			if err != nil {
-				fmt.Println("Error creating gzip reader:", err)
-				continue
-			}
-			defer gzipReader.Close()
-
-			tarReader := tar.NewReader(gzipReader)
-			var body strings.Builder
-
-			for {
-				_, err := tarReader.Next()
-				if err == io.EOF {
-					break
-				}
-				if err != nil {
-					fmt.Println("Error reading tar content:", err)
-					break
-				}
-
-				buf := new(strings.Builder)
-				if _, err := io.Copy(buf, tarReader); err != nil {
-					fmt.Println("Error extracting tar content:", err)
-					break
-				}
-				body.WriteString(buf.String())
-			}
-			result += body.String()
+	}
 
-		case strings.HasSuffix(url, ".gz"):
-			gzipReader, err := gzip.NewReader(resp.Body)
-			if err != nil {
-				fmt.Println("Error creating gzip reader:", err)
-				continue
+	for _, kind := range cl.depKinds() {
+		// the same nodes as the runtime graph, with the edges of the kind
+		kindGraph := graph.New()
+		for id := 0; id < g.Len(); id++ {
+			kindGraph.Node(g.Name(id))
+		}
+		for id := 0; id < g.Len(); id++ {
+			source := g.Name(id)
+			for _, dep := range lo.Union(sourceDeps[repository.DepKindRuntime][source], sourceDeps[kind][source]) {
+				kindGraph.AddEdge(id, kindGraph.Node(dep))
 			}
-			defer gzipReader.Close()
-
-			reader := bufio.NewReader(gzipReader)
-			var body strings.Builder
+		}
+		for id, rank := range rankSources(kindGraph, opts, kind) {
+			cl.kindMetrics(kind, g.Name(id)).PageRank = rank
+		}
+	}
+}
 
-			for {
-				line, err := reader.ReadString('\n')
-				body.WriteString(line)
-				if err == io.EOF {
-					break
-				}
-				if err != nil {
-					fmt.Println("Error reading response body:", err)
-					break
-				}
-			}
-			result += body.String()
+// rankSources returns the PageRank of the nodes of g, logging ranks that
+// did not converge.
+func rankSources(g *graph.Graph, opts graph.PageRankOptions, kind repository.DepKind) []float64 {
+	result, err := g.PageRank(opts)
+	if err != nil {
+		log.Printf("Error computing %s PageRank: %v\n", kind, err)
+		return nil
+	}
+	if !result.Converged {
+		log.Printf("%s PageRank did not converge in %d iterations, residual %g\n", kind, result.Iterations, result.Residual)
+	}
+	return result.Ranks
+}
 
-		default:
-			fmt.Println("Unsupported file type:", url)
+// ReadIndexes opens every index, decompressed, and passes it to parse.
+// An index which cannot be read is logged and skipped.
+func (cl *Collecter) ReadIndexes(urls PackageURL, parse func(r io.Reader) error) {
+	for _, url := range urls {
+		cl.indexes = append(cl.indexes, url)
+		index, err := OpenIndex(url)
+		if err != nil {
+			log.Println("Error opening package index:", url, err)
 			continue
 		}
+		if err := parse(index); err != nil {
+			log.Println("Error parsing package index:", url, err)
+		}
+		index.Close()
 	}
-
-	return result
 }
 
+// GetDepCount counts the source packages depending on each source package,
+// directly or not and itself included, every binary package gets the count
+// of its source. For another kind of dependency, a source depends on its
+// dependencies of that kind and on everything they depend on at runtime.
 func (cl *Collecter) GetDepCount() {
-	countMap := make(map[string]int)
-
-	for _, deps := range cl.PkgInfoMap {
-		for _, dep := range deps.IndirectDepends {
-			if idx := strings.Index(dep, ">"); idx != -1 {
-				dep = dep[:idx]
-			} else if idx := strings.Index(dep, "="); idx != -1 {
-				dep = dep[:idx]
-			} else if idx := strings.Index(dep, "<"); idx != -1 {
-				dep = dep[:idx]
-			}
-			countMap[dep]++
-		}
-	}
+	g, c := cl.dependencyGraph()
+	sourceDeps, sources := cl.sourceGraph()
 
-	for pkgName, count := range countMap {
-		if pkgInfo, ok := cl.PkgInfoMap[pkgName]; ok {
+	for id, count := range c.DependentCounts() {
+		for _, pkgName := range sources[g.Name(id)] {
+			pkgInfo := cl.PkgInfoMap[pkgName]
 			pkgInfo.DependsCount = count
 			cl.PkgInfoMap[pkgName] = pkgInfo
 		}
 	}
+
+	for _, kind := range cl.depKinds() {
+		var roots [][]int
+		for _, deps := range sourceDeps[kind] {
+			root := make([]int, 0, len(deps))
+			for _, dep := range deps {
+				id, _ := g.Lookup(dep)
+				root = append(root, id)
+			}
+			roots = append(roots, root)
+		}
+		for id, count := range c.ReachCounts(roots) {
+			if count > 0 {
+				cl.kindMetrics(kind, g.Name(id)).DependsCount = count
+			}
+		}
+	}
 }
 
+// GetDep builds the runtime dependency graph of the source packages the
+// dependency counts are computed on.
 func (cl *Collecter) GetDep() {
-	for pkgName := range cl.PkgInfoMap {
-		visited := make(map[string]bool)
-		deps := cl.GetAllDep(pkgName, visited, []string{})
-		pkgInfo := cl.PkgInfoMap[pkgName]
-		pkgInfo.IndirectDepends = deps
-		cl.PkgInfoMap[pkgName] = pkgInfo
+	cl.dependencyGraph()
+}
+
+// dependencyGraph returns the graph of the runtime dependencies between
+// source packages, with the cycles condensed, built when first needed.
+func (cl *Collecter) dependencyGraph() (*graph.Graph, *graph.Condensation) {
+	if cl.depGraph != nil {
+		return cl.depGraph, cl.condensation
+	}
+
+	sourceDeps, sources := cl.sourceGraph()
+	names := lo.Keys(sources)
+	sort.Strings(names)
+	g := graph.New()
+	for _, source := range names {
+		g.Node(source)
 	}
+	for _, source := range names {
+		from := g.Node(source)
+		for _, dep := range sourceDeps[repository.DepKindRuntime][source] {
+			g.AddEdge(from, g.Node(dep))
+		}
+	}
+	cl.depGraph, cl.condensation = g, g.Condense()
+	return cl.depGraph, cl.condensation
 }
 
 func (cl *Collecter) SetPkgInfo(pkgName string, pkgInfo *PackageInfo) {
 	pkgInfo.Type = cl.Type
 	pkgInfo.DistPackageTablePrefix = cl.DistPackageTablePrefix
 	cl.PkgInfoMap[pkgName] = *pkgInfo
+	cl.sourceDeps, cl.sources = nil, nil
+	cl.depGraph, cl.condensation = nil, nil
+	for _, capability := range pkgInfo.Provides {
+		capability = Capability(capability)
+		if capability != "" && capability != pkgName && !lo.Contains(cl.Provides[capability], pkgName) {
+			cl.Provides[capability] = append(cl.Provides[capability], pkgName)
+		}
+	}
 }
 
 func (cl *Collecter) GetPkgInfo(pkgName string) *PackageInfo {
@@ -281,30 +406,130 @@ func (cl *Collecter) GetPkgInfo(pkgName string) *PackageInfo {
 }
 
 func (cl *Collecter) UpdateOrInsertDistDependencyDatabase(ac storage.AppDatabaseContext) {
-	var distMap = make(map[string]*repository.DistDependency)
+	kinds := cl.depKinds()
+	distMaps := map[repository.DepKind]map[string]*repository.DistDependency{
+		repository.DepKindRuntime: make(map[string]*repository.DistDependency),
+	}
+	for _, kind := range kinds {
+		distMaps[kind] = make(map[string]*repository.DistDependency)
+	}
+	// binaries of a source share its values, they count once per link
+	counted := make(map[[2]string]bool)
 	for _, pkgInfo := range cl.PkgInfoMap {
 		if pkgInfo.Name == "" {
 			continue
 		}
 
 		pkgInfo.GetGitlinkByPkg(ac)
-		distPackage := pkgInfo.ParseDistLinkInfo()
+		// kept for GenerateDependencyGraph
+		cl.PkgInfoMap[pkgInfo.Name] = pkgInfo
 		if pkgInfo.Gitlink != "" && pkgInfo.Gitlink != "NA" && pkgInfo.Gitlink != "NaN" {
-			if _, ok := distMap[*distPackage.GitLink]; !ok {
-				distMap[*distPackage.GitLink] = distPackage
-			} else {
-				distMap[*distPackage.GitLink].DepCount = lo.ToPtr(*distMap[*distPackage.GitLink].DepCount + *distPackage.DepCount)
-				distMap[*distPackage.GitLink].DepImpact = lo.ToPtr(*distMap[*distPackage.GitLink].DepImpact + *distPackage.DepImpact)
-				distMap[*distPackage.GitLink].PageRank = lo.ToPtr(*distMap[*distPackage.GitLink].PageRank + *distPackage.PageRank)
+			if d, ok := distMaps[repository.DepKindRuntime][pkgInfo.Gitlink]; ok && pkgInfo.DefaultInstall {
+				d.DefaultInstall = lo.ToPtr(true)
+			}
+			key := [2]string{pkgInfo.Gitlink, pkgInfo.SourceName()}
+			if counted[key] {
+				continue
+			}
+			counted[key] = true
+			addDistDependency(distMaps[repository.DepKindRuntime], pkgInfo.ParseDistLinkInfo())
+			for _, kind := range kinds {
+				m := cl.kindMetrics(kind, pkgInfo.SourceName())
+				addDistDependency(distMaps[kind], &repository.DistDependency{
+					GitLink:   lo.ToPtr(pkgInfo.Gitlink),
+					Type:      lo.ToPtr(pkgInfo.Type),
+					DepImpact: lo.ToPtr(m.Impact),
+					DepCount:  lo.ToPtr(m.DependsCount),
+					PageRank:  lo.ToPtr(m.PageRank),
+					Kind:      lo.ToPtr(kind),
+				})
 			}
 		}
 	}
 
-	for _, distPackage := range distMap {
-		repo := repository.NewDistDependencyRepository(ac)
-		err := repo.InsertOrUpdate(distPackage)
-		if err != nil {
-			log.Println("Error inserting package info into database:", err)
+	repo := repository.NewDistDependencyRepository(ac)
+	for _, distMap := range distMaps {
+		for _, distPackage := range distMap {
+			err := repo.InsertOrUpdate(distPackage)
+			if err != nil {
+				log.Println("Error inserting package info into database:", err)
+			}
+		}
+	}
+}
+
+// addDistDependency adds the values of a source to the row of its link
+func addDistDependency(distMap map[string]*repository.DistDependency, distPackage *repository.DistDependency) {
+	if _, ok := distMap[*distPackage.GitLink]; !ok {
+		distMap[*distPackage.GitLink] = distPackage
+	} else {
+		distMap[*distPackage.GitLink].DepCount = lo.ToPtr(*distMap[*distPackage.GitLink].DepCount + *distPackage.DepCount)
+		distMap[*distPackage.GitLink].DepImpact = lo.ToPtr(*distMap[*distPackage.GitLink].DepImpact + *distPackage.DepImpact)
+		distMap[*distPackage.GitLink].PageRank = lo.ToPtr(*distMap[*distPackage.GitLink].PageRank + *distPackage.PageRank)
+	}
+}
+
+// MarkDefaultInstall marks the packages, or the packages providing them,
+// as installed by default.
+func (cl *Collecter) MarkDefaultInstall(names ...string) {
+	for _, name := range names {
+		resolved := cl.Resolve(name)
+		if len(resolved) == 0 {
+			log.Println("Default package not found:", name)
+		}
+		for _, pkgName := range resolved {
+			pkgInfo := cl.PkgInfoMap[pkgName]
+			pkgInfo.DefaultInstall = true
+			cl.PkgInfoMap[pkgName] = pkgInfo
+		}
+	}
+}
+
+// ReadDefaultList marks the packages listed in the file at location, one
+// per line, as installed by default. Empty lines and lines starting with #
+// are skipped, an empty location reads nothing.
+func (cl *Collecter) ReadDefaultList(location string) {
+	if location == "" {
+		return
+	}
+	list, err := OpenIndex(location)
+	if err != nil {
+		log.Println("Error opening default package list:", location, err)
+		return
+	}
+	defer list.Close()
+
+	var names []string
+	err = ForEachLine(list, func(line string) {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	})
+	if err != nil {
+		log.Println("Error reading default package list:", location, err)
+	}
+	cl.MarkDefaultInstall(names...)
+}
+
+// GetDefaultInstall marks every package a package installed by default
+// depends on, directly or not, as installed by default.
+func (cl *Collecter) GetDefaultInstall() {
+	var queue []string
+	for pkgName, pkgInfo := range cl.PkgInfoMap {
+		if pkgInfo.DefaultInstall {
+			queue = append(queue, pkgName)
+		}
+	}
+	for len(queue) > 0 {
+		pkgName := queue[0]
+		queue = queue[1:]
+		for _, dep := range cl.resolveDepends(pkgName, repository.DepKindRuntime) {
+			if pkgInfo := cl.PkgInfoMap[dep]; !pkgInfo.DefaultInstall {
+				pkgInfo.DefaultInstall = true
+				cl.PkgInfoMap[dep] = pkgInfo
+				queue = append(queue, dep)
+			}
 		}
 	}
 }
@@ -314,6 +539,11 @@ func (cl *Collecter) CalculateDistImpact() {
 		pkgInfo.CalculateImpact(cl.DistRepoCount)
 		cl.PkgInfoMap[pkgInfo.Name] = pkgInfo
 	}
+	for _, metrics := range cl.KindMetrics {
+		for _, m := range metrics {
+			m.Impact = float64(m.DependsCount) / float64(cl.DistRepoCount)
+		}
+	}
 }
 
 func (cl *Collecter) UpdateDistRepoCount(ac storage.AppDatabaseContext) {
@@ -326,3 +556,54 @@ func (cl *Collecter) UpdateDistRepoCount(ac storage.AppDatabaseContext) {
 
 	cl.DistRepoCount = count
 }
+
+// RecordSnapshot records a dated snapshot of the packages and their metrics,
+// to be called once the git links are known, after
+// UpdateOrInsertDistDependencyDatabase. The source of the snapshot are the
+// sources, or the indexes read by ReadIndexes if none are given.
+func (cl *Collecter) RecordSnapshot(ac storage.AppDatabaseContext, sources ...string) {
+	if len(sources) == 0 {
+		sources = cl.indexes
+	}
+	snapshot := &repository.DistSnapshot{
+		Type:   lo.ToPtr(cl.Type),
+		Source: lo.ToPtr(strings.Join(sources, "\n")),
+	}
+	packages := cl.snapshotPackages()
+	snapshot.PackageCount = lo.ToPtr(len(packages))
+
+	repo := repository.NewDistSnapshotRepository(ac)
+	if err := repo.Create(snapshot, packages); err != nil {
+		log.Println("Error recording package snapshot:", err)
+		return
+	}
+	log.Printf("Recorded snapshot %d of %d packages\n", *snapshot.ID, len(packages))
+}
+
+// snapshotPackages returns the packages of a snapshot sorted by name
+func (cl *Collecter) snapshotPackages() []*repository.DistSnapshotPackage {
+	names := lo.Keys(cl.PkgInfoMap)
+	sort.Strings(names)
+
+	packages := make([]*repository.DistSnapshotPackage, 0, len(names))
+	for _, name := range names {
+		pkgInfo := cl.PkgInfoMap[name]
+		if pkgInfo.Name == "" {
+			continue
+		}
+		p := &repository.DistSnapshotPackage{
+			Package:        lo.ToPtr(pkgInfo.Name),
+			SourcePackage:  lo.ToPtr(pkgInfo.SourceName()),
+			Version:        lo.ToPtr(pkgInfo.Version),
+			DependsCount:   lo.ToPtr(pkgInfo.DependsCount),
+			PageRank:       lo.ToPtr(pkgInfo.PageRank),
+			Impact:         lo.ToPtr(pkgInfo.Impact),
+			DefaultInstall: lo.ToPtr(pkgInfo.DefaultInstall),
+		}
+		if pkgInfo.Gitlink != "" && pkgInfo.Gitlink != "NA" && pkgInfo.Gitlink != "NaN" {
+			p.GitLink = lo.ToPtr(pkgInfo.Gitlink)
+		}
+		packages = append(packages, p)
+	}
+	return packages
+}
@@ -0,0 +1,249 @@
+package collector
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+func newTestCollecter(pkgs ...PackageInfo) *Collecter {
+	cl := NewCollector(repository.Debian, repository.DistLinkTablePrefixDebian).(*Collecter)
+	for _, pkg := range pkgs {
+		cl.SetPkgInfo(pkg.Name, &pkg)
+	}
+	return cl
+}
+
+func TestResolve(t *testing.T) {
+	cl := newTestCollecter(
+		PackageInfo{Name: "musl", Provides: []string{"so:libc.musl-x86_64.so.1=1", "cmd:ldd=1.2.5-r0"}},
+		PackageInfo{Name: "postfix", Provides: []string{"mail-transport-agent"}},
+		PackageInfo{Name: "exim4", Provides: []string{"mail-transport-agent", "exim4"}},
+	)
+	tests := []struct {
+		dep  string
+		want []string
+	}{
+		{"musl", []string{"musl"}},
+		{"musl>=1.2", []string{"musl"}},
+		{"so:libc.musl-x86_64.so.1", []string{"musl"}},
+		{"cmd:ldd", []string{"musl"}},
+		{"mail-transport-agent", []string{"postfix", "exim4"}},
+		{"exim4", []string{"exim4"}},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		if got := cl.Resolve(tt.dep); !slices.Equal(got, tt.want) {
+			t.Errorf("Resolve(%q) = %v, want %v", tt.dep, got, tt.want)
+		}
+	}
+}
+
+func TestDependsThroughProvides(t *testing.T) {
+	cl := newTestCollecter(
+		PackageInfo{Name: "libfoo1", Provides: []string{"libfoo.so.1()(64bit)"}, DirectDepends: []string{"libfoo.so.1()(64bit)"}},
+		PackageInfo{Name: "app", DirectDepends: []string{"libfoo.so.1()(64bit)", "libfoo1", "missing"}},
+		PackageInfo{Name: "tool", DirectDepends: []string{"app>=1.0"}},
+	)
+	cl.GetDep()
+	cl.GetDepCount()
+	cl.PageRank(0.85, 100)
+
+	if got := cl.PkgInfoMap["app"].DependsCount; got != 2 {
+		t.Errorf("DependsCount of app = %d, want 2", got)
+	}
+	if got := cl.PkgInfoMap["libfoo1"].DependsCount; got != 3 {
+		t.Errorf("DependsCount of libfoo1 = %d, want 3", got)
+	}
+	if cl.PkgInfoMap["libfoo1"].PageRank <= cl.PkgInfoMap["app"].PageRank {
+		t.Errorf("PageRank of libfoo1 %f is not above app %f",
+			cl.PkgInfoMap["libfoo1"].PageRank, cl.PkgInfoMap["app"].PageRank)
+	}
+}
+
+func TestParseProvides(t *testing.T) {
+	got := ParseProvides(" mail-transport-agent, libfoo (= 1.0),")
+	if !slices.Equal(got, []string{"mail-transport-agent", "libfoo"}) {
+		t.Errorf("ParseProvides() = %v", got)
+	}
+}
+
+func TestSourceAggregation(t *testing.T) {
+	cl := newTestCollecter(
+		PackageInfo{Name: "libc6", Source: "glibc"},
+		PackageInfo{Name: "libc-bin", Source: "glibc", DirectDepends: []string{"libc6"}},
+		PackageInfo{Name: "app1", DirectDepends: []string{"libc6"}},
+		PackageInfo{Name: "app2", DirectDepends: []string{"libc-bin", "libc6"}},
+		PackageInfo{Name: "tool", DirectDepends: []string{"app1"}},
+	)
+	cl.GetDep()
+	cl.GetDepCount()
+	cl.PageRank(0.85, 100)
+
+	if got := cl.PkgInfoMap["app1"].DependsCount; got != 2 {
+		t.Errorf("DependsCount of app1 = %d, want 2", got)
+	}
+	for _, name := range []string{"libc6", "libc-bin"} {
+		if got := cl.PkgInfoMap[name].DependsCount; got != 4 {
+			t.Errorf("DependsCount of %s = %d, want 4", name, got)
+		}
+	}
+	if cl.PkgInfoMap["libc6"].PageRank != cl.PkgInfoMap["libc-bin"].PageRank {
+		t.Errorf("binaries of glibc ranked differently")
+	}
+	var total float64
+	for _, source := range []string{"libc6", "app1", "app2", "tool"} {
+		total += cl.PkgInfoMap[source].PageRank
+	}
+	if math.Abs(total-1) > 1e-6 {
+		t.Errorf("PageRank of the sources sums to %f, want 1", total)
+	}
+}
+
+func TestDefaultInstall(t *testing.T) {
+	cl := newTestCollecter(
+		PackageInfo{Name: "base", DirectDepends: []string{"bash", "sh"}},
+		PackageInfo{Name: "bash", DirectDepends: []string{"readline>=8"}},
+		PackageInfo{Name: "readline"},
+		PackageInfo{Name: "dash", Provides: []string{"sh"}},
+		PackageInfo{Name: "essential", DefaultInstall: true, DirectDepends: []string{"zlib"}},
+		PackageInfo{Name: "zlib"},
+		PackageInfo{Name: "vim", DirectDepends: []string{"bash"}},
+	)
+	cl.MarkDefaultInstall("base", "missing")
+	cl.GetDefaultInstall()
+
+	for name, want := range map[string]bool{
+		"base": true, "bash": true, "readline": true, "dash": true,
+		"essential": true, "zlib": true, "vim": false,
+	} {
+		if got := cl.PkgInfoMap[name].DefaultInstall; got != want {
+			t.Errorf("DefaultInstall of %s = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestParseDefaultInstall(t *testing.T) {
+	for field, want := range map[string]bool{
+		"Priority: required":  true,
+		"Priority: important": true,
+		"Priority: optional":  false,
+		"Essential: yes":      true,
+		"Essential: no":       false,
+	} {
+		if got := ParseDefaultInstall(field); got != want {
+			t.Errorf("ParseDefaultInstall(%q) = %v, want %v", field, got, want)
+		}
+	}
+}
+
+func TestDependsKinds(t *testing.T) {
+	cl := newTestCollecter(
+		PackageInfo{Name: "libc"},
+		PackageInfo{Name: "gcc", DirectDepends: []string{"libc"}},
+		PackageInfo{Name: "pytest", DirectDepends: []string{"libc"}},
+		PackageInfo{Name: "app", DirectDepends: []string{"libc"}, BuildDepends: []string{"gcc"}, TestDepends: []string{"pytest"}},
+		PackageInfo{Name: "lib", BuildDepends: []string{"gcc"}},
+	)
+	if got := cl.depKinds(); !slices.Equal(got, []repository.DepKind{repository.DepKindBuild, repository.DepKindTest}) {
+		t.Fatalf("depKinds() = %v", got)
+	}
+	cl.GetDep()
+	cl.GetDepCount()
+	cl.PageRank(0.85, 100)
+	cl.DistRepoCount = 5
+	cl.CalculateDistImpact()
+
+	if got := cl.PkgInfoMap["gcc"].DependsCount; got != 1 {
+		t.Errorf("runtime DependsCount of gcc = %d, want 1", got)
+	}
+	build := cl.KindMetrics[repository.DepKindBuild]
+	// app and lib build with gcc, and so with the libc it runs on
+	if got := build["gcc"].DependsCount; got != 2 {
+		t.Errorf("build DependsCount of gcc = %d, want 2", got)
+	}
+	if got := build["libc"].DependsCount; got != 2 {
+		t.Errorf("build DependsCount of libc = %d, want 2", got)
+	}
+	if got := build["gcc"].Impact; got != 0.4 {
+		t.Errorf("build Impact of gcc = %f, want 0.4", got)
+	}
+	if build["gcc"].PageRank <= cl.PkgInfoMap["gcc"].PageRank {
+		t.Errorf("build PageRank of gcc %f is not above its runtime one %f",
+			build["gcc"].PageRank, cl.PkgInfoMap["gcc"].PageRank)
+	}
+	if m := cl.KindMetrics[repository.DepKindTest]["pytest"]; m == nil || m.DependsCount != 1 {
+		t.Errorf("test metrics of pytest = %+v, want a count of 1", m)
+	}
+	if _, ok := cl.KindMetrics[repository.DepKindOptional]; ok {
+		t.Error("metrics computed for optional dependencies, none are declared")
+	}
+}
+
+func TestDependsCycle(t *testing.T) {
+	cl := newTestCollecter(
+		PackageInfo{Name: "python3", DirectDepends: []string{"python3-pip", "libc6"}},
+		PackageInfo{Name: "python3-pip", DirectDepends: []string{"python3"}},
+		PackageInfo{Name: "app", DirectDepends: []string{"python3-pip"}},
+		PackageInfo{Name: "libc6"},
+	)
+	cl.GetDep()
+	cl.GetDepCount()
+
+	for name, want := range map[string]int{"app": 1, "python3": 3, "python3-pip": 3, "libc6": 4} {
+		if got := cl.PkgInfoMap[name].DependsCount; got != want {
+			t.Errorf("DependsCount of %s = %d, want %d", name, got, want)
+		}
+	}
+}
+
+func TestExportGraph(t *testing.T) {
+	cl := newTestCollecter(
+		PackageInfo{Name: "libc6", Source: "glibc", Version: "2.36"},
+		PackageInfo{Name: "libc-bin", Source: "glibc", Gitlink: "https://sourceware.org/git/glibc.git"},
+		PackageInfo{Name: "gcc", DirectDepends: []string{"libc6"}},
+		PackageInfo{Name: "app", DirectDepends: []string{"libc-bin"}, BuildDepends: []string{"gcc"}},
+	)
+	cl.GetDep()
+	cl.GetDepCount()
+
+	export := cl.ExportGraph()
+	if len(export.Nodes) != 3 {
+		t.Fatalf("nodes = %+v, want one per source", export.Nodes)
+	}
+	glibc := export.Nodes[2]
+	if glibc.ID != "glibc" || glibc.GitLink != "https://sourceware.org/git/glibc.git" || glibc.DependentCount != 3 {
+		t.Errorf("glibc = %+v", glibc)
+	}
+	want := []graph.ExportEdge{
+		{Source: "app", Target: "glibc", Kind: "runtime"},
+		{Source: "gcc", Target: "glibc", Kind: "runtime"},
+		{Source: "app", Target: "gcc", Kind: "build"},
+	}
+	if !slices.Equal(export.Edges, want) {
+		t.Errorf("edges = %+v, want %+v", export.Edges, want)
+	}
+}
+
+func TestSnapshotPackages(t *testing.T) {
+	cl := newTestCollecter(
+		PackageInfo{Name: "libc6", Source: "glibc", Version: "2.36", Gitlink: "https://sourceware.org/git/glibc.git"},
+		PackageInfo{Name: "app", DirectDepends: []string{"libc6"}, Gitlink: "NA"},
+	)
+	cl.GetDep()
+	cl.GetDepCount()
+
+	packages := cl.snapshotPackages()
+	if len(packages) != 2 || *packages[0].Package != "app" || *packages[1].Package != "libc6" {
+		t.Fatalf("packages = %+v, want app and libc6", packages)
+	}
+	if app := packages[0]; app.GitLink != nil || *app.SourcePackage != "app" {
+		t.Errorf("app has git link %v and source %s, want none and app", app.GitLink, *app.SourcePackage)
+	}
+	if libc := packages[1]; *libc.SourcePackage != "glibc" || *libc.DependsCount != 2 || *libc.Version != "2.36" {
+		t.Errorf("libc6 = source %s, count %d, version %s", *libc.SourcePackage, *libc.DependsCount, *libc.Version)
+	}
+}
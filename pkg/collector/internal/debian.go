@@ -0,0 +1,172 @@
+package collector
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// buildDependsFields are the fields of a Debian Sources index listing the
+// build dependencies of a source package
+var buildDependsFields = []string{"Build-Depends", "Build-Depends-Indep", "Build-Depends-Arch"}
+
+// splitRelations splits a Debian relation field into its packages, the
+// alternatives of an "a | b" relation each count, e.g.
+// libc6 (>= 2.36), default-mta | mail-transport-agent
+func splitRelations(field string) []string {
+	var relations []string
+	for _, relation := range strings.Split(field, ",") {
+		for _, alternative := range strings.Split(relation, "|") {
+			if alternative = strings.TrimSpace(alternative); alternative != "" {
+				relations = append(relations, alternative)
+			}
+		}
+	}
+	return relations
+}
+
+// relationName returns the package of a relation without its version,
+// architectures, build profiles and architecture qualifier, e.g.
+// python3 for python3:any (>= 3.11) [amd64] <!nocheck>
+func relationName(relation string) string {
+	if idx := strings.IndexAny(relation, "([<"); idx != -1 {
+		relation = relation[:idx]
+	}
+	if idx := strings.Index(relation, ":"); idx != -1 {
+		relation = relation[:idx]
+	}
+	return strings.TrimSpace(relation)
+}
+
+// ParseDepends parses a Debian relation field such as Depends, Recommends
+// or Build-Depends into the packages it names.
+func ParseDepends(field string) []string {
+	var depends []string
+	for _, relation := range splitRelations(field) {
+		if name := relationName(relation); name != "" {
+			depends = append(depends, name)
+		}
+	}
+	return depends
+}
+
+// ParsePackages returns a parser of a Debian Packages index, as used by
+// Debian, Ubuntu and Deepin. Fields are matched by name, so a description
+// mentioning a field adds nothing.
+func ParsePackages(cl CollecterInterface) func(r io.Reader) error {
+	return func(r io.Reader) error {
+		return forEachStanza(r, func(fields map[string]string) {
+			name := fields["Package"]
+			if name == "" {
+				return
+			}
+			pkgInfo := &PackageInfo{
+				Name:        name,
+				Version:     fields["Version"],
+				Description: Truncate(fields["Description"], 255),
+				Homepage:    fields["Homepage"],
+				Provides:    ParseProvides(fields["Provides"]),
+				// the version follows in parentheses if it differs
+				Source: strings.TrimSpace(strings.Split(fields["Source"], "(")[0]),
+				DefaultInstall: ParseDefaultInstall("Priority:"+fields["Priority"]) ||
+					ParseDefaultInstall("Essential:"+fields["Essential"]),
+			}
+			for _, field := range []string{"Pre-Depends", "Depends"} {
+				pkgInfo.DirectDepends = append(pkgInfo.DirectDepends, ParseDepends(fields[field])...)
+			}
+			for _, field := range []string{"Recommends", "Suggests"} {
+				pkgInfo.OptionalDepends = append(pkgInfo.OptionalDepends, ParseDepends(fields[field])...)
+			}
+			cl.SetPkgInfo(name, pkgInfo)
+		})
+	}
+}
+
+// ParseSources returns a parser of a Debian Sources index. The build
+// dependencies of each source package are added to its binary packages
+// read before, those only needed to run the tests, marked <!nocheck>, as
+// test dependencies.
+func ParseSources(cl CollecterInterface) func(r io.Reader) error {
+	return func(r io.Reader) error {
+		return forEachStanza(r, func(fields map[string]string) {
+			addSourceDepends(cl, fields)
+		})
+	}
+}
+
+// forEachStanza reads a Deb822 index stanza by stanza, passing the fields
+// of each keyed by name. Continuation lines are joined to their field,
+// except those of Description, which keeps its synopsis only.
+func forEachStanza(r io.Reader, fn func(fields map[string]string)) error {
+	fields := make(map[string]string)
+	var field string
+	flush := func() {
+		if len(fields) > 0 {
+			fn(fields)
+		}
+		fields = make(map[string]string)
+		field = ""
+	}
+
+	err := ForEachLine(r, func(line string) {
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+		case line[0] == ' ' || line[0] == '\t':
+			// a continuation of the field above
+			if field != "" && field != "Description" {
+				fields[field] += " " + strings.TrimSpace(line)
+			}
+		default:
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				return
+			}
+			field = name
+			fields[field] = strings.TrimSpace(value)
+		}
+	})
+	flush()
+	return err
+}
+
+// Truncate cuts s to at most n bytes without splitting a character
+func Truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func addSourceDepends(cl CollecterInterface, fields map[string]string) {
+	var build, test []string
+	for _, field := range buildDependsFields {
+		for _, relation := range splitRelations(fields[field]) {
+			name := relationName(relation)
+			if name == "" {
+				continue
+			}
+			if strings.Contains(relation, "<!nocheck>") {
+				test = append(test, name)
+			} else {
+				build = append(build, name)
+			}
+		}
+	}
+	if len(build) == 0 && len(test) == 0 {
+		return
+	}
+
+	for _, binary := range strings.Split(fields["Binary"], ",") {
+		pkgInfo := cl.GetPkgInfo(strings.TrimSpace(binary))
+		if pkgInfo == nil {
+			continue
+		}
+		pkgInfo.BuildDepends = append(pkgInfo.BuildDepends, build...)
+		pkgInfo.TestDepends = append(pkgInfo.TestDepends, test...)
+		cl.SetPkgInfo(pkgInfo.Name, pkgInfo)
+	}
+}
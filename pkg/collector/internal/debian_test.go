@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseDepends(t *testing.T) {
+	got := ParseDepends(" libc6 (>= 2.36), default-mta | mail-transport-agent, libstdc++6, python3:any [amd64] <!nocheck>")
+	want := []string{"libc6", "default-mta", "mail-transport-agent", "libstdc++6", "python3"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ParseDepends() = %v, want %v", got, want)
+	}
+}
+
+const packagesIndex = `Package: curl
+Version: 8.11.1-1
+Pre-Depends: libc6 (>= 2.36)
+Depends: libcurl4t64 (= 8.11.1-1),
+ zlib1g
+Recommends: ca-certificates
+Description: command line tool for transferring data with URL syntax
+ Package: not-a-package
+ Depends: not-a-dependency
+Homepage: https://curl.se/
+Source: curl (8.11.1-1+b1)
+Priority: optional
+
+Package: dash
+Version: 0.5.12-9
+Provides: sh
+Essential: yes
+Description: POSIX-compliant shell, Depends on nothing
+`
+
+func TestParsePackages(t *testing.T) {
+	cl := newTestCollecter()
+	if err := ParsePackages(cl)(strings.NewReader(packagesIndex)); err != nil {
+		t.Fatal(err)
+	}
+	if len(cl.PkgInfoMap) != 2 {
+		t.Fatalf("packages = %v, want curl and dash", cl.PkgInfoMap)
+	}
+	curl := cl.PkgInfoMap["curl"]
+	if want := []string{"libc6", "libcurl4t64", "zlib1g"}; !slices.Equal(curl.DirectDepends, want) {
+		t.Errorf("DirectDepends of curl = %v, want %v", curl.DirectDepends, want)
+	}
+	if want := []string{"ca-certificates"}; !slices.Equal(curl.OptionalDepends, want) {
+		t.Errorf("OptionalDepends of curl = %v, want %v", curl.OptionalDepends, want)
+	}
+	if curl.Version != "8.11.1-1" || curl.Source != "curl" || curl.Homepage != "https://curl.se/" || curl.DefaultInstall {
+		t.Errorf("curl = %+v", curl)
+	}
+	if curl.Description != "command line tool for transferring data with URL syntax" {
+		t.Errorf("Description of curl = %q, want the synopsis", curl.Description)
+	}
+	dash := cl.PkgInfoMap["dash"]
+	if !dash.DefaultInstall || len(dash.DirectDepends) != 0 || !slices.Equal(cl.Resolve("sh"), []string{"dash"}) {
+		t.Errorf("dash = %+v", dash)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("héllo", 2); got != "h" {
+		t.Errorf("Truncate() = %q, want %q", got, "h")
+	}
+	if got := Truncate("hello", 10); got != "hello" {
+		t.Errorf("Truncate() = %q", got)
+	}
+}
+
+const sourcesIndex = `Package: curl
+Binary: curl, libcurl4t64,
+ libcurl4-doc
+Version: 8.11.1-1
+Build-Depends: debhelper-compat (= 13),
+ libssl-dev,
+ python3:native <!nocheck>
+Build-Depends-Indep: groff-base
+
+Package: orphan
+Binary: missing
+Build-Depends: make
+`
+
+func TestParseSources(t *testing.T) {
+	cl := newTestCollecter(
+		PackageInfo{Name: "curl", Source: "curl"},
+		PackageInfo{Name: "libcurl4-doc", Source: "curl"},
+	)
+	if err := ParseSources(cl)(strings.NewReader(sourcesIndex)); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"curl", "libcurl4-doc"} {
+		pkg := cl.PkgInfoMap[name]
+		if want := []string{"debhelper-compat", "libssl-dev", "groff-base"}; !slices.Equal(pkg.BuildDepends, want) {
+			t.Errorf("BuildDepends of %s = %v, want %v", name, pkg.BuildDepends, want)
+		}
+		if want := []string{"python3"}; !slices.Equal(pkg.TestDepends, want) {
+			t.Errorf("TestDepends of %s = %v, want %v", name, pkg.TestDepends, want)
+		}
+	}
+	if _, ok := cl.PkgInfoMap["missing"]; ok {
+		t.Error("a binary not read from the Packages index was added")
+	}
+}
@@ -0,0 +1,189 @@
+package collector
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// MaxLineSize is the longest line ForEachLine accepts, such as a long
+// Depends field.
+const MaxLineSize = 16 * 1024 * 1024
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	xzMagic   = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	tarMagic  = []byte("ustar")
+)
+
+// OpenIndex opens a package index like Open and decompresses it, see
+// Decompress.
+func OpenIndex(location string) (io.ReadCloser, error) {
+	f, err := Open(location)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Decompress(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &multiCloser{Reader: r, closers: []io.Closer{r, f}}, nil
+}
+
+// Decompress returns the content of r, decompressed as long as it is gzip,
+// xz or zstd, detected by the magic bytes. The content of a tar archive is
+// the content of its regular files one after another, each followed by a
+// newline. Nothing is read ahead but a small buffer, so indexes of any size
+// stream through.
+func Decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(512)
+
+	var inner io.Reader
+	var closer io.Closer
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		inner, closer = zr, zr
+	case bytes.HasPrefix(head, xzMagic):
+		zr, err := xz.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		inner = zr
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		inner, closer = zr, zstdCloser{zr}
+	case len(head) >= 262 && bytes.Equal(head[257:262], tarMagic):
+		return io.NopCloser(&tarFiles{tr: tar.NewReader(br)}), nil
+	default:
+		return io.NopCloser(br), nil
+	}
+
+	// the decompressed content may be a tar archive or compressed again
+	decoded, err := Decompress(inner)
+	if err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, err
+	}
+	closers := []io.Closer{decoded}
+	if closer != nil {
+		closers = append(closers, closer)
+	}
+	return &multiCloser{Reader: decoded, closers: closers}, nil
+}
+
+// ForEachLine calls fn with every line of r, without the line break.
+func ForEachLine(r io.Reader, fn func(line string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), MaxLineSize)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// tarFiles reads the regular files of a tar archive one after another
+type tarFiles struct {
+	tr *tar.Reader
+	// inFile is set while reading a file, the newline after it is pending
+	inFile bool
+	done   bool
+}
+
+func (t *tarFiles) Read(p []byte) (int, error) {
+	for !t.done {
+		if t.inFile {
+			n, err := t.tr.Read(p)
+			if n > 0 {
+				return n, nil
+			}
+			if err != io.EOF {
+				return 0, err
+			}
+			t.inFile = false
+			if len(p) == 0 {
+				return 0, nil
+			}
+			p[0] = '\n'
+			return 1, nil
+		}
+
+		hdr, err := t.tr.Next()
+		if err == io.EOF {
+			t.done = true
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		t.inFile = hdr.Typeflag == tar.TypeReg
+	}
+	return 0, io.EOF
+}
+
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *multiCloser) Close() error {
+	var first error
+	for _, c := range m.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// zstdCloser adapts zstd.Decoder, whose Close returns nothing
+type zstdCloser struct {
+	d *zstd.Decoder
+}
+
+func (z zstdCloser) Close() error {
+	z.d.Close()
+	return nil
+}
+
+// StripNUL returns a reader of r without NUL bytes, which some indexes
+// contain although XML forbids them.
+func StripNUL(r io.Reader) io.Reader {
+	return &nulStripper{r: r}
+}
+
+type nulStripper struct {
+	r io.Reader
+}
+
+func (s *nulStripper) Read(p []byte) (int, error) {
+	for {
+		n, err := s.r.Read(p)
+		kept := 0
+		for _, b := range p[:n] {
+			if b != 0 {
+				p[kept] = b
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package collector
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+func gzipData(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarData(t *testing.T, files map[string]string, names ...string) []byte {
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	w.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755})
+	for _, name := range names {
+		w.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(files[name]))})
+		w.Write([]byte(files[name]))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	plain := []byte("Package: a\nDepends: b\n")
+
+	var xzBuf bytes.Buffer
+	xw, _ := xz.NewWriter(&xzBuf)
+	xw.Write(plain)
+	xw.Close()
+
+	var zstdBuf bytes.Buffer
+	zw, _ := zstd.NewWriter(&zstdBuf)
+	zw.Write(plain)
+	zw.Close()
+
+	files := map[string]string{"dir/a/desc": "%NAME%\na", "dir/b/desc": "%NAME%\nb\n"}
+	archive := tarData(t, files, "dir/a/desc", "dir/b/desc")
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"plain", plain, string(plain)},
+		{"gzip", gzipData(t, plain), string(plain)},
+		{"xz", xzBuf.Bytes(), string(plain)},
+		{"zstd", zstdBuf.Bytes(), string(plain)},
+		{"tar.gz", gzipData(t, archive), "%NAME%\na\n%NAME%\nb\n\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := Decompress(bytes.NewReader(test.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("Decompress() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReadIndexes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Packages"), []byte("Package: a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Packages.gz"), gzipData(t, []byte("Package: b\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	cl := &Collecter{}
+	cl.ReadIndexes(PackageURL{
+		filepath.Join(dir, "Packages"),
+		"file://" + filepath.Join(dir, "Packages.gz"),
+		filepath.Join(dir, "missing"),
+	}, func(r io.Reader) error {
+		return ForEachLine(r, func(line string) { lines = append(lines, line) })
+	})
+	if got := strings.Join(lines, ","); got != "Package: a,Package: b" {
+		t.Errorf("lines = %q, want both indexes", got)
+	}
+}
+
+func TestStripNUL(t *testing.T) {
+	got, _ := io.ReadAll(StripNUL(strings.NewReader("\x00<a>\x00\x00b</a>")))
+	if string(got) != "<a>b</a>" {
+		t.Errorf("StripNUL() = %q", got)
+	}
+}
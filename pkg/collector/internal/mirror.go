@@ -0,0 +1,161 @@
+package collector
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Source describes where the package indexes of a distribution are read
+// from. Mirror is a base URL, a file:// URL or a local directory. Path is
+// the path of an index below the mirror, {release} and {component} are
+// replaced by every release and component, e.g.
+//
+//	sources:
+//	  ubuntu:
+//	    mirror: /srv/mirror/ubuntu
+//	    releases: [jammy, noble]
+//	    components: [main, universe]
+//	    overrides:
+//	      - {release: noble, component: universe, mirror: "file:///mnt/noble"}
+type Source struct {
+	Mirror     string   `mapstructure:"mirror"`
+	Releases   []string `mapstructure:"releases"`
+	Components []string `mapstructure:"components"`
+	Path       string   `mapstructure:"path"`
+	// Mirrors of some releases or components, the first matching override
+	// is used
+	Overrides []SourceOverride `mapstructure:"overrides"`
+	// Location of a list of the packages installed by default, one per
+	// line, see Collecter.ReadDefaultList
+	Defaults string `mapstructure:"defaults"`
+	// Path of the index of the source packages, holding their build
+	// dependencies, in the same form as Path
+	BuildPath string `mapstructure:"build_path"`
+}
+
+// SourceOverride replaces the mirror of a release and component of a
+// Source, an empty release or component matches any.
+type SourceOverride struct {
+	Release   string `mapstructure:"release"`
+	Component string `mapstructure:"component"`
+	Mirror    string `mapstructure:"mirror"`
+}
+
+// LoadSource returns the source of the distribution in the config file,
+// under sources.<name>, with the fields not set there taken from def. The
+// mirror, if not empty, overrides the mirror of the config file.
+func LoadSource(name string, def Source, mirror string) Source {
+	var source Source
+	if err := viper.UnmarshalKey("sources."+name, &source); err != nil {
+		log.Printf("Error reading source of %s: %v\n", name, err)
+		source = Source{}
+	}
+	if mirror != "" {
+		source.Mirror = mirror
+	}
+	if source.Mirror == "" {
+		source.Mirror = def.Mirror
+	}
+	if source.Releases == nil {
+		source.Releases = def.Releases
+	}
+	if source.Components == nil {
+		source.Components = def.Components
+	}
+	if source.Path == "" {
+		source.Path = def.Path
+	}
+	if source.Overrides == nil {
+		source.Overrides = def.Overrides
+	}
+	if source.Defaults == "" {
+		source.Defaults = def.Defaults
+	}
+	if source.BuildPath == "" {
+		source.BuildPath = def.BuildPath
+	}
+	return source
+}
+
+// MirrorFor returns the mirror of the release and component.
+func (s *Source) MirrorFor(release, component string) string {
+	for _, o := range s.Overrides {
+		if (o.Release == "" || o.Release == release) && (o.Component == "" || o.Component == component) {
+			return o.Mirror
+		}
+	}
+	return s.Mirror
+}
+
+// URLs returns the URL of the index of every release and component.
+// A source without releases or components has one index for them.
+func (s *Source) URLs() PackageURL {
+	return s.urls(s.Path)
+}
+
+// BuildURLs returns the URL of the index of the source packages of every
+// release and component, or none if the source has no BuildPath.
+func (s *Source) BuildURLs() PackageURL {
+	if s.BuildPath == "" {
+		return nil
+	}
+	return s.urls(s.BuildPath)
+}
+
+func (s *Source) urls(pattern string) PackageURL {
+	releases, components := s.Releases, s.Components
+	if len(releases) == 0 {
+		releases = []string{""}
+	}
+	if len(components) == 0 {
+		components = []string{""}
+	}
+
+	var urls PackageURL
+	for _, release := range releases {
+		for _, component := range components {
+			path := strings.NewReplacer("{release}", release, "{component}", component).Replace(pattern)
+			mirror := s.MirrorFor(release, component)
+			if path == "" {
+				urls = append(urls, mirror)
+			} else {
+				urls = append(urls, strings.TrimSuffix(mirror, "/")+"/"+strings.TrimPrefix(path, "/"))
+			}
+		}
+	}
+	return urls
+}
+
+// Open opens an index given by an http(s) URL, a file:// URL or a local
+// path.
+func Open(location string) (io.ReadCloser, error) {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+		// no scheme, or a windows drive letter
+		return os.Open(location)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return os.Open(u.Path)
+	case "http", "https":
+		resp, err := http.Get(location)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return resp.Body, nil
+	default:
+		return nil, fmt.Errorf("unsupported scheme %s", u.Scheme)
+	}
+}
@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestSourceURLs(t *testing.T) {
+	s := Source{
+		Mirror:     "https://mirror.example/ubuntu/",
+		Releases:   []string{"jammy", "noble"},
+		Components: []string{"main", "universe"},
+		Path:       "dists/{release}/{component}/binary-amd64/Packages.gz",
+		Overrides:  []SourceOverride{{Release: "noble", Component: "universe", Mirror: "/srv/noble"}},
+	}
+	want := PackageURL{
+		"https://mirror.example/ubuntu/dists/jammy/main/binary-amd64/Packages.gz",
+		"https://mirror.example/ubuntu/dists/jammy/universe/binary-amd64/Packages.gz",
+		"https://mirror.example/ubuntu/dists/noble/main/binary-amd64/Packages.gz",
+		"/srv/noble/dists/noble/universe/binary-amd64/Packages.gz",
+	}
+	if got := s.URLs(); !slices.Equal(got, want) {
+		t.Errorf("URLs() = %v, want %v", got, want)
+	}
+
+	git := Source{Mirror: "https://github.com/gentoo/gentoo.git"}
+	if got := git.URLs(); !slices.Equal(got, PackageURL{git.Mirror}) {
+		t.Errorf("URLs() = %v, want the mirror", got)
+	}
+}
+
+func TestLoadSource(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("sources.debian.mirror", "file:///srv/debian")
+	viper.Set("sources.debian.releases", []string{"bookworm"})
+
+	s := LoadSource("debian", DebianSource, "")
+	if s.Mirror != "file:///srv/debian" || !slices.Equal(s.Releases, []string{"bookworm"}) {
+		t.Errorf("LoadSource() = %+v, want the configured mirror and releases", s)
+	}
+	if s.Path != DebianSource.Path || !slices.Equal(s.Components, DebianSource.Components) {
+		t.Errorf("LoadSource() = %+v, want the default path and components", s)
+	}
+	if s := LoadSource("debian", DebianSource, "/srv/local"); s.Mirror != "/srv/local" || !slices.Equal(s.Releases, []string{"bookworm"}) {
+		t.Errorf("LoadSource() = %+v, want the given mirror and the configured releases", s)
+	}
+	if !slices.Equal(DebianSource.Releases, []string{"stable"}) {
+		t.Errorf("LoadSource() changed the default releases to %v", DebianSource.Releases)
+	}
+}
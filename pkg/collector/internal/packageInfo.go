@@ -2,9 +2,11 @@ package collector
 
 import (
 	"log"
+	"strings"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
 )
 
 type PackageInfoInterface interface {
@@ -16,7 +18,6 @@ type PackageInfoInterface interface {
 
 type PackageInfo struct {
 	DirectDepends          []string `json:"Depends"`
-	IndirectDepends        []string
 	DependsCount           int
 	Description            string
 	Homepage               string `json:"URL"`
@@ -27,58 +28,122 @@ type PackageInfo struct {
 	Gitlink                string
 	Type                   repository.DistType
 	DistPackageTablePrefix repository.DistPackageTablePrefix
+	// Capabilities the package provides besides its name, resolved by
+	// Collecter.Resolve
+	Provides []string
+	// Source package the binary package is built from, empty if it is the
+	// package itself
+	Source string `json:"PackageBase"`
+	// Whether the package is installed by default, set for the packages a
+	// distribution installs by default and spread to their dependencies by
+	// Collecter.GetDefaultInstall
+	DefaultInstall bool `json:"-"`
+	// Dependencies of the other kinds, DirectDepends are the runtime ones.
+	// The json names are those of the AUR metadata.
+	BuildDepends    []string `json:"MakeDepends"`
+	TestDepends     []string `json:"CheckDepends"`
+	OptionalDepends []string `json:"OptDepends"`
+}
+
+// Depends returns the direct dependencies of the kind.
+func (pkg PackageInfo) Depends(kind repository.DepKind) []string {
+	switch kind {
+	case repository.DepKindBuild:
+		return pkg.BuildDepends
+	case repository.DepKindTest:
+		return pkg.TestDepends
+	case repository.DepKindOptional:
+		return pkg.OptionalDepends
+	}
+	return pkg.DirectDepends
+}
+
+// SourceName returns the name of the source package of the package.
+func (pkg PackageInfo) SourceName() string {
+	if pkg.Source != "" {
+		return pkg.Source
+	}
+	return pkg.Name
 }
 
 type PackageURL []string
 
+// ParseProvides parses a Debian style Provides field, e.g.
+// mail-transport-agent, libfoo (= 1.0)
+func ParseProvides(field string) []string {
+	var provides []string
+	for _, provide := range strings.Split(field, ",") {
+		if idx := strings.Index(provide, "("); idx != -1 {
+			provide = provide[:idx]
+		}
+		if provide = strings.TrimSpace(provide); provide != "" {
+			provides = append(provides, provide)
+		}
+	}
+	return provides
+}
+
+// ParseDefaultInstall reports whether a Debian style Priority or Essential
+// field marks a package installed by default.
+func ParseDefaultInstall(field string) bool {
+	_, value, _ := strings.Cut(field, ":")
+	switch strings.TrimSpace(value) {
+	case "required", "important", "yes":
+		return true
+	}
+	return false
+}
+
+// Default sources of the package indexes of each distribution, they can be
+// changed in the config file, see LoadSource.
 var (
-	FedoraURL = PackageURL{
-		"https://mirrors.aliyun.com/fedora/releases/41/Everything/source/tree/repodata/df7750a80c5a4e4ff04ff5a1a499d32b6379dd50680b29140638e6edb1d71d68-primary.xml.gz",
-	}
-	DebianURL = PackageURL{
-		"https://mirrors.hust.edu.cn/debian/dists/stable/main/binary-amd64/Packages.gz",
-	}
-	CentOSURL = PackageURL{
-		"https://mirrors.aliyun.com/centos/7/os/x86_64/repodata/2b479c0f3efa73f75b7fb76c82687744275fff78e4a138b5b3efba95f91e099e-primary.xml.gz",
-	}
-	GentooURL = PackageURL{
-		"https://github.com/gentoo/gentoo.git",
-	}
-	HomebrewURL = PackageURL{
-		"https://github.com/Homebrew/homebrew-core.git",
-	}
-	UbuntuURL = PackageURL{
-		"https://mirrors.hust.edu.cn/ubuntu/dists/jammy/main/binary-amd64/Packages.gz",
-		"https://mirrors.hust.edu.cn/ubuntu/dists/jammy/universe/binary-amd64/Packages.gz",
-		"https://mirrors.hust.edu.cn/ubuntu/dists/jammy/multiverse/binary-amd64/Packages.gz",
-		"https://mirrors.hust.edu.cn/ubuntu/dists/jammy/restricted/binary-amd64/Packages.gz",
-	}
-	AlpineURL = PackageURL{
-		"https://mirrors.aliyun.com/alpine/v3.21/main/x86_64/APKINDEX.tar.gz",
-	}
-	ArchlinuxURL = PackageURL{
-		"https://mirrors.hust.edu.cn/archlinux/community/os/x86_64/community.files.tar.gz",
-		"https://mirrors.hust.edu.cn/archlinux/community-staging/os/x86_64/community-staging.files.tar.gz",
-		"https://mirrors.hust.edu.cn/archlinux/community-testing/os/x86_64/community-testing.files.tar.gz",
-		"https://mirrors.hust.edu.cn/archlinux/core/os/x86_64/core.files.tar.gz",
-		"https://mirrors.hust.edu.cn/archlinux/core-staging/os/x86_64/core-staging.files.tar.gz",
-		"https://mirrors.hust.edu.cn/archlinux/core-testing/os/x86_64/core-testing.files.tar.gz",
-		"https://mirrors.hust.edu.cn/archlinux/extra/os/x86_64/extra.files.tar.gz",
-		"https://mirrors.hust.edu.cn/archlinux/extra-staging/os/x86_64/extra-staging.files.tar.gz",
-		"https://mirrors.hust.edu.cn/archlinux/extra-testing/os/x86_64/extra-testing.files.tar.gz",
-		"https://mirrors.hust.edu.cn/archlinux/gnome-unstable/os/x86_64/gnome-unstable.files.tar.gz",
-		"https://mirrors.hust.edu.cn/archlinux/kde-unstable/os/x86_64/kde-unstable.files.tar.gz",
-		"https://mirrors.hust.edu.cn/archlinux/multilib/os/x86_64/multilib.files.tar.gz",
-		"https://mirrors.hust.edu.cn/archlinux/multilib-staging/os/x86_64/multilib-staging.files.tar.gz",
-		"https://mirrors.hust.edu.cn/archlinux/multilib-testing/os/x86_64/multilib-testing.files.tar.gz",
-		"https://mirrors.hust.edu.cn/archlinux/staging/os/x86_64/staging.files.tar.gz",
-		"https://mirrors.hust.edu.cn/archlinux/testing/os/x86_64/testing.files.tar.gz",
-	}
-	AurURL = PackageURL{
-		"https://aur.archlinux.org/packages-meta-ext-v1.json.gz",
-	}
-	DeepinURL = PackageURL{
-		"https://mirrors.hust.edu.cn/deepin/beige/dists/beige/main/binary-amd64/Packages.gz",
+	DebianSource = Source{
+		Mirror:     "https://mirrors.hust.edu.cn/debian",
+		Releases:   []string{"stable"},
+		Components: []string{"main"},
+		Path:       "dists/{release}/{component}/binary-amd64/Packages.gz",
+		BuildPath:  "dists/{release}/{component}/source/Sources.gz",
+	}
+	GentooSource = Source{
+		Mirror: "https://github.com/gentoo/gentoo.git",
+	}
+	HomebrewSource = Source{
+		Mirror: "https://github.com/Homebrew/homebrew-core.git",
+	}
+	UbuntuSource = Source{
+		Mirror:     "https://mirrors.hust.edu.cn/ubuntu",
+		Releases:   []string{"jammy"},
+		Components: []string{"main", "universe", "multiverse", "restricted"},
+		Path:       "dists/{release}/{component}/binary-amd64/Packages.gz",
+		BuildPath:  "dists/{release}/{component}/source/Sources.gz",
+	}
+	AlpineSource = Source{
+		Mirror:     "https://mirrors.aliyun.com/alpine",
+		Releases:   []string{"v3.21"},
+		Components: []string{"main"},
+		Path:       "{release}/{component}/x86_64/APKINDEX.tar.gz",
+	}
+	ArchlinuxSource = Source{
+		Mirror: "https://mirrors.hust.edu.cn/archlinux",
+		Components: []string{
+			"community", "community-staging", "community-testing",
+			"core", "core-staging", "core-testing",
+			"extra", "extra-staging", "extra-testing",
+			"gnome-unstable", "kde-unstable",
+			"multilib", "multilib-staging", "multilib-testing",
+			"staging", "testing",
+		},
+		Path: "{component}/os/x86_64/{component}.files.tar.gz",
+	}
+	AurSource = Source{
+		Mirror: "https://aur.archlinux.org",
+		Path:   "packages-meta-ext-v1.json.gz",
+	}
+	DeepinSource = Source{
+		Mirror:     "https://mirrors.hust.edu.cn/deepin/beige",
+		Releases:   []string{"beige"},
+		Components: []string{"main"},
+		Path:       "dists/{release}/{component}/binary-amd64/Packages.gz",
 	}
 )
 
@@ -91,6 +156,8 @@ func (pkg *PackageInfo) ParseDistPackage() *repository.DistPackage {
 		Description: &pkg.Description,
 		HomePage:    &pkg.Homepage,
 		Version:     &pkg.Version,
+
+		DefaultInstall: lo.ToPtr(lo.Ternary(pkg.DefaultInstall, 1, 0)),
 	}
 }
 
@@ -101,6 +168,8 @@ func (pkg *PackageInfo) ParseDistLinkInfo() *repository.DistDependency {
 		Type:      &pkg.Type,
 		DepCount:  &pkg.DependsCount,
 		PageRank:  &pkg.PageRank,
+
+		DefaultInstall: &pkg.DefaultInstall,
 	}
 }
 
@@ -12,6 +12,7 @@ import (
 	"unicode"
 
 	collector "github.com/HUSTSecLab/criticality_score/pkg/collector/internal"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 )
@@ -20,7 +21,7 @@ type NixCollector struct {
 	collector.CollecterInterface
 }
 
-func (nc *NixCollector) Collect(workerCount int, batchSize int, outputPath string) {
+func (nc *NixCollector) Collect(workerCount int, batchSize int, outputPath string, graphFormat graph.Format) {
 	adc := storage.GetDefaultAppDatabaseContext()
 	err := nc.ParseInfo(workerCount)
 	if err != nil {
@@ -28,14 +29,16 @@ func (nc *NixCollector) Collect(workerCount int, batchSize int, outputPath strin
 		return
 	}
 	nc.GetDep()
-	nc.PageRank(0.85, 20)
+	nc.PageRank(0.85, 100)
 	nc.GetDepCount()
 	nc.UpdateDistRepoCount(adc)
 	nc.CalculateDistImpact()
 	nc.UpdateOrInsertDatabase(adc)
 	nc.UpdateOrInsertDistDependencyDatabase(adc)
+	// the packages are those of the nixpkgs channel of nix-env
+	nc.RecordSnapshot(adc, "<nixpkgs>")
 	if outputPath != "" {
-		err = nc.GenerateDependencyGraph(outputPath)
+		err = nc.GenerateDependencyGraph(outputPath, graphFormat)
 		if err != nil {
 			log.Printf("Error generating dependency graph: %v\n", err)
 			return
@@ -0,0 +1,552 @@
+// Package rpm collects the packages of RPM based distributions from the
+// repodata of their repositories. The indexes of a repository are found in
+// repodata/repomd.xml, so their content hashed names are never configured.
+package rpm
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+
+	collector "github.com/HUSTSecLab/criticality_score/pkg/collector/internal"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/spf13/viper"
+)
+
+// Types of the metadata listed in repomd.xml. The changelogs of other.xml
+// are not read.
+const (
+	DataPrimary   = "primary"
+	DataFilelists = "filelists"
+	// comps.xml, compressed or not
+	DataGroupGz = "group_gz"
+	DataGroup   = "group"
+)
+
+// DefaultGroups are the comps groups installed on every system, their
+// mandatory and default packages are installed by default.
+var DefaultGroups = []string{"core", "base"}
+
+// Distribution is an RPM based distribution. The mirror, releases and
+// components of its Source give the base URLs of its repositories, the
+// directories holding repodata/. The repositories of the source rpms, at
+// the BuildPath of the Source, give the build dependencies.
+type Distribution struct {
+	Name        string
+	Type        repository.DistType
+	TablePrefix repository.DistPackageTablePrefix
+	Source      collector.Source
+}
+
+// Distributions are the built-in RPM based distributions keyed by name
+var Distributions = map[string]Distribution{
+	"fedora": {
+		Name:        "fedora",
+		Type:        repository.Fedora,
+		TablePrefix: repository.DistLinkTablePrefixFedora,
+		Source: collector.Source{
+			Mirror:     "https://mirrors.aliyun.com/fedora",
+			Releases:   []string{"41"},
+			Components: []string{"Everything"},
+			Path:       "releases/{release}/{component}/x86_64/os",
+			BuildPath:  "releases/{release}/{component}/source/tree",
+		},
+	},
+	"centos": {
+		Name:        "centos",
+		Type:        repository.Centos,
+		TablePrefix: repository.DistLinkTablePrefixCentos,
+		Source: collector.Source{
+			Mirror:     "https://mirrors.aliyun.com/centos",
+			Releases:   []string{"7"},
+			Components: []string{"os"},
+			Path:       "{release}/{component}/x86_64",
+		},
+	},
+	"rocky": {
+		Name:        "rocky",
+		Type:        repository.Rocky,
+		TablePrefix: repository.DistLinkTablePrefixRocky,
+		Source: collector.Source{
+			Mirror:     "https://mirrors.aliyun.com/rockylinux",
+			Releases:   []string{"9"},
+			Components: []string{"BaseOS", "AppStream"},
+			Path:       "{release}/{component}/x86_64/os",
+			BuildPath:  "{release}/{component}/source/tree",
+		},
+	},
+	"alma": {
+		Name:        "alma",
+		Type:        repository.Alma,
+		TablePrefix: repository.DistLinkTablePrefixAlma,
+		Source: collector.Source{
+			Mirror:     "https://mirrors.aliyun.com/almalinux",
+			Releases:   []string{"9"},
+			Components: []string{"BaseOS", "AppStream"},
+			Path:       "{release}/{component}/x86_64/os",
+		},
+	},
+	"openeuler": {
+		Name:        "openeuler",
+		Type:        repository.OpenEuler,
+		TablePrefix: repository.DistLinkTablePrefixOpenEuler,
+		Source: collector.Source{
+			Mirror:     "https://mirrors.hust.edu.cn/openeuler",
+			Releases:   []string{"openEuler-24.03-LTS"},
+			Components: []string{"everything"},
+			Path:       "{release}/{component}/x86_64",
+			BuildPath:  "{release}/source",
+		},
+	},
+	"opensuse": {
+		Name:        "opensuse",
+		Type:        repository.OpenSUSE,
+		TablePrefix: repository.DistLinkTablePrefixOpenSUSE,
+		Source: collector.Source{
+			Mirror:     "https://mirrors.hust.edu.cn/opensuse",
+			Releases:   []string{"tumbleweed"},
+			Components: []string{"oss"},
+			Path:       "{release}/repo/{component}",
+			BuildPath:  "{release}/repo/src-{component}",
+		},
+	},
+}
+
+// Names returns the names of the built-in distributions, sorted.
+func Names() []string {
+	names := make([]string, 0, len(Distributions))
+	for name := range Distributions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupDistribution returns the distribution of the name, either a
+// built-in one or one declared in the config file, e.g.
+//
+//	rpm_distributions:
+//	  oracle: {type: 5, table: centos}
+//	sources:
+//	  oracle:
+//	    mirror: https://yum.oracle.com/repo/OracleLinux
+//	    releases: [OL9]
+//	    components: [baseos/latest]
+//	    path: "{release}/{component}/x86_64"
+//
+// where type is the distribution type of its dependencies and table the
+// prefix of its packages table. The source is read with LoadSource, with
+// the mirror overriding the configured one if not empty.
+func LookupDistribution(name, mirror string) (Distribution, bool) {
+	d, ok := Distributions[name]
+	if key := "rpm_distributions." + name; viper.IsSet(key) {
+		var declared struct {
+			Type  int    `mapstructure:"type"`
+			Table string `mapstructure:"table"`
+		}
+		if err := viper.UnmarshalKey(key, &declared); err != nil {
+			log.Printf("Error reading rpm distribution %s: %v\n", name, err)
+			return d, ok
+		}
+		d.Name = name
+		d.Type = repository.DistType(declared.Type)
+		d.TablePrefix = repository.DistPackageTablePrefix(declared.Table)
+		ok = true
+	}
+	if ok {
+		d.Source = collector.LoadSource(name, d.Source, mirror)
+	}
+	return d, ok
+}
+
+// Repomd is the index of the metadata of a repository, repodata/repomd.xml
+type Repomd struct {
+	Data []RepomdData `xml:"data"`
+}
+
+type RepomdData struct {
+	Type     string `xml:"type,attr"`
+	Location struct {
+		// Base overrides the repository base URL for this metadata
+		Base string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"location"`
+}
+
+// ReadRepomd reads repodata/repomd.xml of the repository at base.
+func ReadRepomd(base string) (*Repomd, error) {
+	index, err := collector.OpenIndex(joinURL(base, "repodata/repomd.xml"))
+	if err != nil {
+		return nil, err
+	}
+	defer index.Close()
+
+	repomd := &Repomd{}
+	if err := xml.NewDecoder(index).Decode(repomd); err != nil {
+		return nil, fmt.Errorf("failed to parse repomd.xml of %s: %w", base, err)
+	}
+	return repomd, nil
+}
+
+// Location returns the location of the metadata of the type in the
+// repository at base, or an empty string if it is not listed.
+func (r *Repomd) Location(base, dataType string) string {
+	for _, d := range r.Data {
+		if d.Type != dataType {
+			continue
+		}
+		if d.Location.Base != "" {
+			base = d.Location.Base
+		}
+		return joinURL(base, d.Location.Href)
+	}
+	return ""
+}
+
+func joinURL(base, path string) string {
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+type RPMCollector struct {
+	collector.CollecterInterface
+	Distribution Distribution
+	// binaries are the binary packages of each source rpm
+	binaries map[string][]string
+	// repomds are the repomd.xml read of each repository, nil if it cannot
+	// be read, so each is read once per collection
+	repomds map[string]*Repomd
+}
+
+func (rc *RPMCollector) Collect(outputPath string, graphFormat graph.Format) {
+	adc := storage.GetDefaultAppDatabaseContext()
+	rc.ReadIndexes(rc.MetadataURLs(DataPrimary), rc.ParseInfo)
+	rc.ReadIndexes(rc.MetadataURLs(DataGroupGz, DataGroup), rc.ParseComps)
+	rc.ReadIndexes(rc.BuildMetadataURLs(DataPrimary), rc.ParseBuildInfo)
+	// after every require is known, only the required files are kept
+	rc.ReadIndexes(rc.MetadataURLs(DataFilelists), rc.ParseFilelists)
+	rc.ReadDefaultList(rc.Distribution.Source.Defaults)
+	rc.GetDefaultInstall()
+	rc.GetDep()
+	rc.PageRank(0.85, 100)
+	rc.GetDepCount()
+	rc.UpdateDistRepoCount(adc)
+	rc.CalculateDistImpact()
+	rc.UpdateOrInsertDatabase(adc)
+	rc.UpdateOrInsertDistDependencyDatabase(adc)
+	rc.RecordSnapshot(adc)
+	if outputPath != "" {
+		err := rc.GenerateDependencyGraph(outputPath, graphFormat)
+		if err != nil {
+			log.Printf("Error generating dependency graph: %v\n", err)
+			return
+		}
+	}
+}
+
+// MetadataURLs returns the location of the metadata of the first of the
+// types listed in every repository of the distribution. Repositories whose
+// repomd.xml cannot be read or lists none of the types are logged and
+// skipped.
+func (rc *RPMCollector) MetadataURLs(dataTypes ...string) collector.PackageURL {
+	return rc.metadataURLs(rc.Distribution.Source.URLs(), dataTypes)
+}
+
+// BuildMetadataURLs is MetadataURLs of the repositories of the source rpms.
+func (rc *RPMCollector) BuildMetadataURLs(dataTypes ...string) collector.PackageURL {
+	return rc.metadataURLs(rc.Distribution.Source.BuildURLs(), dataTypes)
+}
+
+func (rc *RPMCollector) metadataURLs(bases collector.PackageURL, dataTypes []string) collector.PackageURL {
+	var urls collector.PackageURL
+	for _, base := range bases {
+		repomd := rc.repomd(base)
+		if repomd == nil {
+			continue
+		}
+		var location string
+		for _, dataType := range dataTypes {
+			if location = repomd.Location(base, dataType); location != "" {
+				break
+			}
+		}
+		if location == "" {
+			log.Printf("Repository %s has no %s metadata\n", base, strings.Join(dataTypes, " or "))
+			continue
+		}
+		urls = append(urls, location)
+	}
+	return urls
+}
+
+// repomd returns repomd.xml of the repository at base, read on the first
+// call, or nil if it cannot be read.
+func (rc *RPMCollector) repomd(base string) *Repomd {
+	if repomd, ok := rc.repomds[base]; ok {
+		return repomd
+	}
+	repomd, err := ReadRepomd(base)
+	if err != nil {
+		log.Printf("Error reading repository %s: %v\n", base, err)
+	}
+	rc.repomds[base] = repomd
+	return repomd
+}
+
+// primaryPackage is a package element of primary.xml
+type primaryPackage struct {
+	Type        string         `xml:"type,attr"`
+	Name        string         `xml:"name"`
+	Arch        string         `xml:"arch"`
+	Version     rpmVersion     `xml:"version"`
+	Description string         `xml:"description"`
+	URL         string         `xml:"url"`
+	Requires    []primaryEntry `xml:"format>requires>entry"`
+	// weak dependencies, installed unless the user opts out or only
+	// suggested
+	Recommends []primaryEntry `xml:"format>recommends>entry"`
+	Suggests   []primaryEntry `xml:"format>suggests>entry"`
+	Provides   []primaryEntry `xml:"format>provides>entry"`
+	// Files listed in primary.xml, those commonly required such as /bin/sh
+	Files     []string `xml:"format>file"`
+	SourceRPM string   `xml:"format>sourcerpm"`
+}
+
+// filelistsPackage is a package element of filelists.xml, with all the
+// files of the package
+type filelistsPackage struct {
+	Name    string     `xml:"name,attr"`
+	Arch    string     `xml:"arch,attr"`
+	Version rpmVersion `xml:"version"`
+	Files   []string   `xml:"file"`
+}
+
+type rpmVersion struct {
+	Epoch string `xml:"epoch,attr"`
+	Ver   string `xml:"ver,attr"`
+	Rel   string `xml:"rel,attr"`
+}
+
+func (v rpmVersion) String() string {
+	return fmt.Sprintf("%s:%s-%s", v.Epoch, v.Ver, v.Rel)
+}
+
+// primaryEntry is a dependency or a provide of a package in primary.xml
+type primaryEntry struct {
+	Name string `xml:"name,attr"`
+}
+
+func entryNames(entries []primaryEntry) []string {
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	return names
+}
+
+// forEachPackage decodes the packages of a primary.xml one by one.
+func forEachPackage(r io.Reader, fn func(pkg *primaryPackage)) error {
+	return forEachElement(r, func(pkg *primaryPackage) {
+		if pkg.Type == "rpm" && pkg.Name != "" {
+			fn(pkg)
+		}
+	})
+}
+
+// forEachElement decodes the package elements of a repodata xml one by
+// one.
+func forEachElement[T any](r io.Reader, fn func(pkg *T)) error {
+	decoder := xml.NewDecoder(collector.StripNUL(r))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if strings.EqualFold(charset, "utf-8") {
+			return input, nil
+		}
+		return nil, fmt.Errorf("unsupported charset: %s", charset)
+	}
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "package" {
+			continue
+		}
+		var pkg T
+		if err := decoder.DecodeElement(&pkg, &se); err != nil {
+			return err
+		}
+		fn(&pkg)
+	}
+}
+
+// ParseInfo parses a primary.xml package by package. Only the first
+// package of a name is kept, source rpms are skipped.
+func (rc *RPMCollector) ParseInfo(r io.Reader) error {
+	return forEachPackage(r, func(pkg *primaryPackage) {
+		if pkg.Arch == "src" || rc.GetPkgInfo(pkg.Name) != nil {
+			return
+		}
+
+		pkgInfo := &collector.PackageInfo{
+			Name:        pkg.Name,
+			Description: collector.Truncate(strings.TrimSpace(pkg.Description), 255),
+			Homepage:    pkg.URL,
+			Version:     pkg.Version.String(),
+		}
+		pkgInfo.DirectDepends = entryNames(pkg.Requires)
+		pkgInfo.OptionalDepends = append(entryNames(pkg.Recommends), entryNames(pkg.Suggests)...)
+		pkgInfo.Provides = append(entryNames(pkg.Provides), pkg.Files...)
+		pkgInfo.Source = sourceName(pkg.SourceRPM)
+		rc.SetPkgInfo(pkgInfo.Name, pkgInfo)
+		rc.binaries[pkgInfo.SourceName()] = append(rc.binaries[pkgInfo.SourceName()], pkgInfo.Name)
+	})
+}
+
+// ParseBuildInfo parses the primary.xml of a repository of source rpms,
+// the requires of a source rpm are the build dependencies of its binary
+// packages read before.
+func (rc *RPMCollector) ParseBuildInfo(r io.Reader) error {
+	return forEachPackage(r, func(pkg *primaryPackage) {
+		if pkg.Arch != "src" {
+			return
+		}
+		for _, name := range rc.binaries[pkg.Name] {
+			pkgInfo := rc.GetPkgInfo(name)
+			if pkgInfo == nil || len(pkgInfo.BuildDepends) > 0 {
+				continue
+			}
+			pkgInfo.BuildDepends = entryNames(pkg.Requires)
+			rc.SetPkgInfo(name, pkgInfo)
+		}
+	})
+}
+
+// ParseFilelists parses a filelists.xml and adds the files of a package
+// which packages read before require, and no package provides yet, to its
+// provides. primary.xml only lists the files commonly required, such as
+// those of /usr/bin, the others are only found here. The other files are
+// dropped, the whole list does not fit in memory.
+func (rc *RPMCollector) ParseFilelists(r io.Reader) error {
+	required := rc.requiredFiles()
+	if len(required) == 0 {
+		return nil
+	}
+	return forEachElement(r, func(pkg *filelistsPackage) {
+		if pkg.Arch == "src" {
+			return
+		}
+		// the files of the package kept by ParseInfo
+		pkgInfo := rc.GetPkgInfo(pkg.Name)
+		if pkgInfo == nil || pkgInfo.Version != pkg.Version.String() {
+			return
+		}
+		var files []string
+		for _, file := range pkg.Files {
+			if required[file] && !slices.Contains(pkgInfo.Provides, file) {
+				files = append(files, file)
+			}
+		}
+		if len(files) > 0 {
+			pkgInfo.Provides = append(slices.Clip(pkgInfo.Provides), files...)
+			rc.SetPkgInfo(pkgInfo.Name, pkgInfo)
+		}
+	})
+}
+
+// requiredFiles returns the files required by any kind of dependency of
+// the packages read so far which no package provides.
+func (rc *RPMCollector) requiredFiles() map[string]bool {
+	required := make(map[string]bool)
+	for _, names := range rc.binaries {
+		for _, name := range names {
+			pkgInfo := rc.GetPkgInfo(name)
+			if pkgInfo == nil {
+				continue
+			}
+			for _, kind := range repository.DepKinds {
+				for _, dep := range pkgInfo.Depends(kind) {
+					if strings.HasPrefix(dep, "/") && len(rc.Resolve(dep)) == 0 {
+						required[dep] = true
+					}
+				}
+			}
+		}
+	}
+	return required
+}
+
+// compsGroup is a group element of comps.xml
+type compsGroup struct {
+	ID       string `xml:"id"`
+	Packages []struct {
+		Type string `xml:"type,attr"`
+		Name string `xml:",chardata"`
+	} `xml:"packagelist>packagereq"`
+}
+
+// ParseComps parses a comps.xml and marks the mandatory and default
+// packages of DefaultGroups as installed by default.
+func (rc *RPMCollector) ParseComps(r io.Reader) error {
+	decoder := xml.NewDecoder(collector.StripNUL(r))
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "group" {
+			continue
+		}
+		var group compsGroup
+		if err := decoder.DecodeElement(&group, &se); err != nil {
+			return err
+		}
+		if !slices.Contains(DefaultGroups, group.ID) {
+			continue
+		}
+		for _, pkg := range group.Packages {
+			// packages without a type are mandatory
+			if pkg.Type == "" || pkg.Type == "mandatory" || pkg.Type == "default" {
+				rc.MarkDefaultInstall(strings.TrimSpace(pkg.Name))
+			}
+		}
+	}
+}
+
+// sourceName returns the name of a source rpm, e.g. glibc for
+// glibc-2.40-3.fc41.src.rpm
+func sourceName(sourceRPM string) string {
+	name := strings.TrimSuffix(sourceRPM, ".src.rpm")
+	// drop the release and the version
+	for i := 0; i < 2; i++ {
+		idx := strings.LastIndex(name, "-")
+		if idx == -1 {
+			return ""
+		}
+		name = name[:idx]
+	}
+	return name
+}
+
+func NewRPMCollector(d Distribution) *RPMCollector {
+	return &RPMCollector{
+		CollecterInterface: collector.NewCollector(d.Type, d.TablePrefix),
+		Distribution:       d,
+		binaries:           make(map[string][]string),
+		repomds:            make(map[string]*Repomd),
+	}
+}
@@ -0,0 +1,276 @@
+package rpm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	collector "github.com/HUSTSecLab/criticality_score/pkg/collector/internal"
+)
+
+const repomdXML = `<?xml version="1.0" encoding="UTF-8"?>
+<repomd xmlns="http://linux.duke.edu/metadata/repo" xmlns:rpm="http://linux.duke.edu/metadata/rpm">
+  <revision>1</revision>
+  <data type="primary">
+    <checksum type="sha256">0123</checksum>
+    <location href="repodata/0123-primary.xml.gz"/>
+  </data>
+  <data type="filelists">
+    <location href="repodata/4567-filelists.xml.gz"/>
+  </data>
+</repomd>
+`
+
+const primaryXML = `<?xml version="1.0" encoding="UTF-8"?>
+<metadata xmlns="http://linux.duke.edu/metadata/common" xmlns:rpm="http://linux.duke.edu/metadata/rpm" packages="4">
+<package type="rpm">
+  <name>curl</name>
+  <arch>x86_64</arch>
+  <version epoch="0" ver="8.9.1" rel="2.fc41"/>
+  <description>A utility for getting files from remote servers</description>
+  <url>https://curl.se/</url>
+  <format>
+    <rpm:sourcerpm>curl-8.9.1-2.fc41.src.rpm</rpm:sourcerpm>
+    <rpm:provides>
+      <rpm:entry name="curl" flags="EQ" epoch="0" ver="8.9.1" rel="2.fc41"/>
+    </rpm:provides>
+    <rpm:requires>
+      <rpm:entry name="libcurl" flags="GE" epoch="0" ver="8.9.1"/>
+      <rpm:entry name="libc.so.6()(64bit)"/>
+      <rpm:entry name="/bin/sh"/>
+      <rpm:entry name="/usr/lib64/libcurl.so.4"/>
+    </rpm:requires>
+    <rpm:recommends>
+      <rpm:entry name="ca-certificates"/>
+    </rpm:recommends>
+  </format>
+</package>
+<package type="rpm">
+  <name>curl</name>
+  <arch>i686</arch>
+  <version epoch="0" ver="8.9.1" rel="2.fc41"/>
+</package>
+<package type="rpm">
+  <name>libcurl</name>
+  <version epoch="0" ver="8.9.1" rel="2.fc41"/>
+  <format>
+    <rpm:sourcerpm>curl-8.9.1-2.fc41.src.rpm</rpm:sourcerpm>
+    <rpm:provides>
+      <rpm:entry name="libcurl.so.4()(64bit)"/>
+    </rpm:provides>
+  </format>
+</package>
+<package type="rpm">
+  <name>bash</name>
+  <version epoch="0" ver="5.2.32" rel="1.fc41"/>
+  <format>
+    <file>/bin/sh</file>
+  </format>
+</package>
+</metadata>
+`
+
+const filelistsXML = `<?xml version="1.0" encoding="UTF-8"?>
+<filelists xmlns="http://linux.duke.edu/metadata/filelists" packages="3">
+<package pkgid="a" name="curl" arch="x86_64">
+  <version epoch="0" ver="8.9.1" rel="2.fc41"/>
+  <file>/usr/bin/curl</file>
+</package>
+<package pkgid="b" name="libcurl" arch="x86_64">
+  <version epoch="0" ver="8.9.1" rel="2.fc41"/>
+  <file>/usr/lib64/libcurl.so.4</file>
+  <file>/usr/lib64/libcurl.so.4.8.0</file>
+</package>
+<package pkgid="c" name="bash" arch="x86_64">
+  <version epoch="0" ver="5.2.32" rel="1.fc41"/>
+  <file>/bin/sh</file>
+  <file>/usr/bin/bash</file>
+</package>
+</filelists>
+`
+
+func TestRPMCollector(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "41", "repodata"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "41", "repodata", "repomd.xml"), []byte(repomdXML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"0123-primary.xml.gz": primaryXML, "4567-filelists.xml.gz": filelistsXML} {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write([]byte(content))
+		w.Close()
+		if err := os.WriteFile(filepath.Join(dir, "41", "repodata", name), buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rc := NewRPMCollector(Distribution{
+		Name:   "test",
+		Source: collector.Source{Mirror: "file://" + dir, Releases: []string{"41", "missing"}, Path: "{release}"},
+	})
+	urls := rc.MetadataURLs(DataPrimary)
+	want := collector.PackageURL{"file://" + dir + "/41/repodata/0123-primary.xml.gz"}
+	if !slices.Equal(urls, want) {
+		t.Fatalf("MetadataURLs() = %v, want %v", urls, want)
+	}
+
+	rc.ReadIndexes(urls, rc.ParseInfo)
+	curl := rc.GetPkgInfo("curl")
+	if curl == nil {
+		t.Fatal("curl not parsed")
+	}
+	if curl.Version != "0:8.9.1-2.fc41" || curl.Homepage != "https://curl.se/" {
+		t.Errorf("curl = %+v", curl)
+	}
+	if !slices.Equal(curl.DirectDepends, []string{"libcurl", "libc.so.6()(64bit)", "/bin/sh", "/usr/lib64/libcurl.so.4"}) {
+		t.Errorf("curl depends on %v, want its requires only", curl.DirectDepends)
+	}
+	if !slices.Equal(curl.OptionalDepends, []string{"ca-certificates"}) {
+		t.Errorf("curl optionally depends on %v, want its recommends", curl.OptionalDepends)
+	}
+	if got := rc.Resolve("libcurl.so.4()(64bit)"); !slices.Equal(got, []string{"libcurl"}) {
+		t.Errorf("libcurl.so.4()(64bit) resolves to %v", got)
+	}
+	if libcurl := rc.GetPkgInfo("libcurl"); libcurl == nil || libcurl.SourceName() != "curl" {
+		t.Errorf("libcurl = %+v, want source curl", libcurl)
+	}
+	if got := rc.Resolve("/bin/sh"); !slices.Equal(got, []string{"bash"}) {
+		t.Errorf("/bin/sh resolves to %v", got)
+	}
+
+	// only filelists.xml lists the library file
+	if got := rc.Resolve("/usr/lib64/libcurl.so.4"); len(got) != 0 {
+		t.Errorf("/usr/lib64/libcurl.so.4 resolves to %v before reading filelists.xml", got)
+	}
+	// repomd.xml is read once per collection
+	if err := os.Remove(filepath.Join(dir, "41", "repodata", "repomd.xml")); err != nil {
+		t.Fatal(err)
+	}
+	urls = rc.MetadataURLs(DataFilelists)
+	want = collector.PackageURL{"file://" + dir + "/41/repodata/4567-filelists.xml.gz"}
+	if !slices.Equal(urls, want) {
+		t.Fatalf("MetadataURLs(DataFilelists) = %v, want %v", urls, want)
+	}
+	rc.ReadIndexes(urls, rc.ParseFilelists)
+	if got := rc.Resolve("/usr/lib64/libcurl.so.4"); !slices.Equal(got, []string{"libcurl"}) {
+		t.Errorf("/usr/lib64/libcurl.so.4 resolves to %v, want libcurl", got)
+	}
+	if provides := rc.GetPkgInfo("libcurl").Provides; slices.Contains(provides, "/usr/lib64/libcurl.so.4.8.0") {
+		t.Errorf("libcurl provides %v, want only the required files", provides)
+	}
+	if provides := rc.GetPkgInfo("bash").Provides; !slices.Equal(provides, []string{"/bin/sh"}) {
+		t.Errorf("bash provides %v, want /bin/sh once", provides)
+	}
+}
+
+func TestRepomdLocationBase(t *testing.T) {
+	r := &Repomd{Data: []RepomdData{{Type: DataPrimary}}}
+	r.Data[0].Location.Href = "repodata/a-primary.xml.gz"
+	if got := r.Location("https://mirror/repo/", DataPrimary); got != "https://mirror/repo/repodata/a-primary.xml.gz" {
+		t.Errorf("Location() = %s", got)
+	}
+	r.Data[0].Location.Base = "https://other/repo"
+	if got := r.Location("https://mirror/repo/", DataPrimary); got != "https://other/repo/repodata/a-primary.xml.gz" {
+		t.Errorf("Location() with xml:base = %s", got)
+	}
+	if got := r.Location("https://mirror/repo/", DataGroup); got != "" {
+		t.Errorf("Location() of a missing type = %s", got)
+	}
+}
+
+func TestSourceName(t *testing.T) {
+	tests := map[string]string{
+		"glibc-2.40-3.fc41.src.rpm":          "glibc",
+		"python-rpm-macros-3.12-8.1.src.rpm": "python-rpm-macros",
+		"":                                   "",
+	}
+	for sourceRPM, want := range tests {
+		if got := sourceName(sourceRPM); got != want {
+			t.Errorf("sourceName(%q) = %q, want %q", sourceRPM, got, want)
+		}
+	}
+}
+
+const compsXML = `<?xml version="1.0" encoding="UTF-8"?>
+<comps>
+  <group>
+    <id>core</id>
+    <name>Core</name>
+    <name xml:lang="de">Kern</name>
+    <packagelist>
+      <packagereq type="mandatory">bash</packagereq>
+      <packagereq type="default">curl</packagereq>
+      <packagereq type="optional">libcurl</packagereq>
+    </packagelist>
+  </group>
+  <group>
+    <id>editors</id>
+    <packagelist>
+      <packagereq type="mandatory">vim</packagereq>
+    </packagelist>
+  </group>
+</comps>
+`
+
+func TestParseComps(t *testing.T) {
+	rc := NewRPMCollector(Distribution{Name: "test"})
+	for _, name := range []string{"bash", "curl", "libcurl", "vim"} {
+		rc.SetPkgInfo(name, &collector.PackageInfo{Name: name})
+	}
+	if err := rc.ParseComps(strings.NewReader(compsXML)); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"bash": true, "curl": true, "libcurl": false, "vim": false} {
+		if got := rc.GetPkgInfo(name).DefaultInstall; got != want {
+			t.Errorf("DefaultInstall of %s = %v, want %v", name, got, want)
+		}
+	}
+}
+
+const sourcePrimaryXML = `<?xml version="1.0" encoding="UTF-8"?>
+<metadata xmlns="http://linux.duke.edu/metadata/common" xmlns:rpm="http://linux.duke.edu/metadata/rpm" packages="1">
+<package type="rpm">
+  <name>curl</name>
+  <arch>src</arch>
+  <version epoch="0" ver="8.9.1" rel="2.fc41"/>
+  <format>
+    <rpm:requires>
+      <rpm:entry name="gcc"/>
+      <rpm:entry name="openssl-devel"/>
+    </rpm:requires>
+  </format>
+</package>
+</metadata>
+`
+
+func TestParseBuildInfo(t *testing.T) {
+	rc := NewRPMCollector(Distribution{Name: "test"})
+	if err := rc.ParseInfo(strings.NewReader(primaryXML)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rc.ParseInfo(strings.NewReader(sourcePrimaryXML)); err != nil {
+		t.Fatal(err)
+	}
+	if curl := rc.GetPkgInfo("curl"); curl.Version != "0:8.9.1-2.fc41" || len(curl.BuildDepends) != 0 {
+		t.Fatalf("curl = %+v, the source rpm is not skipped", curl)
+	}
+
+	if err := rc.ParseBuildInfo(strings.NewReader(sourcePrimaryXML)); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"curl", "libcurl"} {
+		if got := rc.GetPkgInfo(name).BuildDepends; !slices.Equal(got, []string{"gcc", "openssl-devel"}) {
+			t.Errorf("BuildDepends of %s = %v", name, got)
+		}
+	}
+	if got := rc.GetPkgInfo("bash").BuildDepends; got != nil {
+		t.Errorf("BuildDepends of bash = %v, want none", got)
+	}
+}
@@ -10,10 +10,12 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 	"github.com/go-redis/redis/v8"
@@ -452,49 +454,38 @@ func fetchDep(depMap *sync.Map, threadnum int) *sync.Map {
 	return depMapNew
 }
 
+// calculatePageRank ranks the packages by their direct dependencies on
+// each other, dependencies on packages not in pkgInfoMap are left out.
 func calculatePageRank(pkgInfoMap map[string][]Version, iterations int, dampingFactor float64) map[string]float64 {
-	pageRank := &sync.Map{}
-	numPackages := len(pkgInfoMap)
-
-	for pkgName := range pkgInfoMap {
-		pageRank.Store(pkgName, 1.0/float64(numPackages))
+	names := lo.Keys(pkgInfoMap)
+	sort.Strings(names)
+	g := graph.New()
+	for _, pkgName := range names {
+		g.Node(pkgName)
 	}
-
-	for i := 0; i < iterations; i++ {
-		newPageRank := &sync.Map{}
-
-		for pkgName := range pkgInfoMap {
-			newPageRank.Store(pkgName, (1-dampingFactor)/float64(numPackages))
-		}
-
-		var wg sync.WaitGroup
-		for pkgName, deps := range pkgInfoMap {
-			wg.Add(1)
-			go func(pkgName string, deps []Version) {
-				defer wg.Done()
-				depNum := len(deps)
-				for _, depName := range deps {
-					if _, exists := pkgInfoMap[depName.Name]; exists {
-						if val, ok := pageRank.Load(pkgName); ok {
-							if newVal, ok := newPageRank.Load(depName.Name); ok {
-								newPageRank.Store(depName.Name, newVal.(float64)+dampingFactor*(val.(float64)/float64(depNum)))
-							}
-						}
-					}
-				}
-			}(pkgName, deps)
+	for _, pkgName := range names {
+		from := g.Node(pkgName)
+		for _, dep := range pkgInfoMap[pkgName] {
+			if to, ok := g.Lookup(dep.Name); ok {
+				g.AddEdge(from, to)
+			}
 		}
-		wg.Wait()
-		pageRank = newPageRank
 	}
 
-	result := make(map[string]float64)
-	pageRank.Range(func(key, value interface{}) bool {
-		result[key.(string)] = value.(float64)
-		return true
-	})
+	result, err := g.PageRank(graph.PageRankOptions{Damping: dampingFactor, MaxIterations: iterations})
+	if err != nil {
+		log.Println("Error computing PageRank:", err)
+		return make(map[string]float64)
+	}
+	if !result.Converged {
+		log.Printf("PageRank did not converge in %d iterations, residual %g\n", result.Iterations, result.Residual)
+	}
 
-	return result
+	pageRank := make(map[string]float64, len(names))
+	for id, rank := range result.Ranks {
+		pageRank[g.Name(id)] = rank
+	}
+	return pageRank
 }
 
 func getAndProcessDependencies(system, name, version string) Dependencies {
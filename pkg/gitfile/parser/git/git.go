@@ -345,12 +345,15 @@ func getTopNKeys(m map[string]int64) []string {
 	return keys
 }
 
-func (repo *Repo) WalkLog(r *git.Repository) error {
+// WalkLog collects the commit metrics of the repository as of the reference
+// time, commits after it are ignored.
+func (repo *Repo) WalkLog(r *git.Repository, asOf time.Time) error {
+	lastYear := parser.LastYear(asOf)
 	cIter, err := r.Log(&git.LogOptions{
 		//* From:  ref.Hash(),
 		All:   true,
 		Since: &parser.BEGIN_TIME,
-		Until: &parser.END_TIME,
+		Until: &asOf,
 		Order: git.LogOrderCommitterTime,
 	})
 	if err != nil {
@@ -374,7 +377,7 @@ func (repo *Repo) WalkLog(r *git.Repository) error {
 	contributors[author]++
 	orgs[org]++
 
-	if latest_commit.Author.When.After(parser.LAST_YEAR) {
+	if latest_commit.Author.When.After(lastYear) {
 		commit_count++
 	}
 
@@ -392,7 +395,7 @@ func (repo *Repo) WalkLog(r *git.Repository) error {
 		contributors[author]++
 		orgs[org]++
 
-		if created_since.After(parser.LAST_YEAR) {
+		if created_since.After(lastYear) {
 			commit_count++
 		}
 
@@ -485,7 +488,9 @@ func (repo *Repo) Show() {
 	)
 }
 
-func ParseRepo(r *git.Repository) (*Repo, error) {
+// ParseRepo collects the metadata and metrics of the repository as of the
+// reference time, usually time.Now().
+func ParseRepo(r *git.Repository, asOf time.Time) (*Repo, error) {
 
 	repo := NewRepo()
 
@@ -518,7 +523,7 @@ func ParseRepo(r *git.Repository) (*Repo, error) {
 		return nil, errWalkRepoFailed
 	}
 
-	err = repo.WalkLog(r)
+	err = repo.WalkLog(r, asOf)
 	if err != nil {
 		logger.Errorf("Failed to Walk Log for %v", err)
 		return nil, errWalkLogFailed
@@ -9,6 +9,7 @@ package git
 import (
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/collector"
 	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
@@ -41,7 +42,7 @@ func TestParseGitRepo(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
-			repo, err := ParseRepo(r)
+			repo, err := ParseRepo(r, time.Now())
 			if err != nil {
 				t.Fatal(err)
 			}
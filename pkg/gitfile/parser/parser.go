@@ -29,13 +29,19 @@ const (
 var (
 	UNKNOWN_TIME = time.Time{}
 
-	BEGIN_TIME   = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
-	END_TIME     = time.Now()
-	NOW          = time.Now()
-	LAST_YEAR    = NOW.AddDate(-1, 0, 0)
-	LAST_90_DAYS = NOW.AddDate(0, 0, -90)
+	BEGIN_TIME = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
 )
 
+// LastYear returns the start of the year before the reference time
+func LastYear(asOf time.Time) time.Time {
+	return asOf.AddDate(-1, 0, 0)
+}
+
+// Last90Days returns the start of the 90 days before the reference time
+func Last90Days(asOf time.Time) time.Time {
+	return asOf.AddDate(0, 0, -90)
+}
+
 // ToDo Try to cover more
 var LICENSE_FILENAMES = map[string]bool{
 	"LICENSE":     true,
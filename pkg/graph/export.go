@@ -0,0 +1,289 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Format is a file format a dependency graph is exported in.
+type Format string
+
+const (
+	// FormatDOT is Graphviz DOT, with the attributes as node and edge
+	// attributes
+	FormatDOT Format = "dot"
+	// FormatGraphML is GraphML, read by NetworkX, Gephi and yEd
+	FormatGraphML Format = "graphml"
+	// FormatGEXF is GEXF 1.3, the native format of Gephi
+	FormatGEXF Format = "gexf"
+	// FormatJSON is a node and edge list, read by NetworkX with
+	// node_link_graph(data, edges="edges")
+	FormatJSON Format = "json"
+)
+
+var Formats = []Format{FormatDOT, FormatGraphML, FormatGEXF, FormatJSON}
+
+// ParseFormat returns the format of the name, such as graphml.
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(name, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown graph format %q, want one of %v", name, Formats)
+}
+
+// FormatOf returns the format of a file by its extension, DOT if the
+// extension is none of the formats.
+func FormatOf(path string) Format {
+	if f, err := ParseFormat(strings.TrimPrefix(filepath.Ext(path), ".")); err == nil {
+		return f
+	}
+	return FormatDOT
+}
+
+// ExportNode is a package of an exported dependency graph.
+type ExportNode struct {
+	ID             string  `json:"id"`
+	Version        string  `json:"version"`
+	GitLink        string  `json:"gitLink"`
+	PageRank       float64 `json:"pageRank"`
+	Impact         float64 `json:"impact"`
+	DependentCount int     `json:"dependentCount"`
+}
+
+// ExportEdge goes from a package to a package it depends on, Kind is the
+// kind of the dependency, such as runtime or build.
+type ExportEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Kind   string `json:"kind"`
+}
+
+// Export is a dependency graph with the attributes of its packages and
+// dependencies. Two packages may be joined by an edge of each kind.
+type Export struct {
+	Nodes []ExportNode
+	Edges []ExportEdge
+}
+
+// nodeAttribute is an attribute of the nodes in GraphML and GEXF
+type nodeAttribute struct {
+	name string
+	// type in GraphML, GEXF calls int integer
+	typ   string
+	value func(n *ExportNode) string
+}
+
+var nodeAttributes = []nodeAttribute{
+	{"version", "string", func(n *ExportNode) string { return n.Version }},
+	{"gitLink", "string", func(n *ExportNode) string { return n.GitLink }},
+	{"pageRank", "double", func(n *ExportNode) string { return strconv.FormatFloat(n.PageRank, 'g', -1, 64) }},
+	{"impact", "double", func(n *ExportNode) string { return strconv.FormatFloat(n.Impact, 'g', -1, 64) }},
+	{"dependentCount", "int", func(n *ExportNode) string { return strconv.Itoa(n.DependentCount) }},
+}
+
+// Write writes the graph in the format.
+func (e *Export) Write(w io.Writer, format Format) error {
+	bw := bufio.NewWriter(w)
+	var err error
+	switch format {
+	case FormatDOT:
+		err = e.writeDOT(bw)
+	case FormatGraphML:
+		err = e.writeGraphML(bw)
+	case FormatGEXF:
+		err = e.writeGEXF(bw)
+	case FormatJSON:
+		err = e.writeJSON(bw)
+	default:
+		return fmt.Errorf("unknown graph format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func (e *Export) writeDOT(w *bufio.Writer) error {
+	w.WriteString("digraph {\n")
+	for i := range e.Nodes {
+		n := &e.Nodes[i]
+		fmt.Fprintf(w, "  %s [", strconv.Quote(n.ID))
+		for j, a := range nodeAttributes {
+			if j > 0 {
+				w.WriteString(", ")
+			}
+			fmt.Fprintf(w, "%s=%s", a.name, strconv.Quote(a.value(n)))
+		}
+		w.WriteString("];\n")
+	}
+	for _, edge := range e.Edges {
+		fmt.Fprintf(w, "  %s -> %s [kind=%s];\n", strconv.Quote(edge.Source), strconv.Quote(edge.Target), strconv.Quote(edge.Kind))
+	}
+	_, err := w.WriteString("}\n")
+	return err
+}
+
+// attr is an XML attribute
+func attr(name, value string) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+}
+
+// xmlWriter encodes XML tokens one by one, keeping the first error
+type xmlWriter struct {
+	enc *xml.Encoder
+	err error
+}
+
+func (x *xmlWriter) start(name string, attrs ...xml.Attr) {
+	if x.err == nil {
+		x.err = x.enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs})
+	}
+}
+
+func (x *xmlWriter) end(name string) {
+	if x.err == nil {
+		x.err = x.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}})
+	}
+}
+
+func (x *xmlWriter) text(s string) {
+	if x.err == nil {
+		x.err = x.enc.EncodeToken(xml.CharData(s))
+	}
+}
+
+func (x *xmlWriter) empty(name string, attrs ...xml.Attr) {
+	x.start(name, attrs...)
+	x.end(name)
+}
+
+func (x *xmlWriter) flush(w io.Writer) error {
+	if x.err == nil {
+		x.err = x.enc.Flush()
+	}
+	if x.err == nil {
+		_, x.err = io.WriteString(w, "\n")
+	}
+	return x.err
+}
+
+func newXMLWriter(w io.Writer) *xmlWriter {
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return &xmlWriter{enc: enc}
+}
+
+func (e *Export) writeGraphML(w *bufio.Writer) error {
+	x := newXMLWriter(w)
+	x.start("graphml", attr("xmlns", "http://graphml.graphdrawing.org/xmlns"))
+	for _, a := range nodeAttributes {
+		x.empty("key", attr("id", a.name), attr("for", "node"), attr("attr.name", a.name), attr("attr.type", a.typ))
+	}
+	x.empty("key", attr("id", "kind"), attr("for", "edge"), attr("attr.name", "kind"), attr("attr.type", "string"))
+
+	x.start("graph", attr("id", "G"), attr("edgedefault", "directed"))
+	for i := range e.Nodes {
+		n := &e.Nodes[i]
+		x.start("node", attr("id", n.ID))
+		for _, a := range nodeAttributes {
+			x.start("data", attr("key", a.name))
+			x.text(a.value(n))
+			x.end("data")
+		}
+		x.end("node")
+	}
+	for _, edge := range e.Edges {
+		x.start("edge", attr("source", edge.Source), attr("target", edge.Target))
+		x.start("data", attr("key", "kind"))
+		x.text(edge.Kind)
+		x.end("data")
+		x.end("edge")
+	}
+	x.end("graph")
+	x.end("graphml")
+	return x.flush(w)
+}
+
+func (e *Export) writeGEXF(w *bufio.Writer) error {
+	x := newXMLWriter(w)
+	x.start("gexf", attr("xmlns", "http://gexf.net/1.3"), attr("version", "1.3"))
+	x.start("graph", attr("defaultedgetype", "directed"), attr("mode", "static"))
+	x.start("attributes", attr("class", "node"))
+	for i, a := range nodeAttributes {
+		typ := a.typ
+		if typ == "int" {
+			typ = "integer"
+		}
+		x.empty("attribute", attr("id", strconv.Itoa(i)), attr("title", a.name), attr("type", typ))
+	}
+	x.end("attributes")
+	x.start("attributes", attr("class", "edge"))
+	x.empty("attribute", attr("id", "kind"), attr("title", "kind"), attr("type", "string"))
+	x.end("attributes")
+
+	x.start("nodes")
+	for i := range e.Nodes {
+		n := &e.Nodes[i]
+		x.start("node", attr("id", n.ID), attr("label", n.ID))
+		x.start("attvalues")
+		for j, a := range nodeAttributes {
+			x.empty("attvalue", attr("for", strconv.Itoa(j)), attr("value", a.value(n)))
+		}
+		x.end("attvalues")
+		x.end("node")
+	}
+	x.end("nodes")
+	x.start("edges")
+	for i, edge := range e.Edges {
+		x.start("edge", attr("id", strconv.Itoa(i)), attr("source", edge.Source), attr("target", edge.Target))
+		x.start("attvalues")
+		x.empty("attvalue", attr("for", "kind"), attr("value", edge.Kind))
+		x.end("attvalues")
+		x.end("edge")
+	}
+	x.end("edges")
+	x.end("graph")
+	x.end("gexf")
+	return x.flush(w)
+}
+
+// writeJSON writes the node-link form of NetworkX one node and one edge
+// per line, so a large graph is never held as one JSON value.
+func (e *Export) writeJSON(w *bufio.Writer) error {
+	w.WriteString(`{"directed": true, "multigraph": true, "graph": {}, "nodes": [`)
+	for i := range e.Nodes {
+		if err := writeJSONItem(w, i, &e.Nodes[i]); err != nil {
+			return err
+		}
+	}
+	w.WriteString("\n], \"edges\": [")
+	for i := range e.Edges {
+		if err := writeJSONItem(w, i, &e.Edges[i]); err != nil {
+			return err
+		}
+	}
+	_, err := w.WriteString("\n]}\n")
+	return err
+}
+
+func writeJSONItem(w *bufio.Writer, i int, v any) error {
+	if i > 0 {
+		w.WriteString(",")
+	}
+	w.WriteString("\n  ")
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,138 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+var testExport = &Export{
+	Nodes: []ExportNode{
+		{ID: "curl", Version: "8.11.1-1", GitLink: "https://github.com/curl/curl", PageRank: 0.25, Impact: 0.5, DependentCount: 3},
+		{ID: "a&b <c>", Version: "1"},
+	},
+	Edges: []ExportEdge{
+		{Source: "curl", Target: "a&b <c>", Kind: "runtime"},
+		{Source: "curl", Target: "a&b <c>", Kind: "build"},
+	},
+}
+
+func write(t *testing.T, format Format) []byte {
+	var buf bytes.Buffer
+	if err := testExport.Write(&buf, format); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriteGraphML(t *testing.T) {
+	var doc struct {
+		Keys []struct {
+			ID   string `xml:"id,attr"`
+			For  string `xml:"for,attr"`
+			Type string `xml:"attr.type,attr"`
+		} `xml:"key"`
+		Nodes []struct {
+			ID   string `xml:"id,attr"`
+			Data []struct {
+				Key   string `xml:"key,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"data"`
+		} `xml:"graph>node"`
+		Edges []struct {
+			Source string `xml:"source,attr"`
+			Target string `xml:"target,attr"`
+			Kind   string `xml:"data"`
+		} `xml:"graph>edge"`
+	}
+	if err := xml.Unmarshal(write(t, FormatGraphML), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Keys) != 6 || len(doc.Nodes) != 2 || len(doc.Edges) != 2 {
+		t.Fatalf("graphml = %+v", doc)
+	}
+	data := map[string]string{}
+	for _, d := range doc.Nodes[0].Data {
+		data[d.Key] = d.Value
+	}
+	if data["gitLink"] != "https://github.com/curl/curl" || data["pageRank"] != "0.25" || data["dependentCount"] != "3" {
+		t.Errorf("data of curl = %v", data)
+	}
+	if e := doc.Edges[1]; e.Target != "a&b <c>" || e.Kind != "build" {
+		t.Errorf("edge = %+v", e)
+	}
+}
+
+func TestWriteGEXF(t *testing.T) {
+	var doc struct {
+		Attributes []struct {
+			Class      string `xml:"class,attr"`
+			Attributes []struct {
+				Title string `xml:"title,attr"`
+				Type  string `xml:"type,attr"`
+			} `xml:"attribute"`
+		} `xml:"graph>attributes"`
+		Nodes []struct {
+			ID     string `xml:"id,attr"`
+			Values []struct {
+				For   string `xml:"for,attr"`
+				Value string `xml:"value,attr"`
+			} `xml:"attvalues>attvalue"`
+		} `xml:"graph>nodes>node"`
+		Edges []struct {
+			ID   string `xml:"id,attr"`
+			Kind struct {
+				Value string `xml:"value,attr"`
+			} `xml:"attvalues>attvalue"`
+		} `xml:"graph>edges>edge"`
+	}
+	if err := xml.Unmarshal(write(t, FormatGEXF), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Attributes) != 2 || doc.Attributes[0].Attributes[4].Type != "integer" {
+		t.Errorf("attributes = %+v", doc.Attributes)
+	}
+	if len(doc.Nodes) != 2 || doc.Nodes[0].Values[0].Value != "8.11.1-1" {
+		t.Errorf("nodes = %+v", doc.Nodes)
+	}
+	if len(doc.Edges) != 2 || doc.Edges[0].ID == doc.Edges[1].ID || doc.Edges[1].Kind.Value != "build" {
+		t.Errorf("edges = %+v", doc.Edges)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var doc struct {
+		Directed bool         `json:"directed"`
+		Nodes    []ExportNode `json:"nodes"`
+		Edges    []ExportEdge `json:"edges"`
+	}
+	if err := json.Unmarshal(write(t, FormatJSON), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !doc.Directed || len(doc.Nodes) != 2 || doc.Nodes[0] != testExport.Nodes[0] || len(doc.Edges) != 2 || doc.Edges[1] != testExport.Edges[1] {
+		t.Errorf("json = %+v", doc)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	dot := string(write(t, FormatDOT))
+	for _, want := range []string{`"curl" [version="8.11.1-1"`, `dependentCount="3"`, `"curl" -> "a&b <c>" [kind="build"];`} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("dot has no %s:\n%s", want, dot)
+		}
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := map[string]Format{"deps.graphml": FormatGraphML, "deps.GEXF": FormatGEXF, "deps.json": FormatJSON, "deps.dot": FormatDOT, "deps": FormatDOT}
+	for path, want := range tests {
+		if got := FormatOf(path); got != want {
+			t.Errorf("FormatOf(%s) = %s, want %s", path, got, want)
+		}
+	}
+	if _, err := ParseFormat("csv"); err == nil {
+		t.Error("ParseFormat(csv) succeeded")
+	}
+}
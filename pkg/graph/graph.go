@@ -0,0 +1,234 @@
+// Package graph holds the dependency graphs of the collectors, and ranks
+// their nodes with PageRank. Package names are interned into dense integer
+// IDs, so the graph of a whole distribution is a few slices, and nothing is
+// computed recursively, so no graph is too deep.
+package graph
+
+import "math/bits"
+
+// Graph is a directed graph of named nodes. An edge goes from a node to a
+// node it depends on.
+type Graph struct {
+	ids   map[string]int
+	names []string
+	succ  [][]int
+}
+
+func New() *Graph {
+	return &Graph{ids: make(map[string]int)}
+}
+
+// Node returns the ID of the node of the name, adding it if needed. IDs
+// are given in the order the names are first seen, from 0.
+func (g *Graph) Node(name string) int {
+	if id, ok := g.ids[name]; ok {
+		return id
+	}
+	id := len(g.names)
+	g.ids[name] = id
+	g.names = append(g.names, name)
+	g.succ = append(g.succ, nil)
+	return id
+}
+
+// Lookup returns the ID of the node of the name and whether there is one.
+func (g *Graph) Lookup(name string) (int, bool) {
+	id, ok := g.ids[name]
+	return id, ok
+}
+
+func (g *Graph) Name(id int) string {
+	return g.names[id]
+}
+
+// Len returns the number of nodes.
+func (g *Graph) Len() int {
+	return len(g.names)
+}
+
+// AddEdge adds an edge from a node to a node it depends on.
+func (g *Graph) AddEdge(from, to int) {
+	g.succ[from] = append(g.succ[from], to)
+}
+
+// Successors returns the nodes the node depends on.
+func (g *Graph) Successors(id int) []int {
+	return g.succ[id]
+}
+
+// Condensation is the graph of the strongly connected components of a
+// graph, the packages depending on each other in a cycle make up one
+// component. It has no cycle.
+type Condensation struct {
+	// Component of each node of the graph
+	Component []int
+	// Members are the nodes of each component. A component comes before
+	// the components it depends on.
+	Members [][]int
+	// Succ are the components each component depends on, once each
+	Succ [][]int
+}
+
+// Condense returns the condensation of the graph, found with Tarjan's
+// algorithm in time linear in the nodes and edges.
+func (g *Graph) Condense() *Condensation {
+	n := g.Len()
+	// index of each node in the depth first search from 1, 0 if not
+	// visited yet
+	index := make([]int, n)
+	low := make([]int, n)
+	onStack := make([]bool, n)
+	var stack []int
+	// call stack of the search, the node and its next successor
+	type frame struct{ node, next int }
+	var call []frame
+	var components [][]int
+	counter := 0
+
+	visit := func(v int) {
+		counter++
+		index[v], low[v] = counter, counter
+		stack = append(stack, v)
+		onStack[v] = true
+		call = append(call, frame{node: v})
+	}
+	for root := 0; root < n; root++ {
+		if index[root] != 0 {
+			continue
+		}
+		visit(root)
+		for len(call) > 0 {
+			f := &call[len(call)-1]
+			v := f.node
+			if f.next < len(g.succ[v]) {
+				w := g.succ[v][f.next]
+				f.next++
+				if index[w] == 0 {
+					visit(w)
+				} else if onStack[w] && index[w] < low[v] {
+					low[v] = index[w]
+				}
+				continue
+			}
+
+			call = call[:len(call)-1]
+			if len(call) > 0 {
+				if u := call[len(call)-1].node; low[v] < low[u] {
+					low[u] = low[v]
+				}
+			}
+			if low[v] == index[v] {
+				var component []int
+				for {
+					w := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[w] = false
+					component = append(component, w)
+					if w == v {
+						break
+					}
+				}
+				components = append(components, component)
+			}
+		}
+	}
+
+	// Tarjan's algorithm finds a component after those it depends on
+	c := &Condensation{
+		Component: make([]int, n),
+		Members:   make([][]int, len(components)),
+		Succ:      make([][]int, len(components)),
+	}
+	for i, component := range components {
+		id := len(components) - 1 - i
+		c.Members[id] = component
+		for _, v := range component {
+			c.Component[v] = id
+		}
+	}
+	// last component an edge was added to from each component
+	seen := make([]int, len(components))
+	for i := range seen {
+		seen[i] = -1
+	}
+	for id, members := range c.Members {
+		for _, v := range members {
+			for _, w := range g.succ[v] {
+				if to := c.Component[w]; to != id && seen[to] != id {
+					seen[to] = id
+					c.Succ[id] = append(c.Succ[id], to)
+				}
+			}
+		}
+	}
+	return c
+}
+
+// maxBlockBytes bounds the memory of the reachability sets of
+// ReachCounts
+var maxBlockBytes = 64 << 20
+
+// ReachCounts returns for every node of the graph the number of roots it
+// is reachable from, a root being a set of nodes, such as the build
+// dependencies of a package. A node is reachable from the nodes of a root
+// themselves.
+//
+// The roots reaching each component are kept as bitsets and passed along
+// the condensation in topological order, 64 roots per word, so the time is
+// that of walking the condensation once every 64 roots. As many roots as
+// fit in maxBlockBytes are walked together.
+func (c *Condensation) ReachCounts(roots [][]int) []int {
+	nc := len(c.Members)
+	counts := make([]int, nc)
+	if nc == 0 || len(roots) == 0 {
+		return c.nodeCounts(counts)
+	}
+
+	words := (len(roots) + 63) / 64
+	if limit := maxBlockBytes / 8 / nc; words > limit {
+		words = max(limit, 1)
+	}
+	reach := make([]uint64, nc*words)
+	for start := 0; start < len(roots); start += 64 * words {
+		clear(reach)
+		end := min(start+64*words, len(roots))
+		for r := start; r < end; r++ {
+			bit := r - start
+			for _, v := range roots[r] {
+				reach[c.Component[v]*words+bit/64] |= 1 << (bit % 64)
+			}
+		}
+		for id := 0; id < nc; id++ {
+			row := reach[id*words : (id+1)*words]
+			for _, w := range row {
+				counts[id] += bits.OnesCount64(w)
+			}
+			for _, to := range c.Succ[id] {
+				next := reach[to*words : (to+1)*words]
+				for i, w := range row {
+					next[i] |= w
+				}
+			}
+		}
+	}
+	return c.nodeCounts(counts)
+}
+
+// nodeCounts returns the count of the component of every node
+func (c *Condensation) nodeCounts(counts []int) []int {
+	nodes := make([]int, len(c.Component))
+	for v, id := range c.Component {
+		nodes[v] = counts[id]
+	}
+	return nodes
+}
+
+// DependentCounts returns for every node the number of nodes depending on
+// it, directly or not, the node itself included.
+func (c *Condensation) DependentCounts() []int {
+	roots := make([][]int, len(c.Component))
+	for v := range roots {
+		roots[v] = []int{v}
+	}
+	return c.ReachCounts(roots)
+}
@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func newTestGraph(edges [][2]string) *Graph {
+	g := New()
+	for _, e := range edges {
+		g.AddEdge(g.Node(e[0]), g.Node(e[1]))
+	}
+	return g
+}
+
+func TestCondense(t *testing.T) {
+	g := newTestGraph([][2]string{
+		{"app", "python"}, {"python", "pip"}, {"pip", "python"},
+		{"python", "libc"}, {"pip", "libc"}, {"app", "libc"},
+	})
+	c := g.Condense()
+	if len(c.Members) != 3 {
+		t.Fatalf("components = %v, want app, the python cycle and libc", c.Members)
+	}
+	id := func(name string) int {
+		v, _ := g.Lookup(name)
+		return c.Component[v]
+	}
+	if id("python") != id("pip") {
+		t.Error("python and pip are not one component")
+	}
+	if !(id("app") < id("python") && id("python") < id("libc")) {
+		t.Errorf("components are not in topological order: %v", c.Members)
+	}
+	if got := c.Succ[id("python")]; !slices.Equal(got, []int{id("libc")}) {
+		t.Errorf("python component depends on %v, want libc once", got)
+	}
+}
+
+func TestDependentCounts(t *testing.T) {
+	g := newTestGraph([][2]string{
+		{"app", "python"}, {"python", "pip"}, {"pip", "python"},
+		{"python", "libc"}, {"tool", "libc"},
+	})
+	g.Node("lonely")
+	counts := g.Condense().DependentCounts()
+	want := map[string]int{"app": 1, "python": 3, "pip": 3, "libc": 5, "tool": 1, "lonely": 1}
+	for name, n := range want {
+		v, _ := g.Lookup(name)
+		if counts[v] != n {
+			t.Errorf("DependentCounts of %s = %d, want %d", name, counts[v], n)
+		}
+	}
+}
+
+func TestReachCountsDeepChain(t *testing.T) {
+	g := New()
+	const n = 200000
+	for i := 0; i < n; i++ {
+		g.Node(strconv.Itoa(i))
+	}
+	for i := 1; i < n; i++ {
+		g.AddEdge(i-1, i)
+	}
+	// the last node is reached from all three roots
+	counts := g.Condense().ReachCounts([][]int{{0}, {n / 2}, {n - 1}})
+	if counts[n-1] != 3 || counts[n/2] != 2 || counts[0] != 1 {
+		t.Errorf("ReachCounts = %d %d %d, want 1 2 3", counts[0], counts[n/2], counts[n-1])
+	}
+}
+
+func TestReachCountsRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const n = 300
+	g := New()
+	for i := 0; i < n; i++ {
+		g.Node(strconv.Itoa(i))
+	}
+	for i := 0; i < 3*n; i++ {
+		g.AddEdge(rng.Intn(n), rng.Intn(n))
+	}
+	roots := make([][]int, 150)
+	for r := range roots {
+		roots[r] = []int{rng.Intn(n), rng.Intn(n)}
+	}
+
+	got := g.Condense().ReachCounts(roots)
+	// the same walked 64 roots at a time
+	defer func(b int) { maxBlockBytes = b }(maxBlockBytes)
+	maxBlockBytes = 1
+	if blocks := g.Condense().ReachCounts(roots); !slices.Equal(blocks, got) {
+		t.Errorf("ReachCounts in blocks = %v, want %v", blocks, got)
+	}
+	for v := 0; v < n; v++ {
+		want := 0
+		for _, root := range roots {
+			if reaches(g, root, v) {
+				want++
+			}
+		}
+		if got[v] != want {
+			t.Fatalf("ReachCounts of %d = %d, want %d", v, got[v], want)
+		}
+	}
+}
+
+func reaches(g *Graph, from []int, to int) bool {
+	visited := make([]bool, g.Len())
+	queue := slices.Clone(from)
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		if v == to {
+			return true
+		}
+		if visited[v] {
+			continue
+		}
+		visited[v] = true
+		queue = append(queue, g.Successors(v)...)
+	}
+	return false
+}
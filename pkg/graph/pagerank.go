@@ -0,0 +1,161 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Defaults of PageRankOptions
+const (
+	DefaultDamping       = 0.85
+	DefaultTolerance     = 1e-9
+	DefaultMaxIterations = 100
+)
+
+// PageRankOptions tune PageRank, the zero value uses the defaults.
+type PageRankOptions struct {
+	// Damping is the probability of following an edge rather than jumping
+	// to a random node, DefaultDamping if 0
+	Damping float64
+	// Tolerance stops the iterations once the ranks change by less, summed
+	// over all nodes, DefaultTolerance if 0
+	Tolerance float64
+	// MaxIterations stops the iterations even if the ranks have not
+	// converged, DefaultMaxIterations if 0
+	MaxIterations int
+	// Personalization is how likely a jump lands on each node, it need not
+	// sum to 1. Every node is as likely if nil.
+	Personalization []float64
+	// Weight returns the weight of an edge, the rank of a node is shared
+	// among its edges in proportion to their weights. Edges weigh 1 if nil,
+	// those weighing 0 or less are ignored.
+	Weight func(from, to int) float64
+}
+
+// PageRankResult are the ranks of the nodes, summing to 1, and how they
+// were reached.
+type PageRankResult struct {
+	Ranks      []float64
+	Iterations int
+	// Residual is the change of the ranks in the last iteration, summed
+	// over all nodes
+	Residual  float64
+	Converged bool
+}
+
+// PageRank ranks the nodes of the graph by power iteration. The rank of a
+// node flows to the nodes it depends on. The rank of a node without edges,
+// such as a package depending on nothing, is spread like a random jump
+// instead of being lost, so the ranks always sum to 1.
+func (g *Graph) PageRank(opts PageRankOptions) (*PageRankResult, error) {
+	n := g.Len()
+	d := opts.Damping
+	if d == 0 {
+		d = DefaultDamping
+	}
+	if d < 0 || d >= 1 {
+		return nil, fmt.Errorf("damping %v is not in [0, 1)", d)
+	}
+	tolerance := opts.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	maxIterations := opts.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxIterations
+	}
+	result := &PageRankResult{Ranks: make([]float64, n)}
+	if n == 0 {
+		result.Converged = true
+		return result, nil
+	}
+
+	jump, err := personalization(opts.Personalization, n)
+	if err != nil {
+		return nil, err
+	}
+
+	// outWeight is the summed weight of the edges of each node, 0 for a
+	// dangling node
+	weights := make([][]float64, n)
+	outWeight := make([]float64, n)
+	for v, succ := range g.succ {
+		weights[v] = make([]float64, len(succ))
+		for i, w := range succ {
+			weight := 1.0
+			if opts.Weight != nil {
+				weight = opts.Weight(v, w)
+			}
+			if weight > 0 && !math.IsInf(weight, 0) {
+				weights[v][i] = weight
+				outWeight[v] += weight
+			}
+		}
+	}
+
+	ranks := result.Ranks
+	copy(ranks, jump)
+	next := make([]float64, n)
+	for result.Iterations < maxIterations {
+		dangling := 0.0
+		for v, rank := range ranks {
+			if outWeight[v] == 0 {
+				dangling += rank
+			}
+		}
+		for v := range next {
+			next[v] = ((1 - d) + d*dangling) * jump[v]
+		}
+		for v, succ := range g.succ {
+			if outWeight[v] == 0 {
+				continue
+			}
+			share := d * ranks[v] / outWeight[v]
+			for i, w := range succ {
+				next[w] += share * weights[v][i]
+			}
+		}
+
+		result.Residual = 0
+		for v := range next {
+			result.Residual += math.Abs(next[v] - ranks[v])
+		}
+		ranks, next = next, ranks
+		result.Iterations++
+		if result.Residual < tolerance {
+			result.Converged = true
+			break
+		}
+	}
+	result.Ranks = ranks
+	return result, nil
+}
+
+// personalization returns the jump vector normalized to sum to 1
+func personalization(p []float64, n int) ([]float64, error) {
+	jump := make([]float64, n)
+	if p == nil {
+		for v := range jump {
+			jump[v] = 1 / float64(n)
+		}
+		return jump, nil
+	}
+	if len(p) != n {
+		return nil, fmt.Errorf("personalization has %d values for %d nodes", len(p), n)
+	}
+	sum := 0.0
+	for v, x := range p {
+		if x < 0 || math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, fmt.Errorf("personalization of node %d is %v", v, x)
+		}
+		sum += x
+	}
+	if sum == 0 {
+		return nil, errors.New("personalization is all zero")
+	}
+	for v, x := range p {
+		jump[v] = x / sum
+	}
+	return jump, nil
+}
@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"math"
+	"testing"
+)
+
+func sum(ranks []float64) float64 {
+	total := 0.0
+	for _, r := range ranks {
+		total += r
+	}
+	return total
+}
+
+func TestPageRankDangling(t *testing.T) {
+	// libc depends on nothing, its rank must not be lost
+	g := newTestGraph([][2]string{{"app", "python"}, {"python", "libc"}, {"tool", "libc"}})
+	result, err := g.PageRank(PageRankOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Converged || result.Residual >= DefaultTolerance {
+		t.Errorf("not converged after %d iterations, residual %v", result.Iterations, result.Residual)
+	}
+	if total := sum(result.Ranks); math.Abs(total-1) > 1e-9 {
+		t.Errorf("ranks sum to %v, want 1", total)
+	}
+	rank := func(name string) float64 {
+		v, _ := g.Lookup(name)
+		return result.Ranks[v]
+	}
+	if !(rank("libc") > rank("python") && rank("python") > rank("app")) {
+		t.Errorf("ranks = %v, want libc > python > app", result.Ranks)
+	}
+	for v, r := range result.Ranks {
+		if math.IsNaN(r) || math.IsInf(r, 0) || r <= 0 {
+			t.Errorf("rank of %s = %v", g.Name(v), r)
+		}
+	}
+}
+
+func TestPageRankKnownValues(t *testing.T) {
+	// a cycle of two nodes each depending on the other ranks them equally,
+	// a third node depending on both gets only the jump
+	g := newTestGraph([][2]string{{"a", "b"}, {"b", "a"}, {"c", "a"}, {"c", "b"}})
+	result, err := g.PageRank(PageRankOptions{Damping: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := g.Lookup("c")
+	if want := 0.5 / 3; math.Abs(result.Ranks[c]-want) > 1e-9 {
+		t.Errorf("rank of c = %v, want %v", result.Ranks[c], want)
+	}
+	if math.Abs(result.Ranks[0]-result.Ranks[1]) > 1e-9 {
+		t.Errorf("ranks of the cycle differ: %v", result.Ranks)
+	}
+}
+
+func TestPageRankWeightsAndPersonalization(t *testing.T) {
+	g := newTestGraph([][2]string{{"app", "gcc"}, {"app", "libc"}})
+	gcc, _ := g.Lookup("gcc")
+	result, err := g.PageRank(PageRankOptions{
+		Weight: func(from, to int) float64 {
+			if to == gcc {
+				return 3
+			}
+			return 1
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	libc, _ := g.Lookup("libc")
+	if result.Ranks[gcc] <= result.Ranks[libc] {
+		t.Errorf("gcc %v is not above libc %v with a heavier edge", result.Ranks[gcc], result.Ranks[libc])
+	}
+
+	app, _ := g.Lookup("app")
+	p := make([]float64, g.Len())
+	p[app] = 2
+	result, err = g.PageRank(PageRankOptions{Personalization: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(sum(result.Ranks)-1) > 1e-9 || result.Ranks[gcc] != result.Ranks[libc] {
+		t.Errorf("ranks = %v", result.Ranks)
+	}
+}
+
+func TestPageRankOptions(t *testing.T) {
+	g := newTestGraph([][2]string{{"a", "b"}})
+	if _, err := g.PageRank(PageRankOptions{Damping: 1}); err == nil {
+		t.Error("damping 1 accepted")
+	}
+	if _, err := g.PageRank(PageRankOptions{Personalization: []float64{1}}); err == nil {
+		t.Error("personalization of the wrong length accepted")
+	}
+	if _, err := g.PageRank(PageRankOptions{Personalization: []float64{0, 0}}); err == nil {
+		t.Error("all zero personalization accepted")
+	}
+	result, err := g.PageRank(PageRankOptions{MaxIterations: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Iterations != 1 || result.Converged {
+		t.Errorf("result = %+v, want one iteration without convergence", result)
+	}
+	if result, err := New().PageRank(PageRankOptions{}); err != nil || len(result.Ranks) != 0 {
+		t.Errorf("PageRank of an empty graph = %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,256 @@
+package score
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+)
+
+// Feature is a metric of a model used as a feature when calibrating the
+// model against labeled links.
+type Feature struct {
+	Category string
+	Metric   string
+}
+
+// Features returns the metrics declared in the model, including the custom
+// metrics, sorted by category and metric.
+func (m *Model) Features() []Feature {
+	features := make([]Feature, 0)
+	for category, cm := range m.Categories {
+		for metric := range cm.Metrics {
+			features = append(features, Feature{category, metric})
+		}
+	}
+	for metric, custom := range m.CustomMetrics {
+		features = append(features, Feature{custom.Category, metric})
+	}
+	sort.Slice(features, func(i, j int) bool {
+		if features[i].Category != features[j].Category {
+			return features[i].Category < features[j].Category
+		}
+		return features[i].Metric < features[j].Metric
+	})
+	return features
+}
+
+// FeatureValues returns the normalized value of each feature in the
+// breakdown of the score, 0 for features not in the breakdown.
+func (linkScore *LinkScore) FeatureValues(features []Feature) []float64 {
+	normalized := make(map[Feature]float64)
+	for _, c := range linkScore.Breakdown() {
+		if c.Metric != "" {
+			normalized[Feature{c.Category, c.Metric}] = c.Normalized
+		}
+	}
+	values := make([]float64, len(features))
+	for i, f := range features {
+		values[i] = normalized[f]
+	}
+	return values
+}
+
+// FitLogistic fits the coefficients and the intercept of a logistic
+// regression of the labels on x with Newton's method. Coefficients are
+// regularized by l2, the intercept is not. Positive and negative samples
+// weigh the same in total, so a few positives among many negatives are not
+// drowned out. It stops after iterations steps or when a step changes no
+// coefficient by more than 1e-9.
+func FitLogistic(x [][]float64, labels []bool, l2 float64, iterations int) ([]float64, float64) {
+	if len(x) == 0 {
+		return nil, 0
+	}
+	n, dim := len(x), len(x[0])
+
+	positives := 0
+	for _, label := range labels {
+		if label {
+			positives++
+		}
+	}
+	sampleWeight := func(label bool) float64 {
+		if label {
+			return float64(n) / float64(2*max(positives, 1))
+		}
+		return float64(n) / float64(2*max(n-positives, 1))
+	}
+
+	// theta holds the coefficients followed by the intercept
+	theta := make([]float64, dim+1)
+	row := make([]float64, dim+1)
+	for it := 0; it < iterations; it++ {
+		gradient := make([]float64, dim+1)
+		hessian := make([][]float64, dim+1)
+		for i := range hessian {
+			hessian[i] = make([]float64, dim+1)
+		}
+
+		for i := range x {
+			copy(row, x[i])
+			row[dim] = 1
+			z := 0.0
+			for j, v := range row {
+				z += theta[j] * v
+			}
+			p := 1 / (1 + math.Exp(-z))
+			y := 0.0
+			if labels[i] {
+				y = 1
+			}
+			w := sampleWeight(labels[i])
+			for j, vj := range row {
+				gradient[j] += w * (p - y) * vj
+				for k, vk := range row {
+					hessian[j][k] += w * p * (1 - p) * vj * vk
+				}
+			}
+		}
+		for j := 0; j < dim; j++ {
+			gradient[j] += l2 * theta[j]
+			hessian[j][j] += l2
+		}
+		// keeps the system solvable if a feature or the intercept is
+		// constant
+		for j := range hessian {
+			hessian[j][j] += 1e-9
+		}
+
+		step := solve(hessian, gradient)
+		if step == nil {
+			break
+		}
+		change := 0.0
+		for j := range theta {
+			theta[j] -= step[j]
+			change = math.Max(change, math.Abs(step[j]))
+		}
+		if change < 1e-9 {
+			break
+		}
+	}
+	return theta[:dim], theta[dim]
+}
+
+// solve solves a * x = b with Gaussian elimination and partial pivoting,
+// it returns nil if a is singular. a and b are modified.
+func solve(a [][]float64, b []float64) []float64 {
+	n := len(b)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if a[pivot][col] == 0 {
+			return nil
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for r := col + 1; r < n; r++ {
+			f := a[r][col] / a[col][col]
+			for c := col; c < n; c++ {
+				a[r][c] -= f * a[col][c]
+			}
+			b[r] -= f * b[col]
+		}
+	}
+
+	x := make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		sum := b[r]
+		for c := r + 1; c < n; c++ {
+			sum -= a[r][c] * x[c]
+		}
+		x[r] = sum / a[r][r]
+	}
+	return x
+}
+
+// CalibratedModel returns a copy of base which scores links by the sum of
+// the coefficients times the normalized feature values, scaled to at most
+// 100. Metric thresholds and normalizations are kept, the weight of each
+// metric becomes its coefficient divided by the absolute sum of the
+// coefficients of its category, the weight of each category that absolute
+// sum divided by the absolute sum of all coefficients, and categories are
+// normalized linearly. The ranking of the model is the ranking of the
+// fitted regression.
+func CalibratedModel(base *Model, features []Feature, coefficients []float64) *Model {
+	// deep copy, the normalizers are not exported and start fresh
+	data, _ := json.Marshal(base)
+	m := &Model{}
+	_ = json.Unmarshal(data, m)
+
+	categoryTotal := make(map[string]float64)
+	total := 0.0
+	for i, f := range features {
+		categoryTotal[f.Category] += math.Abs(coefficients[i])
+		total += math.Abs(coefficients[i])
+	}
+
+	for name, cm := range m.Categories {
+		cm.Normalization = NormalizationLinear
+		cm.Threshold = 0
+		cm.Weight = 0
+		if total > 0 {
+			cm.Weight = categoryTotal[name] / total
+		}
+		for _, mm := range cm.Metrics {
+			mm.Weight = 0
+		}
+	}
+	for _, custom := range m.CustomMetrics {
+		custom.Weight = 0
+	}
+	for i, f := range features {
+		if categoryTotal[f.Category] == 0 {
+			continue
+		}
+		if mm := m.Metric(f.Category, f.Metric); mm != nil {
+			mm.Weight = coefficients[i] / categoryTotal[f.Category]
+		}
+	}
+	return m
+}
+
+// AUC returns the area under the ROC curve of the scores, the probability
+// that a random positive scores higher than a random negative, ties count
+// half. It is 0.5 if there are no positives or no negatives.
+func AUC(scores []float64, labels []bool) float64 {
+	ranks := averageRanks(scores)
+	var positives, negatives, rankSum float64
+	for i, label := range labels {
+		if label {
+			positives++
+			rankSum += ranks[i]
+		} else {
+			negatives++
+		}
+	}
+	if positives == 0 || negatives == 0 {
+		return 0.5
+	}
+	return (rankSum - positives*(positives+1)/2) / (positives * negatives)
+}
+
+// PrecisionAtK returns the share of positives among the k highest scores.
+// Ties at the cut are broken by order.
+func PrecisionAtK(scores []float64, labels []bool, k int) float64 {
+	k = min(k, len(scores))
+	if k <= 0 {
+		return 0
+	}
+	idx := make([]int, len(scores))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return scores[idx[i]] > scores[idx[j]] })
+
+	hits := 0
+	for _, i := range idx[:k] {
+		if labels[i] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(k)
+}
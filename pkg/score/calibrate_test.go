@@ -0,0 +1,78 @@
+package score
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestFitLogistic(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var x [][]float64
+	var labels []bool
+	for i := 0; i < 2000; i++ {
+		signal, noise := r.Float64(), r.Float64()
+		x = append(x, []float64{signal, noise})
+		// one positive in ten, mostly among high signal values
+		labels = append(labels, r.Float64() < 0.2*signal*signal)
+	}
+
+	coefficients, _ := FitLogistic(x, labels, 1, 50)
+	if coefficients[0] <= 0 {
+		t.Errorf("coefficient of the signal = %v, want > 0", coefficients[0])
+	}
+	if math.Abs(coefficients[1]) > coefficients[0]/4 {
+		t.Errorf("coefficient of the noise = %v, want small against %v", coefficients[1], coefficients[0])
+	}
+}
+
+func TestAUC(t *testing.T) {
+	labels := []bool{true, false, true, false}
+	if got := AUC([]float64{4, 3, 2, 1}, labels); got != 0.75 {
+		t.Errorf("AUC = %v, want 0.75", got)
+	}
+	if got := AUC([]float64{1, 1, 1, 1}, labels); got != 0.5 {
+		t.Errorf("AUC of ties = %v, want 0.5", got)
+	}
+	if got := PrecisionAtK([]float64{4, 3, 2, 1}, labels, 2); got != 0.5 {
+		t.Errorf("PrecisionAtK = %v, want 0.5", got)
+	}
+}
+
+func TestCalibratedModel(t *testing.T) {
+	base := DefaultModel()
+	features := base.Features()
+	coefficients := make([]float64, len(features))
+	for i, f := range features {
+		switch f {
+		case Feature{CategoryDist, "dist_impact"}:
+			coefficients[i] = 3
+		case Feature{CategoryGit, "contributor_count"}:
+			coefficients[i] = -1
+		}
+	}
+
+	m := CalibratedModel(base, features, coefficients)
+	if err := m.Validate(); err != nil {
+		t.Fatalf("calibrated model is invalid: %v", err)
+	}
+	if w := m.Categories[CategoryDist].Weight; w != 0.75 {
+		t.Errorf("dist weight = %v, want 0.75", w)
+	}
+	if w := m.Metric(CategoryGit, "contributor_count").Weight; w != -1 {
+		t.Errorf("contributor_count weight = %v, want -1", w)
+	}
+	if w := m.Categories[CategoryLangEco].Weight; w != 0 {
+		t.Errorf("lang_eco weight = %v, want 0", w)
+	}
+	if base.Categories[CategoryDist].Weight != 0.5 {
+		t.Errorf("base model was modified")
+	}
+	// 100 * (3 * 0.5 - 1 * 0.2) / 4
+	want := 32.5
+	got := m.CategoryScore(CategoryDist, m.Metric(CategoryDist, "dist_impact").Weight*0.5) +
+		m.CategoryScore(CategoryGit, m.Metric(CategoryGit, "contributor_count").Weight*0.2)
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("score = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,269 @@
+package score
+
+import (
+	"math"
+	"sort"
+)
+
+// RankChange is the rank of a link in two rankings. Ranks start at 1, 0
+// means the link is not in the ranking.
+type RankChange struct {
+	Link     string
+	OldRank  int
+	NewRank  int
+	OldScore float64
+	NewScore float64
+}
+
+// Delta returns how many places the link moved up, negative if it moved
+// down.
+func (c *RankChange) Delta() int {
+	return c.OldRank - c.NewRank
+}
+
+// RankingComparison describes how a ranking changed
+type RankingComparison struct {
+	// Links in both rankings, the biggest movers first
+	Changes []*RankChange
+	// Correlation of the scores of the links in both rankings, in [-1, 1]
+	KendallTau float64
+	Spearman   float64
+	TopN       int
+	// Links which entered or left the top N, by rank
+	TopNEntered []*RankChange
+	TopNLeft    []*RankChange
+	// Number of links only in the new or only in the old ranking
+	Added   int
+	Removed int
+}
+
+// TopNChurn returns the share of the top N which is new.
+func (c *RankingComparison) TopNChurn() float64 {
+	if c.TopN == 0 {
+		return 0
+	}
+	return float64(len(c.TopNEntered)) / float64(c.TopN)
+}
+
+// Rank orders the links by score, the highest score gets rank 1. Ties are
+// broken by link so the ranking is stable.
+func Rank(scores map[string]float64) map[string]int {
+	links := make([]string, 0, len(scores))
+	for link := range scores {
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if scores[links[i]] != scores[links[j]] {
+			return scores[links[i]] > scores[links[j]]
+		}
+		return links[i] < links[j]
+	})
+
+	ranks := make(map[string]int, len(links))
+	for i, link := range links {
+		ranks[link] = i + 1
+	}
+	return ranks
+}
+
+// CompareRankings compares the ranking by old scores with the ranking by
+// new scores.
+func CompareRankings(oldScores, newScores map[string]float64, topN int) *RankingComparison {
+	oldRanks, newRanks := Rank(oldScores), Rank(newScores)
+	c := &RankingComparison{TopN: min(topN, len(newScores))}
+
+	var x, y []float64
+	for link, oldRank := range oldRanks {
+		change := &RankChange{Link: link, OldRank: oldRank, OldScore: oldScores[link]}
+		newRank, ok := newRanks[link]
+		if ok {
+			change.NewRank, change.NewScore = newRank, newScores[link]
+			c.Changes = append(c.Changes, change)
+			x = append(x, change.OldScore)
+			y = append(y, change.NewScore)
+		} else {
+			c.Removed++
+		}
+		if oldRank <= c.TopN && (!ok || newRank > c.TopN) {
+			c.TopNLeft = append(c.TopNLeft, change)
+		}
+	}
+	for link, newRank := range newRanks {
+		oldRank, ok := oldRanks[link]
+		if !ok {
+			c.Added++
+		}
+		if newRank <= c.TopN && (!ok || oldRank > c.TopN) {
+			c.TopNEntered = append(c.TopNEntered, &RankChange{
+				Link: link, OldRank: oldRank, NewRank: newRank,
+				OldScore: oldScores[link], NewScore: newScores[link],
+			})
+		}
+	}
+
+	sort.Slice(c.Changes, func(i, j int) bool {
+		di, dj := abs(c.Changes[i].Delta()), abs(c.Changes[j].Delta())
+		if di != dj {
+			return di > dj
+		}
+		return c.Changes[i].NewRank < c.Changes[j].NewRank
+	})
+	sort.Slice(c.TopNEntered, func(i, j int) bool { return c.TopNEntered[i].NewRank < c.TopNEntered[j].NewRank })
+	sort.Slice(c.TopNLeft, func(i, j int) bool { return c.TopNLeft[i].OldRank < c.TopNLeft[j].OldRank })
+
+	c.KendallTau = KendallTau(x, y)
+	c.Spearman = Spearman(x, y)
+	return c
+}
+
+// KendallTau returns the Kendall tau-b rank correlation of x and y, or 0
+// if it is undefined. It runs in O(n log n) with Knight's algorithm.
+func KendallTau(x, y []float64) float64 {
+	n := len(x)
+	if n < 2 || len(y) != n {
+		return 0
+	}
+
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool {
+		if x[idx[i]] != x[idx[j]] {
+			return x[idx[i]] < x[idx[j]]
+		}
+		return y[idx[i]] < y[idx[j]]
+	})
+
+	pairs := int64(n) * int64(n-1) / 2
+	// tiedX are the pairs tied in x, tiedXY the pairs tied in both
+	var tiedX, tiedXY int64
+	for i := 0; i < n; {
+		j, k := i, i
+		for j < n && x[idx[j]] == x[idx[i]] {
+			if y[idx[j]] != y[idx[k]] {
+				tiedXY += ties(int64(j - k))
+				k = j
+			}
+			j++
+		}
+		tiedXY += ties(int64(j - k))
+		tiedX += ties(int64(j - i))
+		i = j
+	}
+
+	ys := make([]float64, n)
+	for i, id := range idx {
+		ys[i] = y[id]
+	}
+	swaps := mergeSortSwaps(ys, make([]float64, n))
+
+	var tiedY int64
+	for i := 0; i < n; {
+		j := i
+		for j < n && ys[j] == ys[i] {
+			j++
+		}
+		tiedY += ties(int64(j - i))
+		i = j
+	}
+
+	denominator := math.Sqrt(float64(pairs-tiedX) * float64(pairs-tiedY))
+	if denominator == 0 {
+		return 0
+	}
+	return float64(pairs-tiedX-tiedY+tiedXY-2*swaps) / denominator
+}
+
+// Spearman returns the Spearman rank correlation of x and y, or 0 if it is
+// undefined. Ties get the average rank.
+func Spearman(x, y []float64) float64 {
+	if len(x) < 2 || len(y) != len(x) {
+		return 0
+	}
+	return pearson(averageRanks(x), averageRanks(y))
+}
+
+func ties(n int64) int64 {
+	return n * (n - 1) / 2
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// mergeSortSwaps sorts values and returns the number of swaps a bubble sort
+// would need, i.e. the number of strictly inverted pairs.
+func mergeSortSwaps(values, buf []float64) int64 {
+	if len(values) < 2 {
+		return 0
+	}
+	mid := len(values) / 2
+	swaps := mergeSortSwaps(values[:mid], buf[:mid]) + mergeSortSwaps(values[mid:], buf[mid:])
+
+	i, j, k := 0, mid, 0
+	for i < mid && j < len(values) {
+		if values[j] < values[i] {
+			buf[k] = values[j]
+			swaps += int64(mid - i)
+			j++
+		} else {
+			buf[k] = values[i]
+			i++
+		}
+		k++
+	}
+	k += copy(buf[k:], values[i:mid])
+	copy(buf[k:], values[j:])
+	copy(values, buf[:len(values)])
+	return swaps
+}
+
+func averageRanks(values []float64) []float64 {
+	idx := make([]int, len(values))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool { return values[idx[i]] < values[idx[j]] })
+
+	ranks := make([]float64, len(values))
+	for i := 0; i < len(idx); {
+		j := i
+		for j < len(idx) && values[idx[j]] == values[idx[i]] {
+			j++
+		}
+		// ranks i+1 .. j share their average
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			ranks[idx[k]] = rank
+		}
+		i = j
+	}
+	return ranks
+}
+
+func pearson(x, y []float64) float64 {
+	n := float64(len(x))
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
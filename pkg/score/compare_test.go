@@ -0,0 +1,104 @@
+package score
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// kendallTauNaive is the O(n^2) definition of Kendall tau-b
+func kendallTauNaive(x, y []float64) float64 {
+	var concordant, discordant, tiedX, tiedY float64
+	for i := range x {
+		for j := i + 1; j < len(x); j++ {
+			dx, dy := x[i]-x[j], y[i]-y[j]
+			switch {
+			case dx == 0 && dy == 0:
+			case dx == 0:
+				tiedX++
+			case dy == 0:
+				tiedY++
+			case dx*dy > 0:
+				concordant++
+			default:
+				discordant++
+			}
+		}
+	}
+	return (concordant - discordant) / math.Sqrt((concordant+discordant+tiedX)*(concordant+discordant+tiedY))
+}
+
+func TestKendallTau(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 2; n < 60; n++ {
+		x, y := make([]float64, n), make([]float64, n)
+		for i := range x {
+			// small ranges to get ties
+			x[i] = float64(r.Intn(8))
+			y[i] = float64(r.Intn(8))
+		}
+		want := kendallTauNaive(x, y)
+		if math.IsNaN(want) {
+			want = 0
+		}
+		if got := KendallTau(x, y); math.Abs(got-want) > 1e-9 {
+			t.Errorf("KendallTau(%v, %v) = %v, want %v", x, y, got, want)
+		}
+	}
+}
+
+func TestSpearman(t *testing.T) {
+	x := []float64{1, 2, 3, 4, 5}
+	if got := Spearman(x, []float64{2, 4, 6, 8, 10}); math.Abs(got-1) > 1e-9 {
+		t.Errorf("Spearman of same order = %v, want 1", got)
+	}
+	if got := Spearman(x, []float64{5, 4, 3, 2, 1}); math.Abs(got+1) > 1e-9 {
+		t.Errorf("Spearman of reverse order = %v, want -1", got)
+	}
+	// ranks of y are 1.5, 1.5, 3, 4, 5
+	if got := Spearman(x, []float64{1, 1, 2, 3, 4}); math.Abs(got-9.5/math.Sqrt(95)) > 1e-9 {
+		t.Errorf("Spearman with ties = %v, want %v", got, 9.5/math.Sqrt(95))
+	}
+}
+
+func TestCompareRankings(t *testing.T) {
+	oldScores := map[string]float64{"a": 4, "b": 3, "c": 2, "d": 1}
+	newScores := map[string]float64{"a": 1, "b": 3, "c": 2, "e": 5}
+
+	c := CompareRankings(oldScores, newScores, 2)
+	if c.Added != 1 || c.Removed != 1 {
+		t.Errorf("Added, Removed = %d, %d, want 1, 1", c.Added, c.Removed)
+	}
+	if len(c.Changes) != 3 || c.Changes[0].Link != "a" || c.Changes[0].Delta() != -3 {
+		t.Errorf("biggest mover = %+v, want a down 3", c.Changes[0])
+	}
+	if len(c.TopNEntered) != 1 || c.TopNEntered[0].Link != "e" || c.TopNEntered[0].OldRank != 0 {
+		t.Errorf("TopNEntered = %+v, want e", c.TopNEntered)
+	}
+	if len(c.TopNLeft) != 1 || c.TopNLeft[0].Link != "a" {
+		t.Errorf("TopNLeft = %+v, want a", c.TopNLeft)
+	}
+	if c.TopNChurn() != 0.5 {
+		t.Errorf("TopNChurn = %v, want 0.5", c.TopNChurn())
+	}
+}
+
+func TestCompareRankingsShortRanking(t *testing.T) {
+	oldScores := map[string]float64{"a": 4, "b": 3, "c": 2, "d": 1}
+	newScores := map[string]float64{"b": 3, "e": 5}
+
+	// the top 10 of two links is the top 2 of both rankings
+	c := CompareRankings(oldScores, newScores, 10)
+	if c.TopN != 2 {
+		t.Fatalf("TopN = %d, want 2", c.TopN)
+	}
+	if len(c.TopNLeft) != 1 || c.TopNLeft[0].Link != "a" {
+		t.Errorf("TopNLeft = %+v, want a", c.TopNLeft)
+	}
+	if len(c.TopNEntered) != 1 || c.TopNEntered[0].Link != "e" {
+		t.Errorf("TopNEntered = %+v, want e", c.TopNEntered)
+	}
+	if c.TopNChurn() != 0.5 {
+		t.Errorf("TopNChurn = %v, want 0.5", c.TopNChurn())
+	}
+}
@@ -0,0 +1,147 @@
+package score
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	log "github.com/HUSTSecLab/criticality_score/pkg/logger"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+// CustomMetricModel declares a metric read from the database and scored in
+// a category like the built-in metrics of the category, e.g.
+//
+//	custom_metrics:
+//	  security_advisories:
+//	    category: git
+//	    query: select git_link, count(*) from advisories group by git_link
+//	    weight: 0.5
+//	    threshold: 50
+//
+// The value is either the result of Query, whose first column is the git
+// link and second the value, or the Column of Table. Values of the same
+// link are summed, links without any value count 0.
+type CustomMetricModel struct {
+	// Category the metric is scored in: git, dist or lang_eco
+	Category string `mapstructure:"category" json:"category"`
+	Query    string `mapstructure:"query" json:"query,omitempty"`
+	Table    string `mapstructure:"table" json:"table,omitempty"`
+	Column   string `mapstructure:"column" json:"column,omitempty"`
+	// Column of Table ordering the rows of a link, such as update_time,
+	// only the last row of each link counts. Empty sums all rows.
+	LatestBy string `mapstructure:"latest_by" json:"latest_by,omitempty"`
+
+	MetricModel `mapstructure:",squash"`
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// SQL returns the query reading the values of the metric.
+func (c *CustomMetricModel) SQL() string {
+	if c.Query != "" {
+		return c.Query
+	}
+	if c.LatestBy == "" {
+		return fmt.Sprintf("SELECT git_link, %s FROM %s", c.Column, c.Table)
+	}
+	return fmt.Sprintf("SELECT DISTINCT ON (git_link) git_link, %s FROM %s ORDER BY git_link, %s DESC NULLS LAST",
+		c.Column, c.Table, c.LatestBy)
+}
+
+func (c *CustomMetricModel) validate(m *Model, name string) error {
+	switch c.Category {
+	case CategoryGit, CategoryDist, CategoryLangEco:
+	default:
+		return fmt.Errorf("unknown category %q", c.Category)
+	}
+	if _, ok := m.Categories[c.Category].Metrics[name]; ok {
+		return fmt.Errorf("metric %s.%s is already declared", c.Category, name)
+	}
+
+	if c.Query != "" {
+		if c.Table != "" || c.Column != "" || c.LatestBy != "" {
+			return fmt.Errorf("query cannot be combined with table, column or latest_by")
+		}
+	} else {
+		if c.Table == "" || c.Column == "" {
+			return fmt.Errorf("either query or table and column is required")
+		}
+		for _, identifier := range []string{c.Table, c.Column, c.LatestBy} {
+			if identifier != "" && !identifierPattern.MatchString(identifier) {
+				return fmt.Errorf("invalid identifier %q", identifier)
+			}
+		}
+	}
+	return validateNormalization(c.Normalization)
+}
+
+// customMetricNames returns the names of the custom metrics of the
+// category, sorted.
+func (m *Model) customMetricNames(category string) []string {
+	names := make([]string, 0)
+	for name, c := range m.CustomMetrics {
+		if c.Category == category {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FetchCustomMetrics reads the values of the custom metrics of the current
+// model for the links, keyed by link and metric name.
+func FetchCustomMetrics(ac storage.AppDatabaseContext, links []string) map[string]map[string]float64 {
+	values := make(map[string]map[string]float64, len(links))
+	repo := repository.NewCustomMetricRepository(ac)
+	for name, c := range currentModel.CustomMetrics {
+		rows, err := repo.QueryByLinks(c.SQL(), links)
+		if err != nil {
+			log.Fatalf("Failed to fetch custom metric %s: %v", name, err)
+		}
+		for row := range rows {
+			if row.Value == nil || *row.Value == nil {
+				continue
+			}
+			if values[*row.GitLink] == nil {
+				values[*row.GitLink] = make(map[string]float64)
+			}
+			values[*row.GitLink][name] = **row.Value
+		}
+	}
+	return values
+}
+
+// customMetricValues returns the values of the custom metrics of the
+// category, missing values count 0.
+func customMetricValues(category string, values map[string]float64) []metricValue {
+	names := currentModel.customMetricNames(category)
+	metricValues := make([]metricValue, 0, len(names))
+	for _, name := range names {
+		metricValues = append(metricValues, metricValue{name, values[name]})
+	}
+	return metricValues
+}
+
+// ObserveCustomMetrics feeds the custom metric values of a link to the
+// population normalizers of the current model, like ObserveMetrics.
+func ObserveCustomMetrics(values map[string]float64) {
+	for _, category := range []string{CategoryGit, CategoryDist, CategoryLangEco} {
+		observeMetricValues(category, customMetricValues(category, values))
+	}
+}
+
+// AddCustomMetrics adds the contributions of the custom metrics of the
+// link to its category scores. The category scores must be calculated.
+func (linkScore *LinkScore) AddCustomMetrics(values map[string]float64) {
+	add := func(category string, score *float64, contributions *[]Contribution) {
+		custom := metricContributions(category, customMetricValues(category, values))
+		*contributions = append(*contributions, custom...)
+		*score += sumContributions(custom)
+	}
+	add(CategoryGit, &linkScore.GitMetadataScore.GitMetadataScore, &linkScore.GitMetadataScore.Contributions)
+	add(CategoryDist, &linkScore.DistScore.DistScore, &linkScore.DistScore.Contributions)
+	add(CategoryLangEco, &linkScore.LangEcoScore.LangEcoScore, &linkScore.LangEcoScore.Contributions)
+}
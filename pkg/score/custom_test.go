@@ -0,0 +1,65 @@
+package score
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCustomMetrics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.yaml")
+	content := `
+name: custom
+categories:
+  git:
+    weight: 1
+    metrics:
+      contributor_count: {weight: 1, threshold: 100}
+  dist: {weight: 0}
+  lang_eco: {weight: 0}
+custom_metrics:
+  stars:
+    category: git
+    table: github_repos
+    column: stargazers
+    latest_by: update_time
+    weight: 2
+    threshold: 1000
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadModel(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mm := m.Metric(CategoryGit, "stars"); mm == nil || mm.Weight != 2 {
+		t.Fatalf("Metric(git, stars) = %v, want weight 2", mm)
+	}
+	if mm := m.Metric(CategoryDist, "stars"); mm != nil {
+		t.Errorf("Metric(dist, stars) = %v, want nil", mm)
+	}
+	want := "SELECT DISTINCT ON (git_link) git_link, stargazers FROM github_repos ORDER BY git_link, update_time DESC NULLS LAST"
+	if got := m.CustomMetrics["stars"].SQL(); got != want {
+		t.Errorf("SQL() = %q, want %q", got, want)
+	}
+
+	old := CurrentModel()
+	SetModel(m)
+	defer SetModel(old)
+
+	linkScore := NewLinkScore(NewGitMetadataScore(), NewDistScore(), NewLangEcoScore(), 1)
+	linkScore.AddCustomMetrics(map[string]float64{"stars": 999})
+	wantScore := 2 * LogNormalize(999, 1000)
+	if math.Abs(linkScore.GitMetadataScore.GitMetadataScore-wantScore) > 1e-9 {
+		t.Errorf("git score = %v, want %v", linkScore.GitMetadataScore.GitMetadataScore, wantScore)
+	}
+	contributions := linkScore.GitMetadataScore.Contributions
+	if len(contributions) != 1 || contributions[0].Metric != "stars" || contributions[0].Value != 999 {
+		t.Errorf("contributions = %v, want one contribution of stars", contributions)
+	}
+	if len(linkScore.DistScore.Contributions) != 0 {
+		t.Errorf("dist contributions = %v, want none", linkScore.DistScore.Contributions)
+	}
+}
@@ -0,0 +1,324 @@
+package score
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"sort"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
+)
+
+// Kinds of anomalies of a metric between two rounds
+const (
+	// the metric was positive and is 0
+	AnomalyZero = "zero"
+	// the metric dropped by at least DiffOptions.DropShare
+	AnomalyDrop = "drop"
+	// the metric grew by at least DiffOptions.RiseFactor
+	AnomalyRise = "rise"
+)
+
+// DiffOptions sets how many changes are listed and which metric changes
+// are anomalies.
+type DiffOptions struct {
+	// Number of links listed in each list of the diff
+	Top int
+	// A metric dropping by this share or more is an anomaly, e.g. 0.9
+	DropShare float64
+	// A metric growing by this factor or more is an anomaly, e.g. 10
+	RiseFactor float64
+	// Metrics never reported as anomalies, such as updated_since which goes
+	// to 0 whenever a project commits
+	IgnoreMetrics []string
+}
+
+// DefaultDiffOptions returns the options used when none are given.
+func DefaultDiffOptions() DiffOptions {
+	return DiffOptions{
+		Top:           50,
+		DropShare:     0.9,
+		RiseFactor:    10,
+		IgnoreMetrics: []string{"updated_since"},
+	}
+}
+
+// MetricChange is the value and contribution of a metric in two rounds
+type MetricChange struct {
+	Category        string
+	Metric          string
+	OldValue        float64
+	NewValue        float64
+	OldContribution float64
+	NewContribution float64
+}
+
+// ContributionDelta returns how much the contribution of the metric grew.
+func (c *MetricChange) ContributionDelta() float64 {
+	return c.NewContribution - c.OldContribution
+}
+
+// LinkChange is the score and rank change of a link between two rounds,
+// with the metric which caused it.
+type LinkChange struct {
+	*RankChange
+	// Metric whose contribution changed the most, within the category
+	// whose contribution changed the most. nil if the scores of either
+	// round have no breakdown.
+	Cause *MetricChange
+}
+
+// ScoreDelta returns how much the score grew.
+func (c *LinkChange) ScoreDelta() float64 {
+	return c.NewScore - c.OldScore
+}
+
+// Anomaly is a metric of a link which changed abnormally between two
+// rounds, usually a collection bug.
+type Anomaly struct {
+	Link string
+	Kind string
+	*MetricChange
+}
+
+// Describe returns a short description of the anomaly, such as
+// "contributor_count dropped by 95%".
+func (a *Anomaly) Describe() string {
+	switch a.Kind {
+	case AnomalyZero:
+		return fmt.Sprintf("%s went from %g to zero", a.Metric, a.OldValue)
+	case AnomalyDrop:
+		return fmt.Sprintf("%s dropped by %.0f%%", a.Metric, (1-a.NewValue/a.OldValue)*100)
+	default:
+		return fmt.Sprintf("%s grew %.1fx", a.Metric, a.NewValue/a.OldValue)
+	}
+}
+
+// severity orders anomalies, the biggest relative change first
+func (a *Anomaly) severity() float64 {
+	if a.NewValue == 0 || a.OldValue == 0 {
+		return math.Inf(1)
+	}
+	return math.Abs(math.Log(a.NewValue / a.OldValue))
+}
+
+// RoundDiff compares the scores of a series in two rounds. Every list is
+// limited to DiffOptions.Top links, the counts are the full sizes.
+type RoundDiff struct {
+	Series   string
+	OldRound int
+	NewRound int
+	// Links only in the new round by new rank, and only in the old round
+	// by old rank
+	Added        []*RankChange
+	AddedCount   int
+	Removed      []*RankChange
+	RemovedCount int
+	// Links in both rounds with the largest score changes and the largest
+	// rank changes
+	ScoreChanges []*LinkChange
+	RankChanges  []*LinkChange
+	Anomalies    []*Anomaly
+	AnomalyCount int
+	// Correlation of the scores of the links in both rounds
+	KendallTau float64
+	Spearman   float64
+}
+
+// roundLink is the score and the breakdown of a link in a round
+type roundLink struct {
+	link    string
+	score   float64
+	metrics map[[2]string]*repository.ScoreMetric
+}
+
+// groupByLink groups the breakdown rows of each link, rows must be sorted
+// by link.
+func groupByLink(rows iter.Seq[*repository.ScoreMetric]) iter.Seq[*roundLink] {
+	return func(yield func(*roundLink) bool) {
+		var current *roundLink
+		for row := range rows {
+			if current == nil || current.link != *row.GitLink {
+				if current != nil && !yield(current) {
+					return
+				}
+				current = &roundLink{link: *row.GitLink, score: lo.FromPtr(row.Score), metrics: make(map[[2]string]*repository.ScoreMetric)}
+			}
+			if row.Category != nil && *row.Category != nil {
+				current.metrics[[2]string{**row.Category, lo.FromPtr(lo.FromPtr(row.Metric))}] = row
+			}
+		}
+		if current != nil {
+			yield(current)
+		}
+	}
+}
+
+// DiffRounds compares the scores of the series in oldRound and newRound.
+// Both rounds are read once in link order, only the scores and the change
+// of each link are kept in memory.
+func DiffRounds(ac storage.AppDatabaseContext, series string, oldRound, newRound int, opts DiffOptions) (*RoundDiff, error) {
+	repo := repository.NewScoreRepository(ac)
+	oldRows, err := repo.QueryMetricsByRound(series, oldRound)
+	if err != nil {
+		return nil, err
+	}
+	newRows, err := repo.QueryMetricsByRound(series, newRound)
+	if err != nil {
+		return nil, err
+	}
+	diff := diffLinks(groupByLink(oldRows), groupByLink(newRows), opts)
+	diff.Series, diff.OldRound, diff.NewRound = series, oldRound, newRound
+	return diff, nil
+}
+
+// diffLinks compares two rounds given as links sorted in byte order.
+func diffLinks(oldLinks, newLinks iter.Seq[*roundLink], opts DiffOptions) *RoundDiff {
+	nextOld, stopOld := iter.Pull(oldLinks)
+	defer stopOld()
+	nextNew, stopNew := iter.Pull(newLinks)
+	defer stopNew()
+
+	oldScores, newScores := make(map[string]float64), make(map[string]float64)
+	causes := make(map[string]*MetricChange)
+	var anomalies []*Anomaly
+
+	o, oldOk := nextOld()
+	n, newOk := nextNew()
+	for oldOk || newOk {
+		switch {
+		case newOk && (!oldOk || n.link < o.link):
+			newScores[n.link] = n.score
+			n, newOk = nextNew()
+		case oldOk && (!newOk || o.link < n.link):
+			oldScores[o.link] = o.score
+			o, oldOk = nextOld()
+		default:
+			oldScores[o.link], newScores[n.link] = o.score, n.score
+			changes := metricChanges(o, n)
+			if cause := mainCause(changes); cause != nil {
+				causes[o.link] = cause
+			}
+			for _, c := range changes {
+				if kind := anomalyKind(c, opts); kind != "" {
+					anomalies = append(anomalies, &Anomaly{Link: o.link, Kind: kind, MetricChange: c})
+				}
+			}
+			o, oldOk = nextOld()
+			n, newOk = nextNew()
+		}
+	}
+
+	comparison := CompareRankings(oldScores, newScores, 0)
+	diff := &RoundDiff{
+		AddedCount:   comparison.Added,
+		RemovedCount: comparison.Removed,
+		AnomalyCount: len(anomalies),
+		KendallTau:   comparison.KendallTau,
+		Spearman:     comparison.Spearman,
+	}
+
+	oldRanks, newRanks := Rank(oldScores), Rank(newScores)
+	for link, rank := range newRanks {
+		if _, ok := oldScores[link]; !ok {
+			diff.Added = append(diff.Added, &RankChange{Link: link, NewRank: rank, NewScore: newScores[link]})
+		}
+	}
+	for link, rank := range oldRanks {
+		if _, ok := newScores[link]; !ok {
+			diff.Removed = append(diff.Removed, &RankChange{Link: link, OldRank: rank, OldScore: oldScores[link]})
+		}
+	}
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].NewRank < diff.Added[j].NewRank })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].OldRank < diff.Removed[j].OldRank })
+
+	// comparison.Changes are sorted by rank change
+	changes := lo.Map(comparison.Changes, func(c *RankChange, _ int) *LinkChange {
+		return &LinkChange{RankChange: c, Cause: causes[c.Link]}
+	})
+	diff.RankChanges = changes[:min(opts.Top, len(changes))]
+	byScore := append([]*LinkChange(nil), changes...)
+	sort.SliceStable(byScore, func(i, j int) bool {
+		return math.Abs(byScore[i].ScoreDelta()) > math.Abs(byScore[j].ScoreDelta())
+	})
+	diff.ScoreChanges = byScore[:min(opts.Top, len(byScore))]
+
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		if si, sj := anomalies[i].severity(), anomalies[j].severity(); si != sj {
+			return si > sj
+		}
+		return anomalies[i].Link < anomalies[j].Link
+	})
+	diff.Anomalies = anomalies[:min(opts.Top, len(anomalies))]
+	diff.Added = diff.Added[:min(opts.Top, len(diff.Added))]
+	diff.Removed = diff.Removed[:min(opts.Top, len(diff.Removed))]
+	return diff
+}
+
+// metricChanges returns the changes of the categories and metrics in the
+// breakdown of both rounds, sorted by category and metric.
+func metricChanges(o, n *roundLink) []*MetricChange {
+	changes := make([]*MetricChange, 0, len(n.metrics))
+	for key, newMetric := range n.metrics {
+		oldMetric, ok := o.metrics[key]
+		if !ok {
+			continue
+		}
+		changes = append(changes, &MetricChange{
+			Category:        key[0],
+			Metric:          key[1],
+			OldValue:        lo.FromPtr(lo.FromPtr(oldMetric.Value)),
+			NewValue:        lo.FromPtr(lo.FromPtr(newMetric.Value)),
+			OldContribution: lo.FromPtr(lo.FromPtr(oldMetric.Contribution)),
+			NewContribution: lo.FromPtr(lo.FromPtr(newMetric.Contribution)),
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Category != changes[j].Category {
+			return changes[i].Category < changes[j].Category
+		}
+		return changes[i].Metric < changes[j].Metric
+	})
+	return changes
+}
+
+// mainCause returns the metric whose contribution changed the most within
+// the category whose contribution changed the most, or among all metrics
+// if there are no category contributions. It returns nil if no metric
+// changed.
+func mainCause(changes []*MetricChange) *MetricChange {
+	biggest := func(keep func(c *MetricChange) bool) *MetricChange {
+		var best *MetricChange
+		for _, c := range changes {
+			if keep(c) && c.ContributionDelta() != 0 &&
+				(best == nil || math.Abs(c.ContributionDelta()) > math.Abs(best.ContributionDelta())) {
+				best = c
+			}
+		}
+		return best
+	}
+
+	category := biggest(func(c *MetricChange) bool { return c.Metric == "" })
+	return biggest(func(c *MetricChange) bool {
+		return c.Metric != "" && (category == nil || c.Category == category.Category)
+	})
+}
+
+// anomalyKind returns the kind of anomaly of the metric change, or an
+// empty string if it is normal.
+func anomalyKind(c *MetricChange, opts DiffOptions) string {
+	if c.Metric == "" || c.OldValue <= 0 || lo.Contains(opts.IgnoreMetrics, c.Metric) {
+		return ""
+	}
+	switch {
+	case c.NewValue == 0:
+		return AnomalyZero
+	case opts.DropShare > 0 && c.NewValue <= c.OldValue*(1-opts.DropShare):
+		return AnomalyDrop
+	case opts.RiseFactor > 0 && c.NewValue >= c.OldValue*opts.RiseFactor:
+		return AnomalyRise
+	}
+	return ""
+}
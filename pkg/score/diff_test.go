@@ -0,0 +1,70 @@
+package score
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
+)
+
+func testRoundLink(link string, score float64, metrics map[[2]string][2]float64) *roundLink {
+	l := &roundLink{link: link, score: score, metrics: make(map[[2]string]*repository.ScoreMetric)}
+	for key, v := range metrics {
+		l.metrics[key] = &repository.ScoreMetric{
+			Value:        lo.ToPtr(lo.ToPtr(v[0])),
+			Contribution: lo.ToPtr(lo.ToPtr(v[1])),
+		}
+	}
+	return l
+}
+
+func TestDiffLinks(t *testing.T) {
+	oldLinks := []*roundLink{
+		testRoundLink("a", 50, map[[2]string][2]float64{
+			{CategoryGit, ""}:                  {0.5, 10},
+			{CategoryGit, "contributor_count"}: {1000, 0.4},
+			{CategoryGit, "updated_since"}:     {3, -0.1},
+			{CategoryDist, ""}:                 {1, 40},
+			{CategoryDist, "dist_impact"}:      {5, 0.5},
+		}),
+		testRoundLink("b", 40, nil),
+		testRoundLink("c", 30, nil),
+	}
+	newLinks := []*roundLink{
+		testRoundLink("a", 20, map[[2]string][2]float64{
+			{CategoryGit, ""}:                  {0.4, 8},
+			{CategoryGit, "contributor_count"}: {50, 0.2},
+			{CategoryGit, "updated_since"}:     {0, 0},
+			{CategoryDist, ""}:                 {0, 12},
+			{CategoryDist, "dist_impact"}:      {0, 0},
+		}),
+		testRoundLink("c", 30, nil),
+		testRoundLink("d", 60, nil),
+	}
+
+	diff := diffLinks(slices.Values(oldLinks), slices.Values(newLinks), DefaultDiffOptions())
+	if diff.AddedCount != 1 || diff.Added[0].Link != "d" || diff.RemovedCount != 1 || diff.Removed[0].Link != "b" {
+		t.Errorf("added %+v, removed %+v, want d and b", diff.Added, diff.Removed)
+	}
+	if len(diff.ScoreChanges) != 2 || diff.ScoreChanges[0].Link != "a" || diff.ScoreChanges[0].ScoreDelta() != -30 {
+		t.Fatalf("biggest score change = %+v, want a down 30", diff.ScoreChanges[0])
+	}
+	if cause := diff.ScoreChanges[0].Cause; cause == nil || cause.Metric != "dist_impact" {
+		t.Errorf("cause = %+v, want dist_impact", cause)
+	}
+	if diff.ScoreChanges[1].Cause != nil {
+		t.Errorf("cause without breakdown = %+v, want nil", diff.ScoreChanges[1].Cause)
+	}
+
+	// updated_since going to 0 is ignored
+	if diff.AnomalyCount != 2 {
+		t.Fatalf("got %d anomalies, want 2", diff.AnomalyCount)
+	}
+	if a := diff.Anomalies[0]; a.Metric != "dist_impact" || a.Kind != AnomalyZero {
+		t.Errorf("first anomaly = %s, want dist_impact to zero", a.Describe())
+	}
+	if a := diff.Anomalies[1]; a.Metric != "contributor_count" || a.Describe() != "contributor_count dropped by 95%" {
+		t.Errorf("second anomaly = %s, want contributor_count dropped by 95%%", a.Describe())
+	}
+}
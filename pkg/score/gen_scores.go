@@ -1,6 +1,8 @@
 package score
 
 import (
+	"fmt"
+	"iter"
 	"math"
 	"time"
 
@@ -9,6 +11,7 @@ import (
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/samber/lo"
 )
 
 type LinkScore struct {
@@ -20,6 +23,16 @@ type LinkScore struct {
 	DistScore DistScore
 	Score     float64
 	Round     int
+	// Contributions of each category to Score
+	Contributions []Contribution
+	// Confidence in [0, 1] of the score, based on which inputs are present
+	// and how fresh they are. 0 means there is no data, not that the link is
+	// not critical.
+	Confidence float64
+	// Score and signal contributions of the link in the OSSF series, set by
+	// CalculateOSSFScore
+	OSSFScore         float64
+	OSSFContributions []Contribution
 }
 
 type GitMetadata struct {
@@ -29,11 +42,24 @@ type GitMetadata struct {
 	ContributorCount int
 	CommitFrequency  float64
 	Org_Count        int
+	// UpdateTime of the git_metrics row, zero if unknown
+	UpdateTime time.Time
+	// Number of metrics which are null in the git_metrics row
+	MissingMetrics int
+	// Present is set by ParseMetadata, an empty GitMetadata stands for a
+	// link without git_metrics row
+	Present bool
 }
 
 type GitMetadataScore struct {
+	// empty if the link has no git metrics
 	GitMetrics       []*repository.GitMetric
 	GitMetadataScore float64
+	Contributions    []Contribution
+	// UpdateTime of the git metrics and the share of metrics present, both
+	// zero if the link has no git metrics
+	UpdateTime   time.Time
+	Completeness float64
 }
 
 type DistMetadata struct {
@@ -42,6 +68,9 @@ type DistMetadata struct {
 	DepCount  int
 	PageRank  float64
 	Type      repository.DistType
+	// Whether a package of the link is installed by default
+	DefaultInstall bool
+	Kind           repository.DepKind
 }
 
 type LangEcoMetadata struct {
@@ -57,6 +86,28 @@ type DistScore struct {
 	DistImpact       float64
 	DistPageRank     float64
 	DistScore        float64
+	Contributions    []Contribution
+	// Contribution of each of DistDependencies to DistImpact and
+	// DistPageRank, in the same order
+	DistContributions []DistContribution
+	// Weighted count of the distributions installing the link by default
+	DistDefaultInstall float64
+	// Weighted impact and pagerank computed on the build, test and optional
+	// dependencies, DistImpact and DistPageRank are the runtime ones
+	KindImpact   map[repository.DepKind]float64
+	KindPageRank map[repository.DepKind]float64
+}
+
+// DistContribution is the part of DistImpact and DistPageRank coming from
+// one distribution.
+type DistContribution struct {
+	Type     repository.DistType
+	Weight   float64
+	Impact   float64
+	PageRank float64
+	// Weight if the distribution installs the link by default, otherwise 0
+	DefaultInstall float64
+	Kind           repository.DepKind
 }
 
 type LangEcoScore struct {
@@ -64,74 +115,63 @@ type LangEcoScore struct {
 	LangEcoImpact   float64
 	LangEcoPageRank float64
 	LangEcoScore    float64
+	Contributions   []Contribution
 }
 
 var SigmoidWeight = 1.2
 
-// Define weights (αi) and max thresholds (Ti)
-var weights = map[string]map[string]float64{
-	"gitMetadataScore": {
-		"created_since":     1,
-		"updated_since":     -1,
-		"contributor_count": 2,
-		"commit_frequency":  1,
-		"org_count":         1,
-		"gitMetadataScore":  0.2,
-	},
-	"distScore": {
-		"dist_impact":   1,
-		"dist_pagerank": 1,
-		"distScore":     0.5,
-	},
-	"langEcoScore": {
-		"lang_eco_impact":   1,
-		"lang_eco_pagerank": 1,
-		"langEcoScore":      0.3,
-	},
-}
-
-var thresholds = map[string]map[string]float64{
-	"gitMetadataScore": {
-		"created_since":     120,
-		"updated_since":     120,
-		"contributor_count": 40000,
-		"commit_frequency":  1000,
-		"org_count":         8400,
-		"gitMetadataScore":  5,
-	},
-	"distScore": {
-		"dist_impact":   22,
-		"dist_pagerank": 3,
-		"distScore":     1.5,
-	},
-	"langEcoScore": {
-		"lang_eco_impact":   1,
-		"lang_eco_pagerank": 0.0002,
-		"langEcoScore":      1.3,
-	},
+// asOf is the reference time of all score calculations, zero means now
+var asOf time.Time
+
+// SetAsOf sets the reference time of all score calculations in this
+// package. Inputs updated after it are ignored, and ages such as the months
+// since creation are measured against it. A zero time means now, read again
+// on every call, so the commands set it once at startup.
+func SetAsOf(t time.Time) {
+	asOf = t
+}
+
+// AsOf returns the reference time of all score calculations in this package.
+func AsOf() time.Time {
+	if asOf.IsZero() {
+		return time.Now()
+	}
+	return asOf
+}
+
+// ParseAsOf parses a reference time given as RFC 3339 or as a date, which
+// means the end of that day in UTC.
+func ParseAsOf(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid reference time %s, expect RFC 3339 or YYYY-MM-DD", s)
+	}
+	return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
 }
 
+// ConfidenceHalfLife is the age of an input at which it counts half in the
+// confidence of a score.
+var ConfidenceHalfLife = 180 * 24 * time.Hour
+
 var PackageList = map[repository.DistType]int{
-	repository.Debian:   0,
-	repository.Arch:     0,
-	repository.Nix:      0,
-	repository.Homebrew: 0,
-	repository.Gentoo:   0,
-	repository.Alpine:   0,
-	repository.Fedora:   0,
-	repository.Ubuntu:   0,
-	repository.Deepin:   0,
-	repository.Aur:      0,
-	repository.Centos:   0,
-}
-
-var PackageWeight = map[repository.LangEcosystemType]float64{
-	repository.Npm:   1.5,
-	repository.Go:    1.4,
-	repository.Maven: 1.3,
-	repository.Pypi:  1.2,
-	repository.NuGet: 1.1,
-	repository.Cargo: 1,
+	repository.Debian:    0,
+	repository.Arch:      0,
+	repository.Nix:       0,
+	repository.Homebrew:  0,
+	repository.Gentoo:    0,
+	repository.Alpine:    0,
+	repository.Fedora:    0,
+	repository.Ubuntu:    0,
+	repository.Deepin:    0,
+	repository.Aur:       0,
+	repository.Centos:    0,
+	repository.Rocky:     0,
+	repository.Alma:      0,
+	repository.OpenEuler: 0,
+	repository.OpenSUSE:  0,
 }
 
 func (langEcoMetadata *LangEcoMetadata) ParseLangEcoMetadata(langEcosystem *repository.LangEcosystem) {
@@ -148,82 +188,256 @@ func (distMetadata *DistMetadata) PraseDistMetadata(distLink *repository.DistDep
 	distMetadata.DepImpact = *distLink.DepImpact
 	distMetadata.PageRank = *distLink.PageRank
 	distMetadata.Type = *distLink.Type
+	distMetadata.DefaultInstall = distLink.DefaultInstall != nil && *distLink.DefaultInstall
+	distMetadata.Kind = lo.FromPtrOr(distLink.Kind, repository.DepKindRuntime)
 }
 
 func (gitMetadata *GitMetadata) ParseMetadata(gitMetic *repository.GitMetric) {
 	gitMetadata.Id = *gitMetic.ID
 	if !sqlutil.IsNull(gitMetic.CreatedSince) {
 		gitMetadata.CreatedSince = **gitMetic.CreatedSince
+	} else {
+		gitMetadata.MissingMetrics++
 	}
 	if !sqlutil.IsNull(gitMetic.UpdatedSince) {
 		gitMetadata.UpdatedSince = **gitMetic.UpdatedSince
+	} else {
+		gitMetadata.MissingMetrics++
 	}
 	if !sqlutil.IsNull(gitMetic.ContributorCount) {
 		gitMetadata.ContributorCount = **gitMetic.ContributorCount
+	} else {
+		gitMetadata.MissingMetrics++
 	}
 	if !sqlutil.IsNull(gitMetic.CommitFrequency) {
 		gitMetadata.CommitFrequency = **gitMetic.CommitFrequency
+	} else {
+		gitMetadata.MissingMetrics++
 	}
 	if !sqlutil.IsNull(gitMetic.OrgCount) {
 		gitMetadata.Org_Count = **gitMetic.OrgCount
+	} else {
+		gitMetadata.MissingMetrics++
+	}
+	if !sqlutil.IsNull(gitMetic.UpdateTime) {
+		gitMetadata.UpdateTime = **gitMetic.UpdateTime
+	}
+	gitMetadata.Present = true
+}
+
+func (langEcoScore *LangEcoScore) metricValues() []metricValue {
+	return []metricValue{
+		{"lang_eco_impact", langEcoScore.LangEcoImpact},
+		{"lang_eco_pagerank", langEcoScore.LangEcoPageRank},
 	}
 }
 
 func (langEcoScore *LangEcoScore) CalculateLangEcoScore() {
-	langEcoScore.LangEcoScore = weights["langEcoScore"]["lang_eco_impact"]*LogNormalize(langEcoScore.LangEcoImpact, thresholds["langEcoScore"]["lang_eco_impact"]) + weights["langEcoScore"]["lang_eco_pagerank"]*LogNormalize(langEcoScore.LangEcoPageRank, thresholds["langEcoScore"]["lang_eco_pagerank"])
+	langEcoScore.Contributions = metricContributions(CategoryLangEco, langEcoScore.metricValues())
+	langEcoScore.LangEcoScore = sumContributions(langEcoScore.Contributions)
 }
 
 func NewLangEcoScore() *LangEcoScore {
 	return &LangEcoScore{}
 }
 
-func (gitMetadataScore *GitMetadataScore) CalculateGitMetadataScore(gitMetadata *GitMetadata) {
-	var score float64
-	var createdSinceScore, updatedSinceScore, contributorCountScore, commitFrequencyScore, orgCountScore float64
-
-	monthsSinceCreation := time.Since(gitMetadata.CreatedSince).Hours() / (24 * 30)
-	createdSinceScore = weights["gitMetadataScore"]["created_since"] * LogNormalize(monthsSinceCreation, thresholds["gitMetadataScore"]["created_since"])
-	score += createdSinceScore
-
-	monthsSinceUpdate := time.Since(gitMetadata.UpdatedSince).Hours() / (24 * 30)
-	updatedSinceScore = weights["gitMetadataScore"]["updated_since"] * LogNormalize(monthsSinceUpdate, thresholds["gitMetadataScore"]["updated_since"])
-	score += updatedSinceScore
-
-	contributorCountScore = weights["gitMetadataScore"]["contributor_count"] * LogNormalize(float64(gitMetadata.ContributorCount), thresholds["gitMetadataScore"]["contributor_count"])
-	score += contributorCountScore
+func (gitMetadata *GitMetadata) metricValues() []metricValue {
+	now := AsOf()
+	monthsSinceCreation := now.Sub(gitMetadata.CreatedSince).Hours() / (24 * 30)
+	monthsSinceUpdate := now.Sub(gitMetadata.UpdatedSince).Hours() / (24 * 30)
 
-	commitFrequencyScore = weights["gitMetadataScore"]["commit_frequency"] * LogNormalize(gitMetadata.CommitFrequency, thresholds["gitMetadataScore"]["commit_frequency"])
-	score += commitFrequencyScore
-
-	orgCountScore = weights["gitMetadataScore"]["org_count"] * LogNormalize(float64(gitMetadata.Org_Count), thresholds["gitMetadataScore"]["org_count"])
-	score += orgCountScore
+	return []metricValue{
+		{"created_since", monthsSinceCreation},
+		{"updated_since", monthsSinceUpdate},
+		{"contributor_count", float64(gitMetadata.ContributorCount)},
+		{"commit_frequency", gitMetadata.CommitFrequency},
+		{"org_count", float64(gitMetadata.Org_Count)},
+	}
+}
 
-	gitMetadataScore.GitMetadataScore = score
+func (gitMetadataScore *GitMetadataScore) CalculateGitMetadataScore(gitMetadata *GitMetadata) {
+	values := gitMetadata.metricValues()
+	gitMetadataScore.Contributions = metricContributions(CategoryGit, values)
+	gitMetadataScore.GitMetadataScore = sumContributions(gitMetadataScore.Contributions)
+	if !gitMetadata.Present {
+		return
+	}
 	gitMetadataScore.GitMetrics = []*repository.GitMetric{
 		{
 			ID: sqlutil.ToData(gitMetadata.Id),
 		},
 	}
+	gitMetadataScore.UpdateTime = gitMetadata.UpdateTime
+	gitMetadataScore.Completeness = float64(len(values)-gitMetadata.MissingMetrics) / float64(len(values))
 }
 
 func NewGitMetadata() *GitMetadata {
 	return &GitMetadata{}
 }
 
+// KindMetric returns the name of the dist metric computed on the
+// dependencies of the kind other than runtime, e.g. dist_build_impact.
+func KindMetric(kind repository.DepKind, metric string) string {
+	return fmt.Sprintf("dist_%s_%s", kind, metric)
+}
+
+// metricValues returns the runtime metrics, and the metrics of the other
+// kinds of dependency declared in the current model.
+func (distScore *DistScore) metricValues() []metricValue {
+	values := []metricValue{
+		{"dist_impact", distScore.DistImpact},
+		{"dist_pagerank", distScore.DistPageRank},
+		{"default_install", distScore.DistDefaultInstall},
+	}
+	for _, kind := range repository.DepKinds[1:] {
+		for _, v := range []metricValue{
+			{KindMetric(kind, "impact"), distScore.KindImpact[kind]},
+			{KindMetric(kind, "pagerank"), distScore.KindPageRank[kind]},
+		} {
+			if currentModel.Metric(CategoryDist, v.name) != nil {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// weights returns the weight of each of DistDependencies
+func (distScore *DistScore) weights() []float64 {
+	return lo.Map(distScore.DistContributions, func(c DistContribution, _ int) float64 {
+		return c.Weight
+	})
+}
+
 func (distScore *DistScore) CalculateDistScore() {
-	distScore.DistScore = weights["distScore"]["dist_impact"]*LogNormalize(distScore.DistImpact, thresholds["distScore"]["dist_impact"]) + weights["distScore"]["dist_pagerank"]*LogNormalize(distScore.DistPageRank, thresholds["distScore"]["dist_pagerank"])
+	distScore.Contributions = metricContributions(CategoryDist, distScore.metricValues())
+	distScore.DistScore = sumContributions(distScore.Contributions)
 }
 
 func (linkScore *LinkScore) CalculateScore() {
-	score := 0.0
+	linkScore.Contributions = []Contribution{
+		currentModel.CategoryContribution(CategoryGit, linkScore.GitMetadataScore.GitMetadataScore),
+		currentModel.CategoryContribution(CategoryLangEco, linkScore.LangEcoScore.LangEcoScore),
+		currentModel.CategoryContribution(CategoryDist, linkScore.DistScore.DistScore),
+	}
+	linkScore.Score = sumContributions(linkScore.Contributions)
+	linkScore.CalculateConfidence()
+}
+
+// Breakdown returns the contributions of all categories and metrics
+func (linkScore *LinkScore) Breakdown() []Contribution {
+	breakdown := make([]Contribution, 0)
+	breakdown = append(breakdown, linkScore.Contributions...)
+	breakdown = append(breakdown, linkScore.GitMetadataScore.Contributions...)
+	breakdown = append(breakdown, linkScore.LangEcoScore.Contributions...)
+	breakdown = append(breakdown, linkScore.DistScore.Contributions...)
+	return breakdown
+}
+
+// CalculateConfidence sets the confidence of the score. Every category
+// counts by the absolute weight of the category in the model. A category
+// without inputs counts 0, otherwise it counts 0.5^(age/ConfidenceHalfLife)
+// of its latest input, and the git category also by the share of git
+// metrics present. Inputs of unknown age count 0 like missing ones, inputs
+// newer than the reference time count 1.
+func (linkScore *LinkScore) CalculateConfidence() {
+	now := AsOf()
+	freshness := func(updateTime time.Time) float64 {
+		if updateTime.IsZero() {
+			return 0
+		}
+		if !updateTime.Before(now) {
+			return 1
+		}
+		return math.Pow(0.5, float64(now.Sub(updateTime))/float64(ConfidenceHalfLife))
+	}
+	latest := func(times []*time.Time) time.Time {
+		var t time.Time
+		for _, u := range times {
+			if u != nil && u.After(t) {
+				t = *u
+			}
+		}
+		return t
+	}
+
+	present := map[string]float64{}
+	if len(linkScore.GitMetadataScore.GitMetrics) > 0 {
+		present[CategoryGit] = linkScore.GitMetadataScore.Completeness * freshness(linkScore.GitMetadataScore.UpdateTime)
+	}
+	if deps := linkScore.DistScore.DistDependencies; len(deps) > 0 {
+		present[CategoryDist] = freshness(latest(lo.Map(deps, func(d *repository.DistDependency, _ int) *time.Time { return d.UpdateTime })))
+	}
+	if ecos := linkScore.LangEcoScore.LangEcosystems; len(ecos) > 0 {
+		present[CategoryLangEco] = freshness(latest(lo.Map(ecos, func(l *repository.LangEcosystem, _ int) *time.Time { return l.UpdateTime })))
+	}
+
+	var confidence, total float64
+	for _, category := range []string{CategoryGit, CategoryDist, CategoryLangEco} {
+		cm, ok := currentModel.Categories[category]
+		if !ok {
+			continue
+		}
+		total += math.Abs(cm.Weight)
+		confidence += math.Abs(cm.Weight) * present[category]
+	}
+	if total == 0 {
+		linkScore.Confidence = 0
+		return
+	}
+	linkScore.Confidence = confidence / total
+}
+
+// ObserveMetrics feeds the metric values of a link to the population
+// normalizers of the current model, such as percentile. It must be called
+// for every link of the round before any score is calculated. nil arguments
+// are skipped.
+func ObserveMetrics(gitMetadata *GitMetadata, distScore *DistScore, langEcoScore *LangEcoScore) {
+	if gitMetadata != nil {
+		observeMetricValues(CategoryGit, gitMetadata.metricValues())
+	}
+	if distScore != nil {
+		observeMetricValues(CategoryDist, distScore.metricValues())
+	}
+	if langEcoScore != nil {
+		observeMetricValues(CategoryLangEco, langEcoScore.metricValues())
+	}
+}
 
-	score += weights["gitMetadataScore"]["gitMetadataScore"] * LogNormalize(linkScore.GitMetadataScore.GitMetadataScore, thresholds["gitMetadataScore"]["gitMetadataScore"]) * 100
+// ObserveCategories feeds the category scores of a link to the population
+// normalizers of the current model. It must be called for every link of the
+// round after the category scores are calculated and before CalculateScore.
+func ObserveCategories(linkScore *LinkScore) {
+	currentModel.Observe(CategoryGit, "", linkScore.GitMetadataScore.GitMetadataScore)
+	currentModel.Observe(CategoryLangEco, "", linkScore.LangEcoScore.LangEcoScore)
+	currentModel.Observe(CategoryDist, "", linkScore.DistScore.DistScore)
+}
 
-	score += weights["langEcoScore"]["langEcoScore"] * LogNormalize(linkScore.LangEcoScore.LangEcoScore, thresholds["langEcoScore"]["langEcoScore"]) * 100
+type metricValue struct {
+	name  string
+	value float64
+}
 
-	score += weights["distScore"]["distScore"] * LogNormalize(linkScore.DistScore.DistScore, thresholds["distScore"]["distScore"]) * 100
+func observeMetricValues(category string, values []metricValue) {
+	for _, v := range values {
+		currentModel.Observe(category, v.name, v.value)
+	}
+}
 
-	linkScore.Score = score
+func metricContributions(category string, values []metricValue) []Contribution {
+	contributions := make([]Contribution, 0, len(values))
+	for _, v := range values {
+		contributions = append(contributions, currentModel.MetricContribution(category, v.name, v.value))
+	}
+	return contributions
+}
+
+func sumContributions(contributions []Contribution) float64 {
+	sum := 0.0
+	for _, c := range contributions {
+		sum += c.Contribution
+	}
+	return sum
 }
 
 func NewGitMetadataScore() *GitMetadataScore {
@@ -261,7 +475,7 @@ func Sigmoid(value, threshold float64) float64 {
 
 func FetchGitMetrics(ac storage.AppDatabaseContext) map[string]*GitMetadata {
 	repo := repository.NewGitMetricsRepository(ac)
-	linksIter, err := repo.Query()
+	linksIter, err := queryAsOf(repo.Query, repo.QueryAsOf)
 	linksMap := make(map[string]*GitMetadata)
 	if err != nil {
 		log.Fatalf("Failed to fetch git links: %v", err)
@@ -277,20 +491,12 @@ func FetchGitMetrics(ac storage.AppDatabaseContext) map[string]*GitMetadata {
 func FetchLangEcoMetadata(ac storage.AppDatabaseContext) map[string]*LangEcoScore {
 	repo := repository.NewLangEcoLinkRepository(ac)
 	LangEcoMap := make(map[string]*LangEcoScore)
-	linksIter, err := repo.Query()
+	linksIter, err := queryAsOf(repo.Query, repo.QueryAsOf)
 	if err != nil {
 		log.Fatalf("Failed to fetch lang eco links: %v", err)
 	}
 	for link := range linksIter {
-		langEcoMetadata := NewLangEcoMetadata()
-		langEcoMetadata.ParseLangEcoMetadata(link)
-		if exists, ok := LangEcoMap[*link.GitLink]; ok && exists != nil {
-			LangEcoMap[*link.GitLink].LangEcosystems = append(LangEcoMap[*link.GitLink].LangEcosystems, link)
-			LangEcoMap[*link.GitLink].LangEcoImpact += langEcoMetadata.LangEcoImpact * PackageWeight[langEcoMetadata.Type]
-			LangEcoMap[*link.GitLink].LangEcoPageRank += langEcoMetadata.LangEcoPageRank * PackageWeight[langEcoMetadata.Type]
-		} else {
-			LangEcoMap[*link.GitLink] = &LangEcoScore{LangEcosystems: []*repository.LangEcosystem{link}, LangEcoImpact: langEcoMetadata.LangEcoImpact * PackageWeight[langEcoMetadata.Type], LangEcoPageRank: langEcoMetadata.LangEcoPageRank * PackageWeight[langEcoMetadata.Type]}
-		}
+		addLangEcosystem(LangEcoMap, link)
 	}
 	return LangEcoMap
 }
@@ -298,24 +504,81 @@ func FetchLangEcoMetadata(ac storage.AppDatabaseContext) map[string]*LangEcoScor
 func FetchDistMetadata(ac storage.AppDatabaseContext) map[string]*DistScore {
 	repo := repository.NewDistDependencyRepository(ac)
 	distMap := make(map[string]*DistScore)
-	linksIter, err := repo.Query()
+	linksIter, err := queryAsOf(repo.Query, repo.QueryAsOf)
 	if err != nil {
 		log.Fatalf("Failed to fetch dist links: %v", err)
 	}
 	for link := range linksIter {
-		distMetadata := NewDistMetadata()
-		distMetadata.PraseDistMetadata(link)
-		coefficient := PackageList[distMetadata.Type] / PackageList[repository.Homebrew]
-		if exists, ok := distMap[*link.GitLink]; ok && exists != nil {
-			distMap[*link.GitLink].DistDependencies = append(distMap[*link.GitLink].DistDependencies, link)
-			distMap[*link.GitLink].DistImpact += float64(coefficient) * distMetadata.DepImpact
-			distMap[*link.GitLink].DistPageRank += float64(coefficient) * distMetadata.PageRank
-		} else {
-			distMap[*link.GitLink] = &DistScore{DistDependencies: []*repository.DistDependency{link}, DistImpact: float64(coefficient) * distMetadata.DepImpact, DistPageRank: float64(coefficient) * distMetadata.PageRank}
-		}
+		addDistDependency(distMap, link)
 	}
 	return distMap
 }
+
+// queryAsOf queries the latest rows, or the rows as of the reference time
+// if one is set.
+func queryAsOf[T any](query func() (iter.Seq[*T], error), queryAsOf func(time.Time) (iter.Seq[*T], error)) (iter.Seq[*T], error) {
+	if asOf.IsZero() {
+		return query()
+	}
+	return queryAsOf(asOf)
+}
+
+// addLangEcosystem adds the weighted impact and pagerank of the ecosystem to
+// the lang ecosystem score of the link.
+func addLangEcosystem(langEcoMap map[string]*LangEcoScore, link *repository.LangEcosystem) {
+	langEcoMetadata := NewLangEcoMetadata()
+	langEcoMetadata.ParseLangEcoMetadata(link)
+	weight := currentModel.EcosystemWeight(langEcoMetadata.Type)
+
+	langEcoScore, ok := langEcoMap[*link.GitLink]
+	if !ok || langEcoScore == nil {
+		langEcoScore = NewLangEcoScore()
+		langEcoMap[*link.GitLink] = langEcoScore
+	}
+	langEcoScore.LangEcosystems = append(langEcoScore.LangEcosystems, link)
+	langEcoScore.LangEcoImpact += langEcoMetadata.LangEcoImpact * weight
+	langEcoScore.LangEcoPageRank += langEcoMetadata.LangEcoPageRank * weight
+}
+
+// addDistDependency adds the weighted impact, pagerank and default install
+// flag of the distribution to the dist score of the link, to the metrics of
+// the kind of dependency of the row.
+func addDistDependency(distMap map[string]*DistScore, link *repository.DistDependency) {
+	distMetadata := NewDistMetadata()
+	distMetadata.PraseDistMetadata(link)
+	weight := currentModel.DistWeight(distMetadata.Type, PackageList)
+
+	distScore, ok := distMap[*link.GitLink]
+	if !ok || distScore == nil {
+		distScore = NewDistScore()
+		distMap[*link.GitLink] = distScore
+	}
+	distScore.DistDependencies = append(distScore.DistDependencies, link)
+	distScore.DistContributions = append(distScore.DistContributions, DistContribution{
+		Type:     distMetadata.Type,
+		Weight:   weight,
+		Impact:   weight * distMetadata.DepImpact,
+		PageRank: weight * distMetadata.PageRank,
+
+		DefaultInstall: lo.Ternary(distMetadata.DefaultInstall, weight, 0),
+		Kind:           distMetadata.Kind,
+	})
+	if distMetadata.Kind != repository.DepKindRuntime {
+		if distScore.KindImpact == nil {
+			distScore.KindImpact = make(map[repository.DepKind]float64)
+			distScore.KindPageRank = make(map[repository.DepKind]float64)
+		}
+		distScore.KindImpact[distMetadata.Kind] += weight * distMetadata.DepImpact
+		distScore.KindPageRank[distMetadata.Kind] += weight * distMetadata.PageRank
+		return
+	}
+	distScore.DistImpact += weight * distMetadata.DepImpact
+	distScore.DistPageRank += weight * distMetadata.PageRank
+	if distMetadata.DefaultInstall {
+		distScore.DistDefaultInstall += weight
+	}
+}
+
 func FetchGitLink(ac storage.AppDatabaseContext) []string {
 	repo := repository.NewAllGitLinkRepository(ac)
 	linksIter, err := repo.Query()
@@ -340,9 +603,12 @@ func UpdatePackageList(ac storage.AppDatabaseContext) {
 	}
 }
 
+// UpdateScore inserts the scores of the links in the default series.
 func UpdateScore(ac storage.AppDatabaseContext, packageScore map[string]*LinkScore) {
 	repo := repository.NewScoreRepository(ac)
 	scores := []*repository.Score{}
+	modelName, modelVersion, modelHash := currentModel.Name, currentModel.Version, currentModel.Hash()
+	series := SeriesDefault
 	for link, linkScore := range packageScore {
 		score := repository.Score{
 			Score:            &linkScore.Score,
@@ -354,6 +620,13 @@ func UpdateScore(ac storage.AppDatabaseContext, packageScore map[string]*LinkSco
 			LangScore:        &linkScore.LangEcoScore.LangEcoScore,
 			GitScore:         &linkScore.GitMetadataScore.GitMetadataScore,
 			Round:            &linkScore.Round,
+			ModelName:        &modelName,
+			ModelVersion:     &modelVersion,
+			ModelHash:        &modelHash,
+			Breakdown:        toScoreBreakdown(linkScore.Breakdown()),
+			DistWeights:      linkScore.DistScore.weights(),
+			Confidence:       &linkScore.Confidence,
+			Series:           &series,
 		}
 		scores = append(scores, &score)
 	}
@@ -362,28 +635,69 @@ func UpdateScore(ac storage.AppDatabaseContext, packageScore map[string]*LinkSco
 	}
 }
 
+// UpdateOSSFScore inserts the scores of the links in the OSSF series. The
+// category scores are left empty, the breakdown has the signal
+// contributions.
+func UpdateOSSFScore(ac storage.AppDatabaseContext, packageScore map[string]*LinkScore) {
+	repo := repository.NewScoreRepository(ac)
+	scores := []*repository.Score{}
+	modelName, modelVersion, modelHash := SeriesOSSF, OSSFVersion, OSSFHash()
+	series := SeriesOSSF
+	for link, linkScore := range packageScore {
+		score := repository.Score{
+			Score:            &linkScore.OSSFScore,
+			GitLink:          &link,
+			DistDependencies: linkScore.DistScore.DistDependencies,
+			GitMetrics:       linkScore.GitMetadataScore.GitMetrics,
+			LangEcosystems:   linkScore.LangEcoScore.LangEcosystems,
+			Round:            &linkScore.Round,
+			ModelName:        &modelName,
+			ModelVersion:     &modelVersion,
+			ModelHash:        &modelHash,
+			Breakdown:        toScoreBreakdown(linkScore.OSSFContributions),
+			Confidence:       &linkScore.Confidence,
+			Series:           &series,
+		}
+		scores = append(scores, &score)
+	}
+	if err := repo.BatchInsertOrUpdate(scores); err != nil {
+		log.Fatalf("Failed to update ossf score: %v", err)
+	}
+}
+
+func toScoreBreakdown(contributions []Contribution) []*repository.ScoreBreakdown {
+	breakdown := make([]*repository.ScoreBreakdown, 0, len(contributions))
+	for _, c := range contributions {
+		breakdown = append(breakdown, &repository.ScoreBreakdown{
+			Category:     lo.ToPtr(c.Category),
+			Metric:       lo.ToPtr(c.Metric),
+			Value:        lo.ToPtr(c.Value),
+			Normalized:   lo.ToPtr(c.Normalized),
+			Weight:       lo.ToPtr(c.Weight),
+			Contribution: lo.ToPtr(c.Contribution),
+		})
+	}
+	return breakdown
+}
+
 func FetchDistMetadataSingle(ac storage.AppDatabaseContext, link string) map[string]*DistScore {
 	repo := repository.NewDistDependencyRepository(ac)
 	linksMap := []*repository.DistDependency{}
 	distMap := make(map[string]*DistScore)
 	for PackageType := range PackageList {
-		distInfo, err := repo.GetByLink(link, int(PackageType))
-		if err != nil {
-			log.Fatalf("Failed to fetch dist links: %v", err)
+		for _, kind := range repository.DepKinds {
+			distInfo, err := repo.GetByLink(link, int(PackageType), kind)
+			if err != nil {
+				log.Fatalf("Failed to fetch dist links: %v", err)
+			}
+			if distInfo == nil {
+				continue
+			}
+			linksMap = append(linksMap, distInfo)
 		}
-		linksMap = append(linksMap, distInfo)
 	}
 	for _, link := range linksMap {
-		distMetadata := NewDistMetadata()
-		distMetadata.PraseDistMetadata(link)
-		coefficient := PackageList[distMetadata.Type] / PackageList[repository.Homebrew]
-		if exists, ok := distMap[*link.GitLink]; ok && exists != nil {
-			distMap[*link.GitLink].DistDependencies = append(distMap[*link.GitLink].DistDependencies, link)
-			distMap[*link.GitLink].DistImpact += float64(coefficient) * distMetadata.DepImpact
-			distMap[*link.GitLink].DistPageRank += float64(coefficient) * distMetadata.PageRank
-		} else {
-			distMap[*link.GitLink] = &DistScore{DistDependencies: []*repository.DistDependency{link}, DistImpact: float64(coefficient) * distMetadata.DepImpact, DistPageRank: float64(coefficient) * distMetadata.PageRank}
-		}
+		addDistDependency(distMap, link)
 	}
 	return distMap
 }
@@ -396,14 +710,7 @@ func FetchLangEcoMetadataSingle(ac storage.AppDatabaseContext, link string) map[
 		log.Fatalf("Failed to fetch lang eco links: %v", err)
 	}
 	for link := range linksIter {
-		langEcoMetadata := NewLangEcoMetadata()
-		langEcoMetadata.ParseLangEcoMetadata(link)
-		if exists, ok := langEcoMap[*link.GitLink]; ok && exists != nil {
-			langEcoMap[*link.GitLink].LangEcosystems = append(langEcoMap[*link.GitLink].LangEcosystems, link)
-			langEcoMap[*link.GitLink].LangEcoImpact += langEcoMetadata.LangEcoImpact
-		} else {
-			langEcoMap[*link.GitLink] = &LangEcoScore{LangEcosystems: []*repository.LangEcosystem{link}, LangEcoImpact: langEcoMetadata.LangEcoImpact}
-		}
+		addLangEcosystem(langEcoMap, link)
 	}
 	return langEcoMap
 }
@@ -428,3 +735,99 @@ func GetRound(ac storage.AppDatabaseContext) int {
 	}
 	return round
 }
+
+// FetchRoundScores returns the final score of each link in the round of
+// the series.
+func FetchRoundScores(ac storage.AppDatabaseContext, series string, round int) map[string]float64 {
+	repo := repository.NewScoreRepository(ac)
+	scoresIter, err := repo.QueryByRound(series, round)
+	if err != nil {
+		log.Fatalf("Failed to fetch scores of round %d: %v", round, err)
+	}
+	roundScores := make(map[string]float64)
+	for score := range scoresIter {
+		if score.GitLink == nil || score.Score == nil {
+			continue
+		}
+		roundScores[*score.GitLink] = *score.Score
+	}
+	return roundScores
+}
+
+// GetRoundSummary returns the summary of the round of the series, or nil if
+// the round has no scores in the series.
+func GetRoundSummary(ac storage.AppDatabaseContext, series string, round int) *repository.ScoreRound {
+	repo := repository.NewScoreRepository(ac)
+	summary, err := repo.GetRoundSummary(series, round)
+	if err != nil {
+		log.Fatalf("Failed to fetch round %d: %v", round, err)
+	}
+	return summary
+}
+
+// FetchChangedLinks returns the links whose git metrics, dist dependencies
+// or lang ecosystems were updated after since.
+func FetchChangedLinks(ac storage.AppDatabaseContext, since time.Time) map[string]bool {
+	repo := repository.NewScoreRepository(ac)
+	linksIter, err := repo.QueryChangedLinks(since)
+	if err != nil {
+		log.Fatalf("Failed to fetch changed links: %v", err)
+	}
+	changed := make(map[string]bool)
+	for link := range linksIter {
+		changed[link] = true
+	}
+	return changed
+}
+
+// CarryForwardScores copies the scores of the series of all links not in
+// exclude from fromRound, calculated as of since, into toRound. Their
+// confidence is aged to the reference time, see ConfidenceDecay.
+func CarryForwardScores(ac storage.AppDatabaseContext, series string, fromRound, toRound int, exclude map[string]bool, since time.Time) int64 {
+	repo := repository.NewScoreRepository(ac)
+	cnt, err := repo.CarryForward(series, fromRound, toRound, lo.Keys(exclude), ConfidenceDecay(since))
+	if err != nil {
+		log.Fatalf("Failed to carry forward scores: %v", err)
+	}
+	return cnt
+}
+
+// ConfidenceDecay returns the factor the confidence of a score calculated
+// as of since is multiplied by when its inputs age to the reference time.
+// Every input ages by the same time, so the freshness of each decays by the
+// same factor, as long as the inputs are not newer than since.
+func ConfidenceDecay(since time.Time) float64 {
+	age := AsOf().Sub(since)
+	if age <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, float64(age)/float64(ConfidenceHalfLife))
+}
+
+// CarriesForward reports whether scores of the series can be carried
+// forward to the next round when their inputs did not change. Trending
+// scores cannot, their window ends at the reference time.
+func CarriesForward(series string) bool {
+	return series != SeriesTrending
+}
+
+// DistWeightsChanged reports whether a distribution has another weight
+// than in the round of the default series, as the package counts changed.
+// The dist scores of all links change with the weights.
+func DistWeightsChanged(ac storage.AppDatabaseContext, round int) bool {
+	repo := repository.NewScoreRepository(ac)
+	weightsIter, err := repo.QueryDistWeights(SeriesDefault, round)
+	if err != nil {
+		log.Fatalf("Failed to fetch dist weights of round %d: %v", round, err)
+	}
+	changed := false
+	for w := range weightsIter {
+		if w.Type == nil || w.Weight == nil {
+			continue
+		}
+		if math.Abs(*w.Weight-currentModel.DistWeight(*w.Type, PackageList)) > 1e-9 {
+			changed = true
+		}
+	}
+	return changed
+}
@@ -2,7 +2,11 @@ package score
 
 import (
 	"math"
+	"slices"
 	"testing"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 )
 
 func TestCalculateDistScore(t *testing.T) {
@@ -11,7 +15,9 @@ func TestCalculateDistScore(t *testing.T) {
 		DistPageRank: 0.5,
 	}
 
-	expectedScore := (weights["distScore"]["dist_impact"] * distScore.DistImpact) + (weights["distScore"]["dist_pagerank"] * distScore.DistPageRank)
+	impact := CurrentModel().Metric(CategoryDist, "dist_impact")
+	pagerank := CurrentModel().Metric(CategoryDist, "dist_pagerank")
+	expectedScore := (impact.Weight * LogNormalize(distScore.DistImpact, impact.Threshold)) + (pagerank.Weight * LogNormalize(distScore.DistPageRank, pagerank.Threshold))
 	distScore.CalculateDistScore()
 
 	if distScore.DistScore != expectedScore {
@@ -30,3 +36,234 @@ func TestLogNormalize(t *testing.T) {
 		t.Errorf("Expected %v, but got %v", expected, actual)
 	}
 }
+
+func TestScoreBreakdown(t *testing.T) {
+	gitScore := NewGitMetadataScore()
+	gitScore.CalculateGitMetadataScore(&GitMetadata{
+		CreatedSince:     time.Now().AddDate(-5, 0, 0),
+		UpdatedSince:     time.Now().AddDate(0, -1, 0),
+		ContributorCount: 100,
+		CommitFrequency:  10,
+		Org_Count:        20,
+	})
+	distScore := &DistScore{DistImpact: 0.5, DistPageRank: 0.1}
+	distScore.CalculateDistScore()
+	langEcoScore := &LangEcoScore{LangEcoImpact: 0.2, LangEcoPageRank: 0.0001}
+	langEcoScore.CalculateLangEcoScore()
+
+	linkScore := NewLinkScore(gitScore, distScore, langEcoScore, 1)
+	linkScore.CalculateScore()
+
+	categorySum := 0.0
+	metricSum := map[string]float64{}
+	for _, c := range linkScore.Breakdown() {
+		if c.Metric == "" {
+			categorySum += c.Contribution
+		} else {
+			metricSum[c.Category] += c.Contribution
+		}
+	}
+
+	if math.Abs(categorySum-linkScore.Score) > 1e-9 {
+		t.Errorf("Expected category contributions to sum to %v, but got %v", linkScore.Score, categorySum)
+	}
+	if math.Abs(metricSum[CategoryGit]-gitScore.GitMetadataScore) > 1e-9 {
+		t.Errorf("Expected git contributions to sum to %v, but got %v", gitScore.GitMetadataScore, metricSum[CategoryGit])
+	}
+	if math.Abs(metricSum[CategoryDist]-distScore.DistScore) > 1e-9 {
+		t.Errorf("Expected dist contributions to sum to %v, but got %v", distScore.DistScore, metricSum[CategoryDist])
+	}
+	if math.Abs(metricSum[CategoryLangEco]-langEcoScore.LangEcoScore) > 1e-9 {
+		t.Errorf("Expected lang eco contributions to sum to %v, but got %v", langEcoScore.LangEcoScore, metricSum[CategoryLangEco])
+	}
+}
+
+func TestAsOf(t *testing.T) {
+	asOf, err := ParseAsOf("2025-02-21")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 2, 21, 23, 59, 59, 999999999, time.UTC); !asOf.Equal(want) {
+		t.Errorf("ParseAsOf = %v, want %v", asOf, want)
+	}
+	if _, err := ParseAsOf("yesterday"); err == nil {
+		t.Error("ParseAsOf(\"yesterday\") succeeded, want error")
+	}
+
+	SetAsOf(asOf)
+	defer SetAsOf(time.Time{})
+
+	gitMetadata := &GitMetadata{
+		CreatedSince:     asOf.AddDate(0, 0, -300),
+		UpdatedSince:     asOf.AddDate(0, 0, -30),
+		ContributorCount: 10,
+		CommitFrequency:  2,
+		Org_Count:        3,
+	}
+	values := gitMetadata.metricValues()
+	if values[0].value != 10 || values[1].value != 1 {
+		t.Errorf("months since creation and update = %v, %v, want 10, 1", values[0].value, values[1].value)
+	}
+
+	a, b := NewGitMetadataScore(), NewGitMetadataScore()
+	a.CalculateGitMetadataScore(gitMetadata)
+	time.Sleep(time.Millisecond)
+	b.CalculateGitMetadataScore(gitMetadata)
+	if a.GitMetadataScore != b.GitMetadataScore {
+		t.Errorf("scores differ with a fixed reference time: %v, %v", a.GitMetadataScore, b.GitMetadataScore)
+	}
+}
+
+func TestConfidence(t *testing.T) {
+	asOf := time.Date(2025, 2, 21, 0, 0, 0, 0, time.UTC)
+	SetAsOf(asOf)
+	defer SetAsOf(time.Time{})
+
+	gitScore := NewGitMetadataScore()
+	gitScore.CalculateGitMetadataScore(&GitMetadata{UpdateTime: asOf, MissingMetrics: 1, Present: true})
+
+	// git only, 4 of 5 metrics present
+	linkScore := NewLinkScore(gitScore, NewDistScore(), NewLangEcoScore(), 1)
+	linkScore.CalculateScore()
+	if want := 0.2 * 0.8; math.Abs(linkScore.Confidence-want) > 1e-9 {
+		t.Errorf("Confidence = %v, want %v", linkScore.Confidence, want)
+	}
+
+	// dist data one half life old
+	old := asOf.Add(-ConfidenceHalfLife)
+	distScore := &DistScore{DistDependencies: []*repository.DistDependency{{UpdateTime: &old}}}
+	linkScore = NewLinkScore(gitScore, distScore, NewLangEcoScore(), 1)
+	linkScore.CalculateScore()
+	if want := 0.2*0.8 + 0.5*0.5; math.Abs(linkScore.Confidence-want) > 1e-9 {
+		t.Errorf("Confidence = %v, want %v", linkScore.Confidence, want)
+	}
+	// no git_metrics row, dist data of unknown age
+	missing := NewGitMetadataScore()
+	missing.CalculateGitMetadataScore(NewGitMetadata())
+	if len(missing.GitMetrics) != 0 {
+		t.Errorf("GitMetrics = %v, want none without git_metrics row", missing.GitMetrics)
+	}
+	distScore = &DistScore{DistDependencies: []*repository.DistDependency{{}}}
+	linkScore = NewLinkScore(missing, distScore, NewLangEcoScore(), 1)
+	linkScore.CalculateScore()
+	if linkScore.Confidence != 0 {
+		t.Errorf("Confidence = %v, want 0 without git metrics and of unknown age", linkScore.Confidence)
+	}
+}
+
+func TestAddDistDependencyDefaultInstall(t *testing.T) {
+	dep := func(typ repository.DistType, defaultInstall *bool) *repository.DistDependency {
+		return &repository.DistDependency{
+			ID:             new(int64),
+			GitLink:        new(string),
+			Type:           &typ,
+			DepImpact:      new(float64),
+			DepCount:       new(int),
+			PageRank:       new(float64),
+			DefaultInstall: defaultInstall,
+		}
+	}
+	defer func(debian, homebrew int) {
+		PackageList[repository.Debian], PackageList[repository.Homebrew] = debian, homebrew
+	}(PackageList[repository.Debian], PackageList[repository.Homebrew])
+	PackageList[repository.Debian], PackageList[repository.Homebrew] = 100, 50
+
+	yes, no := true, false
+	distMap := map[string]*DistScore{}
+	addDistDependency(distMap, dep(repository.Debian, &yes))
+	addDistDependency(distMap, dep(repository.Arch, &no))
+	addDistDependency(distMap, dep(repository.Alpine, nil))
+
+	distScore := distMap[""]
+	want := 2.0
+	if distScore.DistDefaultInstall != want {
+		t.Errorf("DistDefaultInstall = %v, want %v", distScore.DistDefaultInstall, want)
+	}
+	if c := distScore.DistContributions; c[0].DefaultInstall != want || c[1].DefaultInstall != 0 || c[2].DefaultInstall != 0 {
+		t.Errorf("DistContributions = %+v", c)
+	}
+}
+
+func TestAddDistDependencyKinds(t *testing.T) {
+	dep := func(kind repository.DepKind, impact float64) *repository.DistDependency {
+		typ := repository.Debian
+		return &repository.DistDependency{
+			ID:        new(int64),
+			GitLink:   new(string),
+			Type:      &typ,
+			DepImpact: &impact,
+			DepCount:  new(int),
+			PageRank:  new(float64),
+			Kind:      &kind,
+		}
+	}
+	defer func(debian int) { PackageList[repository.Debian] = debian }(PackageList[repository.Debian])
+	PackageList[repository.Debian] = 100
+
+	distMap := map[string]*DistScore{}
+	addDistDependency(distMap, dep(repository.DepKindRuntime, 0.1))
+	addDistDependency(distMap, dep(repository.DepKindBuild, 0.3))
+
+	distScore := distMap[""]
+	weight := distScore.DistContributions[0].Weight
+	if distScore.DistImpact != weight*0.1 {
+		t.Errorf("DistImpact = %v, want only the runtime impact %v", distScore.DistImpact, weight*0.1)
+	}
+	if got := distScore.KindImpact[repository.DepKindBuild]; got != weight*0.3 {
+		t.Errorf("build impact = %v, want %v", got, weight*0.3)
+	}
+
+	names := func() []string {
+		var names []string
+		for _, v := range distScore.metricValues() {
+			names = append(names, v.name)
+		}
+		return names
+	}
+	if got := names(); slices.Contains(got, KindMetric(repository.DepKindBuild, "impact")) {
+		t.Errorf("metrics = %v, the build impact is not in the model", got)
+	}
+
+	m := DefaultModel()
+	m.Categories[CategoryDist].Metrics[KindMetric(repository.DepKindBuild, "impact")] = &MetricModel{Weight: 1, Threshold: 1}
+	old := CurrentModel()
+	SetModel(m)
+	defer SetModel(old)
+	if got := names(); !slices.Contains(got, "dist_build_impact") || slices.Contains(got, "dist_build_pagerank") {
+		t.Errorf("metrics = %v, want dist_build_impact only of the build metrics", got)
+	}
+}
+
+func TestCarriesForward(t *testing.T) {
+	for series, want := range map[string]bool{SeriesDefault: true, SeriesOSSF: true, SeriesTrending: false} {
+		if got := CarriesForward(series); got != want {
+			t.Errorf("CarriesForward(%q) = %v, want %v", series, got, want)
+		}
+	}
+}
+
+func TestConfidenceDecay(t *testing.T) {
+	asOf := time.Date(2025, 2, 21, 0, 0, 0, 0, time.UTC)
+	defer SetAsOf(time.Time{})
+
+	gitUpdate, distUpdate := asOf.AddDate(0, 0, -10), asOf.AddDate(0, 0, -100)
+	confidence := func() float64 {
+		gitScore := NewGitMetadataScore()
+		gitScore.CalculateGitMetadataScore(&GitMetadata{UpdateTime: gitUpdate, MissingMetrics: 1, Present: true})
+		distScore := &DistScore{DistDependencies: []*repository.DistDependency{{UpdateTime: &distUpdate}}}
+		linkScore := NewLinkScore(gitScore, distScore, NewLangEcoScore(), 1)
+		linkScore.CalculateScore()
+		return linkScore.Confidence
+	}
+
+	SetAsOf(asOf)
+	before := confidence()
+	// a carried score is aged by one factor instead of being recomputed
+	SetAsOf(asOf.AddDate(0, 1, 0))
+	if got, want := before*ConfidenceDecay(asOf), confidence(); math.Abs(got-want) > 1e-9 {
+		t.Errorf("decayed confidence = %v, want the recomputed %v", got, want)
+	}
+	if got := ConfidenceDecay(asOf.AddDate(0, 2, 0)); got != 1 {
+		t.Errorf("ConfidenceDecay() of a later time = %v, want 1", got)
+	}
+}
@@ -0,0 +1,489 @@
+package score
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/spf13/viper"
+)
+
+// Category names used in a scoring model file
+const (
+	CategoryGit     = "git"
+	CategoryDist    = "dist"
+	CategoryLangEco = "lang_eco"
+)
+
+// Distribution weighting modes used in a scoring model file
+const (
+	// every distribution weighs 1
+	DistWeightingEqual = "equal"
+	// package count of the distribution divided by the package count of all
+	// distributions
+	DistWeightingPackageShare = "package_share"
+	// package count of the distribution divided by the package count of the
+	// reference distribution
+	DistWeightingRelative = "relative"
+	// weights are listed in the model
+	DistWeightingExplicit = "explicit"
+)
+
+// Model describes how metrics are combined into a score: the weight,
+// threshold and normalization of every metric, grouped by category.
+// The category itself has a weight, threshold and normalization which are
+// applied to the category score when building the final score.
+//
+// A model can be loaded from a yaml or json file with LoadModel, e.g.
+//
+//	name: default
+//	version: "1"
+//	categories:
+//	  git:
+//	    weight: 0.2
+//	    threshold: 5
+//	    metrics:
+//	      contributor_count: {weight: 2, threshold: 40000}
+type Model struct {
+	Name       string                    `mapstructure:"name" json:"name"`
+	Version    string                    `mapstructure:"version" json:"version"`
+	Categories map[string]*CategoryModel `mapstructure:"categories" json:"categories"`
+	// Weight of each language ecosystem when summing lang_ecosystems rows,
+	// keyed by ecosystem name (npm, go, maven, pypi, nuget, cargo)
+	EcosystemWeights map[string]float64 `mapstructure:"ecosystem_weights" json:"ecosystem_weights"`
+	// Weight of each distribution when summing distribution_dependencies rows
+	DistWeighting DistWeightingModel `mapstructure:"dist_weighting" json:"dist_weighting"`
+	// Metrics read from the database with a query, keyed by metric name
+	CustomMetrics map[string]*CustomMetricModel `mapstructure:"custom_metrics" json:"custom_metrics,omitempty"`
+
+	// normalizer of each category and metric, created on first use
+	normalizers map[string]Normalizer
+}
+
+type CategoryModel struct {
+	Weight        float64                 `mapstructure:"weight" json:"weight"`
+	Threshold     float64                 `mapstructure:"threshold" json:"threshold"`
+	Normalization string                  `mapstructure:"normalization" json:"normalization"`
+	Metrics       map[string]*MetricModel `mapstructure:"metrics" json:"metrics"`
+}
+
+type MetricModel struct {
+	Weight        float64 `mapstructure:"weight" json:"weight"`
+	Threshold     float64 `mapstructure:"threshold" json:"threshold"`
+	Normalization string  `mapstructure:"normalization" json:"normalization"`
+}
+
+// DistWeightingModel sets how much each distribution counts in DistImpact
+// and DistPageRank.
+type DistWeightingModel struct {
+	// Empty means relative
+	Mode string `mapstructure:"mode" json:"mode"`
+	// Reference distribution of the relative mode, empty means homebrew
+	Reference string `mapstructure:"reference" json:"reference,omitempty"`
+	// Weights of the explicit mode keyed by distribution name (debian, arch,
+	// homebrew, ...), distributions not listed weigh 0
+	Weights map[string]float64 `mapstructure:"weights" json:"weights,omitempty"`
+}
+
+var distNames = map[string]repository.DistType{
+	"debian":    repository.Debian,
+	"arch":      repository.Arch,
+	"homebrew":  repository.Homebrew,
+	"nix":       repository.Nix,
+	"alpine":    repository.Alpine,
+	"centos":    repository.Centos,
+	"aur":       repository.Aur,
+	"deepin":    repository.Deepin,
+	"fedora":    repository.Fedora,
+	"gentoo":    repository.Gentoo,
+	"ubuntu":    repository.Ubuntu,
+	"rocky":     repository.Rocky,
+	"alma":      repository.Alma,
+	"openeuler": repository.OpenEuler,
+	"opensuse":  repository.OpenSUSE,
+}
+
+// DistName returns the name of the distribution used in a scoring model
+// file, or an empty string for an unknown distribution.
+func DistName(t repository.DistType) string {
+	for name, typ := range distNames {
+		if typ == t {
+			return name
+		}
+	}
+	return ""
+}
+
+var ecosystemNames = map[string]repository.LangEcosystemType{
+	"npm":   repository.Npm,
+	"go":    repository.Go,
+	"maven": repository.Maven,
+	"pypi":  repository.Pypi,
+	"nuget": repository.NuGet,
+	"cargo": repository.Cargo,
+}
+
+// DefaultModel returns the built-in scoring model.
+func DefaultModel() *Model {
+	return &Model{
+		Name:    "default",
+		Version: "1",
+		Categories: map[string]*CategoryModel{
+			CategoryGit: {
+				Weight:    0.2,
+				Threshold: 5,
+				Metrics: map[string]*MetricModel{
+					"created_since":     {Weight: 1, Threshold: 120},
+					"updated_since":     {Weight: -1, Threshold: 120},
+					"contributor_count": {Weight: 2, Threshold: 40000},
+					"commit_frequency":  {Weight: 1, Threshold: 1000},
+					"org_count":         {Weight: 1, Threshold: 8400},
+				},
+			},
+			CategoryDist: {
+				Weight:    0.5,
+				Threshold: 1.5,
+				Metrics: map[string]*MetricModel{
+					"dist_impact":     {Weight: 1, Threshold: 22},
+					"dist_pagerank":   {Weight: 1, Threshold: 3},
+					"default_install": {Weight: 0.5, Threshold: 22},
+				},
+			},
+			CategoryLangEco: {
+				Weight:    0.3,
+				Threshold: 1.3,
+				Metrics: map[string]*MetricModel{
+					"lang_eco_impact":   {Weight: 1, Threshold: 1},
+					"lang_eco_pagerank": {Weight: 1, Threshold: 0.0002},
+				},
+			},
+		},
+		EcosystemWeights: map[string]float64{
+			"npm":   1.5,
+			"go":    1.4,
+			"maven": 1.3,
+			"pypi":  1.2,
+			"nuget": 1.1,
+			"cargo": 1,
+		},
+		DistWeighting: DistWeightingModel{
+			Mode:      DistWeightingRelative,
+			Reference: "homebrew",
+		},
+	}
+}
+
+// LoadModel reads a scoring model from a yaml or json file. The format is
+// detected by the file extension.
+func LoadModel(path string) (*Model, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read model file %s: %w", path, err)
+	}
+
+	m := &Model{}
+	if err := v.Unmarshal(m); err != nil {
+		return nil, fmt.Errorf("failed to parse model file %s: %w", path, err)
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid model file %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Validate checks that the model has a name, declares all categories and
+// uses only known normalizations and ecosystems.
+func (m *Model) Validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("model name is empty")
+	}
+
+	for _, category := range []string{CategoryGit, CategoryDist, CategoryLangEco} {
+		if _, ok := m.Categories[category]; !ok {
+			return fmt.Errorf("category %s is missing", category)
+		}
+	}
+
+	for categoryName, category := range m.Categories {
+		if category == nil {
+			return fmt.Errorf("category %s is empty", categoryName)
+		}
+		if err := validateNormalization(category.Normalization); err != nil {
+			return fmt.Errorf("category %s: %w", categoryName, err)
+		}
+		for metricName, metric := range category.Metrics {
+			if metric == nil {
+				return fmt.Errorf("metric %s.%s is empty", categoryName, metricName)
+			}
+			if err := validateNormalization(metric.Normalization); err != nil {
+				return fmt.Errorf("metric %s.%s: %w", categoryName, metricName, err)
+			}
+		}
+	}
+
+	for name := range m.EcosystemWeights {
+		if _, ok := ecosystemNames[strings.ToLower(name)]; !ok {
+			return fmt.Errorf("unknown ecosystem %s", name)
+		}
+	}
+
+	if err := m.DistWeighting.validate(); err != nil {
+		return fmt.Errorf("dist_weighting: %w", err)
+	}
+
+	for name, custom := range m.CustomMetrics {
+		if custom == nil {
+			return fmt.Errorf("custom metric %s is empty", name)
+		}
+		if err := custom.validate(m, name); err != nil {
+			return fmt.Errorf("custom metric %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (w *DistWeightingModel) validate() error {
+	switch w.Mode {
+	case DistWeightingEqual, DistWeightingPackageShare, DistWeightingExplicit:
+	case "", DistWeightingRelative:
+		if _, ok := distNames[w.reference()]; !ok {
+			return fmt.Errorf("unknown reference distribution %q", w.Reference)
+		}
+	default:
+		return fmt.Errorf("unknown mode %q", w.Mode)
+	}
+	for name := range w.Weights {
+		if _, ok := distNames[strings.ToLower(name)]; !ok {
+			return fmt.Errorf("unknown distribution %s", name)
+		}
+	}
+	return nil
+}
+
+func validateNormalization(name string) error {
+	_, err := NewNormalizer(name)
+	return err
+}
+
+// Hash returns the sha256 of the model content. Two models with the same
+// hash produce the same scores.
+func (m *Model) Hash() string {
+	// map keys are sorted by encoding/json, so the output is stable
+	data, _ := json.Marshal(m)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Metric returns the model of the metric in the category, or nil if the
+// metric is not declared. Custom metrics are declared in their category.
+func (m *Model) Metric(category, metric string) *MetricModel {
+	c, ok := m.Categories[category]
+	if !ok {
+		return nil
+	}
+	if mm, ok := c.Metrics[metric]; ok {
+		return mm
+	}
+	if custom, ok := m.CustomMetrics[metric]; ok && custom.Category == category {
+		return &custom.MetricModel
+	}
+	return nil
+}
+
+// Contribution describes how a raw metric value turns into a part of the
+// score. Metric is empty for the contribution of a whole category to the
+// final score.
+type Contribution struct {
+	Category     string
+	Metric       string
+	Value        float64
+	Normalized   float64
+	Weight       float64
+	Contribution float64
+}
+
+// MetricContribution returns the weighted and normalized value of the
+// metric. Metrics not declared in the model contribute nothing.
+func (m *Model) MetricContribution(category, metric string, value float64) Contribution {
+	c := Contribution{Category: category, Metric: metric, Value: value}
+	mm := m.Metric(category, metric)
+	if mm == nil {
+		return c
+	}
+	c.Normalized = m.normalizer(category, metric, mm.Normalization).Normalize(value, mm.Threshold)
+	c.Weight = mm.Weight
+	c.Contribution = c.Weight * c.Normalized
+	return c
+}
+
+// MetricScore returns the weighted and normalized value of the metric.
+func (m *Model) MetricScore(category, metric string, value float64) float64 {
+	return m.MetricContribution(category, metric, value).Contribution
+}
+
+// CategoryContribution returns the weighted and normalized category score,
+// scaled to its share of 100.
+func (m *Model) CategoryContribution(category string, value float64) Contribution {
+	c := Contribution{Category: category, Value: value}
+	cm, ok := m.Categories[category]
+	if !ok {
+		return c
+	}
+	c.Normalized = m.normalizer(category, "", cm.Normalization).Normalize(value, cm.Threshold)
+	c.Weight = cm.Weight
+	c.Contribution = c.Weight * c.Normalized * 100
+	return c
+}
+
+// CategoryScore returns the weighted and normalized category score, scaled
+// to its share of 100.
+func (m *Model) CategoryScore(category string, value float64) float64 {
+	return m.CategoryContribution(category, value).Contribution
+}
+
+// EcosystemWeight returns the weight of the language ecosystem. Ecosystems
+// not declared in the model weigh 0.
+func (m *Model) EcosystemWeight(t repository.LangEcosystemType) float64 {
+	for name, weight := range m.EcosystemWeights {
+		if typ, ok := ecosystemNames[strings.ToLower(name)]; ok && typ == t {
+			return weight
+		}
+	}
+	return 0
+}
+
+func (w *DistWeightingModel) mode() string {
+	if w.Mode == "" {
+		return DistWeightingRelative
+	}
+	return w.Mode
+}
+
+func (w *DistWeightingModel) reference() string {
+	if w.Reference == "" {
+		return "homebrew"
+	}
+	return strings.ToLower(w.Reference)
+}
+
+// DistWeight returns the weight of the distribution given the package count
+// of every distribution. Weights which cannot be calculated, such as a
+// share of no packages, are 0.
+func (m *Model) DistWeight(t repository.DistType, packageCounts map[repository.DistType]int) float64 {
+	w := m.DistWeighting
+	switch w.mode() {
+	case DistWeightingEqual:
+		return 1
+	case DistWeightingPackageShare:
+		total := 0
+		for _, count := range packageCounts {
+			total += count
+		}
+		if total == 0 {
+			return 0
+		}
+		return float64(packageCounts[t]) / float64(total)
+	case DistWeightingRelative:
+		reference := packageCounts[distNames[w.reference()]]
+		if reference == 0 {
+			return 0
+		}
+		return float64(packageCounts[t]) / float64(reference)
+	case DistWeightingExplicit:
+		for name, weight := range w.Weights {
+			if typ, ok := distNames[strings.ToLower(name)]; ok && typ == t {
+				return weight
+			}
+		}
+	}
+	return 0
+}
+
+// Observe feeds the value of the metric to its normalizer if the normalizer
+// depends on the population of the round. An empty metric means the
+// category score.
+func (m *Model) Observe(category, metric string, value float64) {
+	var normalization string
+	if metric == "" {
+		cm, ok := m.Categories[category]
+		if !ok {
+			return
+		}
+		normalization = cm.Normalization
+	} else {
+		mm := m.Metric(category, metric)
+		if mm == nil {
+			return
+		}
+		normalization = mm.Normalization
+	}
+	if n, ok := m.normalizer(category, metric, normalization).(PopulationNormalizer); ok {
+		n.Observe(value)
+	}
+}
+
+// UsesPopulation reports whether any metric or category of the model is
+// normalized against the population of the round. Scores of such a model
+// change whenever any link of the round changes.
+func (m *Model) UsesPopulation() bool {
+	isPopulation := func(normalization string) bool {
+		n, err := NewNormalizer(normalization)
+		if err != nil {
+			return false
+		}
+		_, ok := n.(PopulationNormalizer)
+		return ok
+	}
+	for _, category := range m.Categories {
+		if isPopulation(category.Normalization) {
+			return true
+		}
+		for _, metric := range category.Metrics {
+			if isPopulation(metric.Normalization) {
+				return true
+			}
+		}
+	}
+	for _, custom := range m.CustomMetrics {
+		if isPopulation(custom.Normalization) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Model) normalizer(category, metric, normalization string) Normalizer {
+	key := category + "." + metric
+	if n, ok := m.normalizers[key]; ok {
+		return n
+	}
+	if m.normalizers == nil {
+		m.normalizers = make(map[string]Normalizer)
+	}
+	n, err := NewNormalizer(normalization)
+	if err != nil {
+		// Validate rejects unknown normalizations, fall back for models
+		// built in code
+		n = LogNormalizer{}
+	}
+	m.normalizers[key] = n
+	return n
+}
+
+var currentModel = DefaultModel()
+
+// SetModel sets the model used by all score calculations in this package.
+func SetModel(m *Model) {
+	currentModel = m
+}
+
+// CurrentModel returns the model used by all score calculations in this
+// package.
+func CurrentModel() *Model {
+	return currentModel
+}
@@ -0,0 +1,99 @@
+package score
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+func TestLoadModel(t *testing.T) {
+	m, err := LoadModel("../../cmd/scores-caculator/default-model.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Hash() != DefaultModel().Hash() {
+		t.Errorf("Expected default-model.yaml to match the built-in model")
+	}
+}
+
+func TestLoadModelInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"no name", `{"categories": {"git": {}, "dist": {}, "lang_eco": {}}}`},
+		{"missing category", `{"name": "m", "categories": {"git": {}, "dist": {}}}`},
+		{"unknown normalization", `{"name": "m", "categories": {"git": {"normalization": "cubic"}, "dist": {}, "lang_eco": {}}}`},
+		{"unknown ecosystem", `{"name": "m", "categories": {"git": {}, "dist": {}, "lang_eco": {}}, "ecosystem_weights": {"cpan": 1}}`},
+		{"custom metric without source", `{"name": "m", "categories": {"git": {}, "dist": {}, "lang_eco": {}}, "custom_metrics": {"stars": {"category": "git"}}}`},
+		{"custom metric in unknown category", `{"name": "m", "categories": {"git": {}, "dist": {}, "lang_eco": {}}, "custom_metrics": {"stars": {"category": "web", "table": "t", "column": "c"}}}`},
+		{"custom metric with invalid column", `{"name": "m", "categories": {"git": {}, "dist": {}, "lang_eco": {}}, "custom_metrics": {"stars": {"category": "git", "table": "t", "column": "c; drop table t"}}}`},
+		{"custom metric shadowing a metric", `{"name": "m", "categories": {"git": {"metrics": {"stars": {}}}, "dist": {}, "lang_eco": {}}, "custom_metrics": {"stars": {"category": "git", "table": "t", "column": "c"}}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "model.json")
+			if err := os.WriteFile(path, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadModel(path); err == nil {
+				t.Errorf("Expected an error for %s", test.name)
+			}
+		})
+	}
+}
+
+func TestModelHash(t *testing.T) {
+	a := DefaultModel()
+	b := DefaultModel()
+	if a.Hash() != b.Hash() {
+		t.Errorf("Expected equal models to have equal hashes")
+	}
+
+	b.Categories[CategoryGit].Metrics["contributor_count"].Weight = 3
+	if a.Hash() == b.Hash() {
+		t.Errorf("Expected different models to have different hashes")
+	}
+}
+
+func TestDistWeight(t *testing.T) {
+	counts := map[repository.DistType]int{
+		repository.Debian:   300,
+		repository.Homebrew: 100,
+		repository.Arch:     100,
+	}
+	tests := []struct {
+		weighting DistWeightingModel
+		want      float64
+	}{
+		{DistWeightingModel{}, 3},
+		{DistWeightingModel{Mode: DistWeightingRelative, Reference: "arch"}, 3},
+		{DistWeightingModel{Mode: DistWeightingEqual}, 1},
+		{DistWeightingModel{Mode: DistWeightingPackageShare}, 0.6},
+		{DistWeightingModel{Mode: DistWeightingExplicit, Weights: map[string]float64{"debian": 2}}, 2},
+		{DistWeightingModel{Mode: DistWeightingExplicit, Weights: map[string]float64{"arch": 2}}, 0},
+	}
+	for _, tt := range tests {
+		m := DefaultModel()
+		m.DistWeighting = tt.weighting
+		if err := m.Validate(); err != nil {
+			t.Fatalf("Validate(%+v) = %v", tt.weighting, err)
+		}
+		if got := m.DistWeight(repository.Debian, counts); got != tt.want {
+			t.Errorf("DistWeight with %+v = %v, want %v", tt.weighting, got, tt.want)
+		}
+	}
+
+	m := DefaultModel()
+	m.DistWeighting.Reference = "homebrew"
+	if got := m.DistWeight(repository.Debian, map[repository.DistType]int{repository.Debian: 10}); got != 0 {
+		t.Errorf("DistWeight without reference packages = %v, want 0", got)
+	}
+	m.DistWeighting = DistWeightingModel{Mode: "unknown"}
+	if err := m.Validate(); err == nil {
+		t.Error("Validate with unknown mode succeeded, want error")
+	}
+}
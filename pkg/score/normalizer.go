@@ -0,0 +1,131 @@
+package score
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Normalization names used in a scoring model file
+const (
+	NormalizationLog        = "log"
+	NormalizationSigmoid    = "sigmoid"
+	NormalizationMinMax     = "minmax"
+	NormalizationPercentile = "percentile"
+	NormalizationLinear     = "linear"
+)
+
+// Normalizer maps a raw metric value to a comparable scale, usually [0, 1].
+type Normalizer interface {
+	Normalize(value, threshold float64) float64
+}
+
+// PopulationNormalizer is a Normalizer which depends on the values of all
+// links scored in the round. Every value must be passed to Observe before
+// the first call to Normalize.
+type PopulationNormalizer interface {
+	Normalizer
+	Observe(value float64)
+}
+
+// NormalizerFactory creates a new normalizer. Every metric of a model gets
+// its own normalizer, so population normalizers do not share state.
+type NormalizerFactory func() Normalizer
+
+var normalizers = map[string]NormalizerFactory{
+	NormalizationLog:        func() Normalizer { return LogNormalizer{} },
+	NormalizationSigmoid:    func() Normalizer { return SigmoidNormalizer{} },
+	NormalizationMinMax:     func() Normalizer { return &MinMaxNormalizer{} },
+	NormalizationPercentile: func() Normalizer { return &PercentileNormalizer{} },
+	NormalizationLinear:     func() Normalizer { return LinearNormalizer{} },
+}
+
+// RegisterNormalizer makes a normalizer available to scoring models under
+// the given name. It replaces any normalizer registered with the same name.
+func RegisterNormalizer(name string, factory NormalizerFactory) {
+	normalizers[name] = factory
+}
+
+// NewNormalizer creates the normalizer registered with the given name. An
+// empty name means the log normalizer.
+func NewNormalizer(name string) (Normalizer, error) {
+	if name == "" {
+		name = NormalizationLog
+	}
+	factory, ok := normalizers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown normalization %s", name)
+	}
+	return factory(), nil
+}
+
+// LogNormalizer scales the value logarithmically against the threshold, see
+// LogNormalize.
+type LogNormalizer struct{}
+
+func (LogNormalizer) Normalize(value, threshold float64) float64 {
+	return LogNormalize(value, threshold)
+}
+
+// SigmoidNormalizer centers a sigmoid curve on the threshold, see Sigmoid.
+type SigmoidNormalizer struct{}
+
+func (SigmoidNormalizer) Normalize(value, threshold float64) float64 {
+	return Sigmoid(value, threshold)
+}
+
+// LinearNormalizer returns the value unchanged. The threshold is ignored.
+type LinearNormalizer struct{}
+
+func (LinearNormalizer) Normalize(value, threshold float64) float64 {
+	return value
+}
+
+// MinMaxNormalizer scales the value linearly between the smallest and the
+// largest value observed in the round. The threshold is ignored.
+type MinMaxNormalizer struct {
+	min, max float64
+	observed bool
+}
+
+func (n *MinMaxNormalizer) Observe(value float64) {
+	if !n.observed {
+		n.min, n.max, n.observed = value, value, true
+		return
+	}
+	n.min = math.Min(n.min, value)
+	n.max = math.Max(n.max, value)
+}
+
+func (n *MinMaxNormalizer) Normalize(value, threshold float64) float64 {
+	if !n.observed || n.max == n.min {
+		return 0
+	}
+	return math.Max(0, math.Min(1, (value-n.min)/(n.max-n.min)))
+}
+
+// PercentileNormalizer returns the percentile rank of the value among the
+// values observed in the round, ties get the mid rank. The threshold is
+// ignored.
+type PercentileNormalizer struct {
+	values []float64
+	sorted bool
+}
+
+func (n *PercentileNormalizer) Observe(value float64) {
+	n.values = append(n.values, value)
+	n.sorted = false
+}
+
+func (n *PercentileNormalizer) Normalize(value, threshold float64) float64 {
+	if len(n.values) == 0 {
+		return 0
+	}
+	if !n.sorted {
+		sort.Float64s(n.values)
+		n.sorted = true
+	}
+	below := sort.SearchFloat64s(n.values, value)
+	notAbove := sort.Search(len(n.values), func(i int) bool { return n.values[i] > value })
+	return (float64(below) + float64(notAbove-below)/2) / float64(len(n.values))
+}
@@ -0,0 +1,75 @@
+package score
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMinMaxNormalizer(t *testing.T) {
+	n := &MinMaxNormalizer{}
+	if got := n.Normalize(5, 0); got != 0 {
+		t.Errorf("Normalize without observations = %v, want 0", got)
+	}
+
+	for _, v := range []float64{10, 2, 6} {
+		n.Observe(v)
+	}
+	tests := []struct {
+		value float64
+		want  float64
+	}{
+		{2, 0},
+		{6, 0.5},
+		{10, 1},
+		{20, 1},
+	}
+	for _, tt := range tests {
+		if got := n.Normalize(tt.value, 0); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Normalize(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPercentileNormalizer(t *testing.T) {
+	n := &PercentileNormalizer{}
+	for _, v := range []float64{4, 1, 3, 3} {
+		n.Observe(v)
+	}
+	tests := []struct {
+		value float64
+		want  float64
+	}{
+		{0, 0},
+		{1, 0.125},
+		{3, 0.5},
+		{4, 0.875},
+		{5, 1},
+	}
+	for _, tt := range tests {
+		if got := n.Normalize(tt.value, 0); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Normalize(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNewNormalizer(t *testing.T) {
+	n, err := NewNormalizer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := n.(LogNormalizer); !ok {
+		t.Errorf("NewNormalizer(\"\") = %T, want LogNormalizer", n)
+	}
+
+	// every metric gets its own population state
+	a, _ := NewNormalizer(NormalizationPercentile)
+	b, _ := NewNormalizer(NormalizationPercentile)
+	a.(PopulationNormalizer).Observe(1)
+	if got := b.Normalize(1, 0); got != 0 {
+		t.Errorf("normalizers share state, got %v", got)
+	}
+
+	if _, err := NewNormalizer("unknown"); err == nil {
+		t.Error("NewNormalizer(\"unknown\") succeeded, want error")
+	}
+}
@@ -0,0 +1,109 @@
+package score
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+)
+
+// Score series. Every round has a score of each link in each series it was
+// calculated for, ranked separately.
+const (
+	// SeriesDefault is scored with the current scoring model
+	SeriesDefault = "default"
+	// SeriesOSSF is scored with the original formula of the OpenSSF
+	// criticality score
+	SeriesOSSF = "ossf"
+)
+
+// AllSeries are the names of all score series
+var AllSeries = []string{SeriesDefault, SeriesOSSF, SeriesTrending}
+
+// CategoryOSSF is the category of the signal contributions of the OSSF
+// series
+const CategoryOSSF = "ossf"
+
+// OSSFVersion is recorded as the model version of the OSSF series
+const OSSFVersion = "1"
+
+// OSSFSignal is a signal of the OSSF formula, see OSSFSignals.
+type OSSFSignal struct {
+	Name      string  `json:"name"`
+	Weight    float64 `json:"weight"`
+	Threshold float64 `json:"threshold"`
+}
+
+// OSSFSignals are the signals of the OSSF criticality score with their
+// original weights and thresholds, limited to the signals we collect. The
+// issue, release and comment signals of the GitHub API are left out, which
+// is the same as giving them a weight of 0. dependents_count is the sum of
+// the dependents of the link in all distributions and language ecosystems.
+var OSSFSignals = []OSSFSignal{
+	{"created_since", 1, 120},
+	{"updated_since", -1, 120},
+	{"contributor_count", 2, 5000},
+	{"org_count", 1, 10},
+	{"commit_frequency", 1, 1000},
+	{"dependents_count", 2, 500000},
+}
+
+// OSSFHash returns the sha256 of OSSFSignals, it is recorded as the model
+// hash of the OSSF series.
+func OSSFHash() string {
+	data, _ := json.Marshal(OSSFSignals)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// OSSFScore scores the signal values with the OSSF formula
+//
+//	sum(weight * log(1 + value) / log(1 + max(value, threshold))) / sum(weight)
+//
+// and returns the score and the contribution of every signal. Missing and
+// negative values count as 0.
+func OSSFScore(values map[string]float64) (float64, []Contribution) {
+	totalWeight := 0.0
+	for _, s := range OSSFSignals {
+		totalWeight += s.Weight
+	}
+
+	contributions := make([]Contribution, 0, len(OSSFSignals))
+	for _, s := range OSSFSignals {
+		value := math.Max(0, values[s.Name])
+		c := Contribution{
+			Category:   CategoryOSSF,
+			Metric:     s.Name,
+			Value:      value,
+			Normalized: LogNormalize(value, s.Threshold),
+			Weight:     s.Weight / totalWeight,
+		}
+		c.Contribution = c.Normalized * c.Weight
+		contributions = append(contributions, c)
+	}
+	return sumContributions(contributions), contributions
+}
+
+// CalculateOSSFScore sets the score of the link in the OSSF series from the
+// git metadata and the dependents in the dist and lang ecosystem scores of
+// the link.
+func (linkScore *LinkScore) CalculateOSSFScore(gitMetadata *GitMetadata) {
+	values := make(map[string]float64)
+	for _, v := range gitMetadata.metricValues() {
+		values[v.name] = v.value
+	}
+	dependents := 0
+	for _, dist := range linkScore.DistScore.DistDependencies {
+		if dist.DepCount != nil && dist.IsRuntime() {
+			dependents += *dist.DepCount
+		}
+	}
+	for _, langEco := range linkScore.LangEcoScore.LangEcosystems {
+		if langEco.DepCount != nil {
+			dependents += *langEco.DepCount
+		}
+	}
+	values["dependents_count"] = float64(dependents)
+
+	linkScore.OSSFScore, linkScore.OSSFContributions = OSSFScore(values)
+}
@@ -0,0 +1,34 @@
+package score
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOSSFScore(t *testing.T) {
+	atThreshold := make(map[string]float64)
+	for _, s := range OSSFSignals {
+		atThreshold[s.Name] = s.Threshold
+	}
+	if score, _ := OSSFScore(atThreshold); math.Abs(score-1) > 1e-9 {
+		t.Errorf("score at all thresholds = %v, want 1", score)
+	}
+	if score, _ := OSSFScore(map[string]float64{}); score != 0 {
+		t.Errorf("score without values = %v, want 0", score)
+	}
+
+	// contributor_count and org_count at their thresholds, weights 2 and 1
+	values := map[string]float64{"contributor_count": 5000, "org_count": 10, "updated_since": -3}
+	score, contributions := OSSFScore(values)
+	if want := 3.0 / 6; math.Abs(score-want) > 1e-9 {
+		t.Errorf("score = %v, want %v", score, want)
+	}
+	if len(contributions) != len(OSSFSignals) {
+		t.Fatalf("got %d contributions, want %d", len(contributions), len(OSSFSignals))
+	}
+	for _, c := range contributions {
+		if c.Metric == "updated_since" && c.Value != 0 {
+			t.Errorf("negative updated_since counts as %v, want 0", c.Value)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package score
+
+import (
+	"iter"
+
+	log "github.com/HUSTSecLab/criticality_score/pkg/logger"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+// Batch holds the inputs of a batch of links
+type Batch struct {
+	// Links sorted, those without git metrics have an empty GitMetadata
+	Links         []string
+	GitMetrics    map[string]*GitMetadata
+	DistScores    map[string]*DistScore
+	LangEcoScores map[string]*LangEcoScore
+	// Values of the custom metrics of the model keyed by link and metric
+	CustomMetrics map[string]map[string]float64
+	// Rows DistScores and LangEcoScores are built from, weighted by the
+	// current model, see weigh
+	distDependencies []*repository.DistDependency
+	langEcosystems   []*repository.LangEcosystem
+}
+
+// FetchBatches reads the inputs of all links in all_gitlinks, batchSize
+// links at a time in link order. Links without git metrics get an empty
+// GitMetadata. Only one batch is held in memory.
+func FetchBatches(ac storage.AppDatabaseContext, batchSize int) iter.Seq[*Batch] {
+	gitRepo := repository.NewGitMetricsRepository(ac)
+	distRepo := repository.NewDistDependencyRepository(ac)
+	langEcoRepo := repository.NewLangEcoLinkRepository(ac)
+
+	return func(yield func(*Batch) bool) {
+		for links := range FetchLinkBatches(ac, batchSize) {
+			batch := &Batch{
+				Links:      links,
+				GitMetrics: make(map[string]*GitMetadata, len(links)),
+			}
+			gitIter, err := gitRepo.QueryByLinks(links, AsOf())
+			if err != nil {
+				log.Fatalf("Failed to fetch git metrics: %v", err)
+			}
+			for gitMetric := range gitIter {
+				gitMetadata := NewGitMetadata()
+				gitMetadata.ParseMetadata(gitMetric)
+				batch.GitMetrics[*gitMetric.GitLink] = gitMetadata
+			}
+			for _, link := range links {
+				if _, ok := batch.GitMetrics[link]; !ok {
+					batch.GitMetrics[link] = NewGitMetadata()
+				}
+			}
+
+			distIter, err := distRepo.QueryByLinks(links, AsOf())
+			if err != nil {
+				log.Fatalf("Failed to fetch dist links: %v", err)
+			}
+			for dist := range distIter {
+				batch.distDependencies = append(batch.distDependencies, dist)
+			}
+
+			langEcoIter, err := langEcoRepo.QueryByLinks(links, AsOf())
+			if err != nil {
+				log.Fatalf("Failed to fetch lang eco links: %v", err)
+			}
+			for langEco := range langEcoIter {
+				batch.langEcosystems = append(batch.langEcosystems, langEco)
+			}
+
+			batch.CustomMetrics = FetchCustomMetrics(ac, links)
+			batch.weigh()
+
+			if !yield(batch) {
+				return
+			}
+		}
+	}
+}
+
+// FetchLinkBatches returns the links of all_gitlinks batchSize at a time
+// in link order.
+func FetchLinkBatches(ac storage.AppDatabaseContext, batchSize int) iter.Seq[[]string] {
+	linkRepo := repository.NewAllGitLinkRepository(ac)
+	return func(yield func([]string) bool) {
+		after := ""
+		for {
+			linksIter, err := linkRepo.QueryBatch(after, batchSize)
+			if err != nil {
+				log.Fatalf("Failed to fetch git links: %v", err)
+			}
+			links := make([]string, 0, batchSize)
+			for link := range linksIter {
+				links = append(links, link)
+			}
+			if len(links) == 0 || !yield(links) || len(links) < batchSize {
+				return
+			}
+			after = links[len(links)-1]
+		}
+	}
+}
+
+// weigh builds DistScores and LangEcoScores with the distribution and
+// ecosystem weights of the current model
+func (batch *Batch) weigh() {
+	batch.DistScores = make(map[string]*DistScore, len(batch.Links))
+	batch.LangEcoScores = make(map[string]*LangEcoScore, len(batch.Links))
+	for _, dist := range batch.distDependencies {
+		addDistDependency(batch.DistScores, dist)
+	}
+	for _, langEco := range batch.langEcosystems {
+		addLangEcosystem(batch.LangEcoScores, langEco)
+	}
+	for _, link := range batch.Links {
+		if _, ok := batch.DistScores[link]; !ok {
+			batch.DistScores[link] = NewDistScore()
+		}
+		if _, ok := batch.LangEcoScores[link]; !ok {
+			batch.LangEcoScores[link] = NewLangEcoScore()
+		}
+	}
+}
+
+// categoryScores calculates the category scores of the links in the batch
+func (batch *Batch) categoryScores(round int) map[string]*LinkScore {
+	linkScores := make(map[string]*LinkScore, len(batch.Links))
+	for _, link := range batch.Links {
+		batch.DistScores[link].CalculateDistScore()
+		batch.LangEcoScores[link].CalculateLangEcoScore()
+		gitMetadataScore := NewGitMetadataScore()
+		gitMetadataScore.CalculateGitMetadataScore(batch.GitMetrics[link])
+		linkScores[link] = NewLinkScore(gitMetadataScore, batch.DistScores[link], batch.LangEcoScores[link], round)
+		linkScores[link].AddCustomMetrics(batch.CustomMetrics[link])
+		linkScores[link].CalculateOSSFScore(batch.GitMetrics[link])
+	}
+	return linkScores
+}
+
+// StreamScores scores all links with the current model,
+// including its custom metrics, and with the OSSF formula. The links are
+// scored batchSize at a time and the scores of every batch are passed to
+// fn. Links for which keep returns false are not scored, keep may be nil.
+//
+// If the model uses population normalizers, the inputs are read three
+// times: to observe the metric values, to observe the category scores of
+// all links, and to score them.
+func StreamScores(ac storage.AppDatabaseContext, batchSize int, round int, keep func(link string) bool, fn func(linkScores map[string]*LinkScore)) {
+	streamScores(ac, batchSize, round, []*Model{currentModel}, keep, func(_ int, linkScores map[string]*LinkScore) {
+		fn(linkScores)
+	})
+}
+
+// StreamModelScores scores all links like StreamScores with each of the
+// models. Every batch is read once and scored with all models, so the
+// scores of two models differ by the models only, not by inputs updated
+// in between. fn gets the scores of every batch and model, model being its
+// index in models. The current model is restored on return.
+func StreamModelScores(ac storage.AppDatabaseContext, batchSize int, round int, models []*Model, fn func(model int, linkScores map[string]*LinkScore)) {
+	defer SetModel(currentModel)
+	streamScores(ac, batchSize, round, models, nil, fn)
+}
+
+func streamScores(ac storage.AppDatabaseContext, batchSize int, round int, models []*Model, keep func(link string) bool, fn func(model int, linkScores map[string]*LinkScore)) {
+	var population []*Model
+	for _, m := range models {
+		if m.UsesPopulation() {
+			population = append(population, m)
+		}
+	}
+	if len(population) > 0 {
+		log.Infof("Observing metric values of all links")
+		for batch := range FetchBatches(ac, batchSize) {
+			for _, m := range population {
+				SetModel(m)
+				batch.weigh()
+				for _, link := range batch.Links {
+					ObserveMetrics(batch.GitMetrics[link], batch.DistScores[link], batch.LangEcoScores[link])
+					ObserveCustomMetrics(batch.CustomMetrics[link])
+				}
+			}
+		}
+		log.Infof("Observing category scores of all links")
+		for batch := range FetchBatches(ac, batchSize) {
+			for _, m := range population {
+				SetModel(m)
+				batch.weigh()
+				for _, linkScore := range batch.categoryScores(round) {
+					ObserveCategories(linkScore)
+				}
+			}
+		}
+	}
+
+	cnt := 0
+	for batch := range FetchBatches(ac, batchSize) {
+		if keep != nil {
+			links := batch.Links[:0]
+			for _, link := range batch.Links {
+				if keep(link) {
+					links = append(links, link)
+				}
+			}
+			batch.Links = links
+		}
+		if len(batch.Links) == 0 {
+			continue
+		}
+
+		var linkScores map[string]*LinkScore
+		for i, m := range models {
+			SetModel(m)
+			batch.weigh()
+			linkScores = batch.categoryScores(round)
+			for _, linkScore := range linkScores {
+				linkScore.CalculateScore()
+			}
+			fn(i, linkScores)
+		}
+
+		cnt += len(linkScores)
+		log.WithFields(map[string]any{"cnt": cnt}).Info("Scored links")
+	}
+}
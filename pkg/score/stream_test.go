@@ -0,0 +1,40 @@
+package score
+
+import (
+	"testing"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+func TestBatchWeigh(t *testing.T) {
+	link, typ, impact, pageRank := "a", repository.Npm, 0.5, 0.1
+	batch := &Batch{
+		Links:      []string{"a", "b"},
+		GitMetrics: map[string]*GitMetadata{"a": NewGitMetadata(), "b": NewGitMetadata()},
+		langEcosystems: []*repository.LangEcosystem{{
+			ID:                new(int64),
+			GitLink:           &link,
+			Type:              &typ,
+			LangEcoImpact:     &impact,
+			Lang_eco_pagerank: &pageRank,
+			DepCount:          new(int),
+		}},
+	}
+
+	old := CurrentModel()
+	defer SetModel(old)
+	// the batch is weighed by each model in turn, as StreamModelScores does
+	for _, weight := range []float64{1, 2} {
+		m := DefaultModel()
+		m.EcosystemWeights = map[string]float64{"npm": weight}
+		SetModel(m)
+		batch.weigh()
+		if got := batch.LangEcoScores["a"]; got.LangEcoImpact != weight*impact || got.LangEcoPageRank != weight*pageRank {
+			t.Errorf("npm weight %v: impact, pagerank = %v, %v, want %v, %v",
+				weight, got.LangEcoImpact, got.LangEcoPageRank, weight*impact, weight*pageRank)
+		}
+		if batch.DistScores["b"] == nil || batch.LangEcoScores["b"] == nil {
+			t.Errorf("link without rows has no scores")
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package score
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+
+	log "github.com/HUSTSecLab/criticality_score/pkg/logger"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
+)
+
+// SeriesTrending is scored with the growth of the metrics of a link, see
+// TrendModel.
+const SeriesTrending = "trending"
+
+// CategoryTrend is the category of the metric contributions of the trending
+// series
+const CategoryTrend = "trend"
+
+// Metrics of the trending series
+const (
+	// growth of contributor_count of the git metrics
+	TrendContributorGrowth = "contributor_growth"
+	// growth of the sum of dep_count over all distributions
+	TrendDistGrowth = "dist_growth"
+	// growth of the sum of dep_count over all language ecosystems
+	TrendLangEcoGrowth = "lang_eco_growth"
+)
+
+// TrendMetric is the weight of the growth of a metric in the trending
+// score. The growth of a metric is the least squares slope of
+// log(1 + value) per month over the window, so doubling every year is a
+// growth of about 0.058 whatever the size of the project.
+type TrendMetric struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+	// Growth per month which counts fully, lower growth counts linearly
+	// less, shrinking counts 0
+	Growth float64 `json:"growth"`
+	// The growth counts by the latest value normalized with LogNormalize
+	// against Level, so a project growing from 1 to 3 contributors does not
+	// outrank one growing from 100 to 300
+	Level float64 `json:"level"`
+}
+
+// TrendModel scores how fast links are becoming critical, the "rising
+// criticality" of a link, from the history of its metrics.
+type TrendModel struct {
+	// Length of the window in months, ending at AsOf
+	Months  int           `json:"months"`
+	Metrics []TrendMetric `json:"metrics"`
+}
+
+// DefaultTrendModel returns the trend model used when none is given.
+func DefaultTrendModel() *TrendModel {
+	doublingPerYear := math.Log(2) / 12
+	return &TrendModel{
+		Months: 6,
+		Metrics: []TrendMetric{
+			{TrendContributorGrowth, 0.3, doublingPerYear, 1000},
+			{TrendDistGrowth, 0.4, doublingPerYear, 1000},
+			{TrendLangEcoGrowth, 0.3, doublingPerYear, 100000},
+		},
+	}
+}
+
+var currentTrendModel = DefaultTrendModel()
+
+// SetTrendModel sets the trend model used by all trend calculations in this
+// package.
+func SetTrendModel(m *TrendModel) {
+	currentTrendModel = m
+}
+
+// CurrentTrendModel returns the trend model used by all trend calculations
+// in this package.
+func CurrentTrendModel() *TrendModel {
+	return currentTrendModel
+}
+
+// Hash returns the sha256 of the trend model, it is recorded as the model
+// hash of the trending series.
+func (m *TrendModel) Hash() string {
+	data, _ := json.Marshal(m)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Window returns the start and the end of the window ending at AsOf.
+func (m *TrendModel) Window() (time.Time, time.Time) {
+	until := AsOf()
+	return until.AddDate(0, -m.Months, 0), until
+}
+
+// Sample is the value of a metric at a time
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// LogSlope returns the least squares slope of log(1 + value) per month of
+// the samples, or 0 if they do not span two distinct times.
+func LogSlope(samples []Sample) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	month := float64(30 * 24 * time.Hour)
+	var meanX, meanY float64
+	for _, s := range samples {
+		meanX += float64(s.Time.Sub(samples[0].Time)) / month
+		meanY += math.Log1p(math.Max(0, s.Value))
+	}
+	meanX /= float64(len(samples))
+	meanY /= float64(len(samples))
+
+	var cov, varX float64
+	for _, s := range samples {
+		dx := float64(s.Time.Sub(samples[0].Time))/month - meanX
+		cov += dx * (math.Log1p(math.Max(0, s.Value)) - meanY)
+		varX += dx * dx
+	}
+	if varX == 0 {
+		return 0
+	}
+	return cov / varX
+}
+
+// SumSamples adds up series of parts of a metric, such as the dep_count of
+// every distribution, into one series. At every time of any part, the sum
+// is the latest value of each part at or before it. Samples before start
+// count at start.
+func SumSamples(parts [][]Sample, start time.Time) []Sample {
+	type point struct {
+		part int
+		Sample
+	}
+	var points []point
+	for i, part := range parts {
+		for _, s := range part {
+			if s.Time.Before(start) {
+				s.Time = start
+			}
+			points = append(points, point{i, s})
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+
+	latest := make([]float64, len(parts))
+	var sum []Sample
+	for i, p := range points {
+		latest[p.part] = p.Value
+		// one sample per time, after all parts at that time
+		if i+1 < len(points) && points[i+1].Time.Equal(p.Time) {
+			continue
+		}
+		sum = append(sum, Sample{p.Time, lo.Sum(latest)})
+	}
+	return sum
+}
+
+// LinkTrend is the history of the metrics of a link in the window and its
+// trending score
+type LinkTrend struct {
+	// Series of each trend metric
+	Samples       map[string][]Sample
+	Score         float64
+	Contributions []Contribution
+	Round         int
+	// Latest inputs of the link, recorded with the score
+	GitMetrics       []*repository.GitMetric
+	DistDependencies []*repository.DistDependency
+	LangEcosystems   []*repository.LangEcosystem
+}
+
+// CalculateTrendScore sets the score of the link in the trending series:
+// 100 times the sum of weight * min(growth / Growth, 1) * level of each
+// metric, where growth is the LogSlope of the metric and level its latest
+// value normalized against Level. Shrinking metrics count 0.
+func (linkTrend *LinkTrend) CalculateTrendScore() {
+	linkTrend.Contributions = make([]Contribution, 0, len(currentTrendModel.Metrics))
+	for _, m := range currentTrendModel.Metrics {
+		samples := linkTrend.Samples[m.Name]
+		c := Contribution{Category: CategoryTrend, Metric: m.Name, Weight: m.Weight}
+		if len(samples) > 0 && m.Growth > 0 {
+			c.Value = LogSlope(samples)
+			level := LogNormalize(math.Max(0, samples[len(samples)-1].Value), m.Level)
+			c.Normalized = math.Max(0, math.Min(c.Value/m.Growth, 1)) * level
+		}
+		c.Contribution = c.Weight * c.Normalized * 100
+		linkTrend.Contributions = append(linkTrend.Contributions, c)
+	}
+	linkTrend.Score = sumContributions(linkTrend.Contributions)
+}
+
+// FetchTrends reads the history of the links in the window of the current
+// trend model and calculates their trending scores.
+func FetchTrends(ac storage.AppDatabaseContext, links []string, round int) map[string]*LinkTrend {
+	since, until := currentTrendModel.Window()
+	trends := make(map[string]*LinkTrend, len(links))
+	for _, link := range links {
+		trends[link] = &LinkTrend{Samples: make(map[string][]Sample), Round: round}
+	}
+
+	gitIter, err := repository.NewGitMetricsRepository(ac).QueryHistoryByLinks(links, since, until)
+	if err != nil {
+		log.Fatalf("Failed to fetch git metrics history: %v", err)
+	}
+	for gitMetric := range gitIter {
+		t := trends[*gitMetric.GitLink]
+		t.GitMetrics = []*repository.GitMetric{{ID: gitMetric.ID}}
+		if gitMetric.ContributorCount == nil || *gitMetric.ContributorCount == nil {
+			continue
+		}
+		t.Samples[TrendContributorGrowth] = append(t.Samples[TrendContributorGrowth], Sample{
+			Time:  maxTime(**gitMetric.UpdateTime, since),
+			Value: float64(**gitMetric.ContributorCount),
+		})
+	}
+
+	distParts := make(map[string]map[repository.DistType][]Sample)
+	distIter, err := repository.NewDistDependencyRepository(ac).QueryHistoryByLinks(links, since, until)
+	if err != nil {
+		log.Fatalf("Failed to fetch dist dependencies history: %v", err)
+	}
+	for dist := range distIter {
+		// growth of the runtime dependents
+		if !dist.IsRuntime() {
+			continue
+		}
+		if distParts[*dist.GitLink] == nil {
+			distParts[*dist.GitLink] = make(map[repository.DistType][]Sample)
+		}
+		distParts[*dist.GitLink][*dist.Type] = append(distParts[*dist.GitLink][*dist.Type], Sample{*dist.UpdateTime, float64(lo.FromPtr(dist.DepCount))})
+		trends[*dist.GitLink].DistDependencies = latestByType(trends[*dist.GitLink].DistDependencies, dist,
+			func(d *repository.DistDependency) bool { return *d.Type == *dist.Type })
+	}
+	for link, parts := range distParts {
+		trends[link].Samples[TrendDistGrowth] = SumSamples(lo.Values(parts), since)
+	}
+
+	langEcoParts := make(map[string]map[repository.LangEcosystemType][]Sample)
+	langEcoIter, err := repository.NewLangEcoLinkRepository(ac).QueryHistoryByLinks(links, since, until)
+	if err != nil {
+		log.Fatalf("Failed to fetch lang ecosystems history: %v", err)
+	}
+	for langEco := range langEcoIter {
+		if langEcoParts[*langEco.GitLink] == nil {
+			langEcoParts[*langEco.GitLink] = make(map[repository.LangEcosystemType][]Sample)
+		}
+		langEcoParts[*langEco.GitLink][*langEco.Type] = append(langEcoParts[*langEco.GitLink][*langEco.Type], Sample{*langEco.UpdateTime, float64(lo.FromPtr(langEco.DepCount))})
+		trends[*langEco.GitLink].LangEcosystems = latestByType(trends[*langEco.GitLink].LangEcosystems, langEco,
+			func(l *repository.LangEcosystem) bool { return *l.Type == *langEco.Type })
+	}
+	for link, parts := range langEcoParts {
+		trends[link].Samples[TrendLangEcoGrowth] = SumSamples(lo.Values(parts), since)
+	}
+
+	for _, t := range trends {
+		t.CalculateTrendScore()
+	}
+	return trends
+}
+
+// latestByType replaces the row of the same type in rows with row, rows are
+// read in update time order so the last one is the latest.
+func latestByType[T any](rows []*T, row *T, sameType func(*T) bool) []*T {
+	rows = lo.Reject(rows, func(r *T, _ int) bool { return sameType(r) })
+	return append(rows, row)
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// UpdateTrendScore inserts the scores of the links in the trending series.
+func UpdateTrendScore(ac storage.AppDatabaseContext, trends map[string]*LinkTrend) {
+	repo := repository.NewScoreRepository(ac)
+	scores := []*repository.Score{}
+	modelName, modelVersion, modelHash := SeriesTrending, "1", currentTrendModel.Hash()
+	series := SeriesTrending
+	for link, trend := range trends {
+		score := repository.Score{
+			Score:            &trend.Score,
+			GitLink:          &link,
+			DistDependencies: trend.DistDependencies,
+			GitMetrics:       trend.GitMetrics,
+			LangEcosystems:   trend.LangEcosystems,
+			Round:            &trend.Round,
+			ModelName:        &modelName,
+			ModelVersion:     &modelVersion,
+			ModelHash:        &modelHash,
+			Breakdown:        toScoreBreakdown(trend.Contributions),
+			Series:           &series,
+		}
+		scores = append(scores, &score)
+	}
+	if err := repo.BatchInsertOrUpdate(scores); err != nil {
+		log.Fatalf("Failed to update trend score: %v", err)
+	}
+}
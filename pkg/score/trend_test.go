@@ -0,0 +1,55 @@
+package score
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLogSlope(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	month := 30 * 24 * time.Hour
+	// doubling of 1 + value every month
+	samples := []Sample{{start, 0}, {start.Add(month), 1}, {start.Add(2 * month), 3}}
+	if got := LogSlope(samples); math.Abs(got-math.Log(2)) > 1e-9 {
+		t.Errorf("LogSlope = %v, want %v", got, math.Log(2))
+	}
+	if got := LogSlope(samples[:1]); got != 0 {
+		t.Errorf("LogSlope of one sample = %v, want 0", got)
+	}
+}
+
+func TestSumSamples(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	parts := [][]Sample{
+		{{start.Add(-day), 10}, {start.Add(2 * day), 20}},
+		{{start.Add(day), 5}},
+	}
+	want := []Sample{{start, 10}, {start.Add(day), 15}, {start.Add(2 * day), 25}}
+	got := SumSamples(parts, start)
+	if len(got) != len(want) {
+		t.Fatalf("SumSamples = %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Time.Equal(want[i].Time) || got[i].Value != want[i].Value {
+			t.Errorf("sample %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCalculateTrendScore(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	year := 12 * 30 * 24 * time.Hour
+	trend := &LinkTrend{Samples: map[string][]Sample{
+		// grows much faster than doubling every year, at the level
+		TrendContributorGrowth: {{start, 9}, {start.Add(year), 999}},
+		// shrinks
+		TrendDistGrowth: {{start, 100}, {start.Add(year), 10}},
+	}}
+	trend.CalculateTrendScore()
+	want := 30 * LogNormalize(999, 1000)
+	if math.Abs(trend.Score-want) > 1e-9 {
+		t.Errorf("score = %v, want %v", trend.Score, want)
+	}
+}
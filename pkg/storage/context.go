@@ -101,3 +101,43 @@ func (app *appDatabaseContext) Close() error {
 	}
 	return nil
 }
+
+// txDatabaseContext runs the statements of an AppDatabaseContext in a
+// transaction
+type txDatabaseContext struct {
+	AppDatabaseContext
+	tx *sql.Tx
+}
+
+// WithTransaction runs fn with a context whose statements are in one
+// transaction, committed if fn returns nil and rolled back otherwise.
+func WithTransaction(ctx AppDatabaseContext, fn func(tx AppDatabaseContext) error) error {
+	if _, ok := ctx.(*txDatabaseContext); ok {
+		return fn(ctx)
+	}
+	conn, err := ctx.GetDatabaseConnection()
+	if err != nil {
+		return err
+	}
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(&txDatabaseContext{AppDatabaseContext: ctx, tx: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (c *txDatabaseContext) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.tx.Exec(query, args...)
+}
+
+func (c *txDatabaseContext) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.tx.Query(query, args...)
+}
+
+func (c *txDatabaseContext) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.tx.QueryRow(query, args...)
+}
@@ -4,12 +4,17 @@ import (
 	"iter"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/lib/pq"
 )
 
 type AllGitLinkRepository interface {
 	/** QUERY **/
 	Query() (iter.Seq[string], error)
 	QueryByLink(search string) (iter.Seq[string], error)
+	// QueryByLinks returns those of the links which are in all_gitlinks
+	QueryByLinks(links []string) (iter.Seq[string], error)
+	// QueryBatch returns at most limit links after the link, in link order.
+	QueryBatch(after string, limit int) (iter.Seq[string], error)
 	QueryCache() (iter.Seq[string], error)
 	MakeCache() error
 }
@@ -23,6 +28,16 @@ func (a *allGitLinkRepository) QueryByLink(search string) (iter.Seq[string], err
 	return gitlinksQuery(a.ctx, "SELECT git_link FROM all_gitlinks WHERE git_link LIKE $1", search)
 }
 
+// QueryByLinks implements AllGitLinkRepository.
+func (a *allGitLinkRepository) QueryByLinks(links []string) (iter.Seq[string], error) {
+	return gitlinksQuery(a.ctx, "SELECT git_link FROM all_gitlinks WHERE git_link = ANY($1)", pq.Array(links))
+}
+
+// QueryBatch implements AllGitLinkRepository.
+func (a *allGitLinkRepository) QueryBatch(after string, limit int) (iter.Seq[string], error) {
+	return gitlinksQuery(a.ctx, "SELECT git_link FROM all_gitlinks WHERE git_link > $1 ORDER BY git_link LIMIT $2", after, limit)
+}
+
 // MakeCache implements AllGitLinkRepository.
 func (a *allGitLinkRepository) MakeCache() error {
 	_, err := a.ctx.Exec(`DROP TABLE IF EXISTS all_gitlinks_cache;
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"iter"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
+)
+
+// CustomMetricValue is the value of a custom metric of a git link
+type CustomMetricValue struct {
+	GitLink *string
+	Value   **float64
+}
+
+type CustomMetricRepository interface {
+	/** QUERY **/
+
+	// QueryByLinks runs the query of a custom metric for the links. The
+	// first column of the query is the git link and the second the value,
+	// values of the same link are summed.
+	QueryByLinks(query string, links []string) (iter.Seq[*CustomMetricValue], error)
+}
+
+type customMetricRepository struct {
+	ctx storage.AppDatabaseContext
+}
+
+var _ CustomMetricRepository = (*customMetricRepository)(nil)
+
+func NewCustomMetricRepository(ctx storage.AppDatabaseContext) CustomMetricRepository {
+	return &customMetricRepository{ctx: ctx}
+}
+
+// QueryByLinks implements CustomMetricRepository.
+func (r *customMetricRepository) QueryByLinks(query string, links []string) (iter.Seq[*CustomMetricValue], error) {
+	return sqlutil.Query[CustomMetricValue](r.ctx, `SELECT git_link, sum(value)::double precision AS value
+	FROM (`+query+`) AS m(git_link, value)
+	WHERE git_link = ANY($1) GROUP BY git_link`, pq.Array(links))
+}
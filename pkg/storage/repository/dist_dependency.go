@@ -6,6 +6,7 @@ import (
 
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
 	"github.com/samber/lo"
 )
 
@@ -15,8 +16,19 @@ type DistDependencyRepository interface {
 	/** QUERY **/
 
 	Query() (iter.Seq[*DistDependency], error) // Query all distribution information.
+	// QueryAsOf returns the latest dependency of each link, distribution
+	// and kind updated at or before asOf.
+	QueryAsOf(asOf time.Time) (iter.Seq[*DistDependency], error)
+	// QueryByLinks returns the latest dependency updated at or before asOf
+	// of each of the links, distribution and kind.
+	QueryByLinks(links []string, asOf time.Time) (iter.Seq[*DistDependency], error)
+	// QueryHistoryByLinks returns the dependencies of the links updated
+	// after since and at or before until, and the latest dependency of each
+	// link, distribution and kind updated at or before since, sorted by link
+	// and update time.
+	QueryHistoryByLinks(links []string, since, until time.Time) (iter.Seq[*DistDependency], error)
 	QueryByType(distType int) (iter.Seq[*DistDependency], error)
-	GetByLink(packageName string, distType int) (*DistDependency, error)
+	GetByLink(packageName string, distType int, kind DepKind) (*DistDependency, error)
 	QueryDistCountByType(distType DistType) (int, error) // Get the total number of packages in a Distro.
 
 	/** INSERT/UPDATE **/
@@ -44,6 +56,10 @@ const (
 	Fedora
 	Gentoo
 	Ubuntu
+	Rocky
+	Alma
+	OpenEuler
+	OpenSUSE
 )
 
 type DistDependency struct {
@@ -54,6 +70,28 @@ type DistDependency struct {
 	DepCount   *int
 	PageRank   *float64
 	UpdateTime *time.Time
+	// Whether a package of the link is installed by default
+	DefaultInstall *bool
+	// Kind of the dependencies the row is computed on, runtime if nil
+	Kind *DepKind
+}
+
+// DepKind is a kind of dependency between distribution packages
+type DepKind string
+
+const (
+	DepKindRuntime  DepKind = "runtime"
+	DepKindBuild    DepKind = "build"
+	DepKindTest     DepKind = "test"
+	DepKindOptional DepKind = "optional"
+)
+
+// DepKinds are all kinds of dependency, runtime first
+var DepKinds = []DepKind{DepKindRuntime, DepKindBuild, DepKindTest, DepKindOptional}
+
+// IsRuntime reports whether the row is computed on the runtime dependencies.
+func (d *DistDependency) IsRuntime() bool {
+	return d.Kind == nil || *d.Kind == DepKindRuntime
 }
 
 func NewDistDependencyRepository(appDb storage.AppDatabaseContext) DistDependencyRepository {
@@ -62,7 +100,29 @@ func NewDistDependencyRepository(appDb storage.AppDatabaseContext) DistDependenc
 
 // Query implements DistributionDependencyRepository.
 func (r *distLinkRepository) Query() (iter.Seq[*DistDependency], error) {
-	return sqlutil.Query[DistDependency](r.ctx, `SELECT DISTINCT ON (git_link, "type") id, git_link, type, dep_impact, dep_count, page_rank, update_time FROM distribution_dependencies ORDER BY git_link, "type", id DESC`)
+	return sqlutil.Query[DistDependency](r.ctx, `SELECT DISTINCT ON (git_link, "type", kind) id, git_link, type, dep_impact, dep_count, page_rank, update_time, default_install, kind FROM distribution_dependencies ORDER BY git_link, "type", kind, id DESC`)
+}
+
+// QueryAsOf implements DistributionDependencyRepository.
+func (r *distLinkRepository) QueryAsOf(asOf time.Time) (iter.Seq[*DistDependency], error) {
+	return sqlutil.Query[DistDependency](r.ctx, `SELECT DISTINCT ON (git_link, "type", kind) id, git_link, type, dep_impact, dep_count, page_rank, update_time, default_install, kind FROM distribution_dependencies WHERE update_time IS NULL OR update_time <= $1 ORDER BY git_link, "type", kind, id DESC`, asOf)
+}
+
+// QueryByLinks implements DistributionDependencyRepository.
+func (r *distLinkRepository) QueryByLinks(links []string, asOf time.Time) (iter.Seq[*DistDependency], error) {
+	return sqlutil.Query[DistDependency](r.ctx, `SELECT DISTINCT ON (git_link, "type", kind) id, git_link, type, dep_impact, dep_count, page_rank, update_time, default_install, kind FROM distribution_dependencies WHERE git_link = ANY($1) AND (update_time IS NULL OR update_time <= $2) ORDER BY git_link, "type", kind, id DESC`, pq.Array(links), asOf)
+}
+
+// QueryHistoryByLinks implements DistributionDependencyRepository.
+func (r *distLinkRepository) QueryHistoryByLinks(links []string, since, until time.Time) (iter.Seq[*DistDependency], error) {
+	return sqlutil.Query[DistDependency](r.ctx, `SELECT * FROM (
+		(SELECT id, git_link, type, dep_impact, dep_count, page_rank, update_time, default_install, kind
+		FROM distribution_dependencies WHERE git_link = ANY($1) AND update_time > $2 AND update_time <= $3)
+		UNION ALL
+		(SELECT DISTINCT ON (git_link, "type", kind) id, git_link, type, dep_impact, dep_count, page_rank, update_time, default_install, kind
+		FROM distribution_dependencies WHERE git_link = ANY($1) AND update_time <= $2
+		ORDER BY git_link, "type", kind, id DESC)) AS t
+		ORDER BY git_link, update_time, id`, pq.Array(links), since, until)
 }
 
 // QueryDistCountByType implements DistributionDependencyRepository.
@@ -91,6 +151,14 @@ func (r *distLinkRepository) QueryDistCountByType(distType DistType) (int, error
 		tableName = "gentoo_packages"
 	case Ubuntu:
 		tableName = "ubuntu_packages"
+	case Rocky:
+		tableName = "rocky_packages"
+	case Alma:
+		tableName = "alma_packages"
+	case OpenEuler:
+		tableName = "openeuler_packages"
+	case OpenSUSE:
+		tableName = "opensuse_packages"
 	default:
 		return 0, ErrInvalidInput
 	}
@@ -102,9 +170,9 @@ func (r *distLinkRepository) QueryDistCountByType(distType DistType) (int, error
 }
 
 // GetByLink implements DistributionDependencyRepository.
-func (r *distLinkRepository) GetByLink(packageName string, distType int) (*DistDependency, error) {
+func (r *distLinkRepository) GetByLink(packageName string, distType int, kind DepKind) (*DistDependency, error) {
 	return sqlutil.QueryCommonFirst[DistDependency](r.ctx, DistDependencyTableName,
-		`WHERE git_link = $1 and type = $2 ORDER BY id DESC`, packageName, distType)
+		`WHERE git_link = $1 and type = $2 and kind = $3 ORDER BY id DESC`, packageName, distType, kind)
 }
 
 // InsertOrUpdate implements DistributionDependencyRepository.
@@ -114,8 +182,11 @@ func (r *distLinkRepository) InsertOrUpdate(packageInfo *DistDependency) error {
 	}
 
 	packageInfo.UpdateTime = lo.ToPtr(time.Now())
+	if packageInfo.Kind == nil {
+		packageInfo.Kind = lo.ToPtr(DepKindRuntime)
+	}
 
-	oldInfo, err := r.GetByLink(*packageInfo.GitLink, int(*packageInfo.Type))
+	oldInfo, err := r.GetByLink(*packageInfo.GitLink, int(*packageInfo.Type), *packageInfo.Kind)
 	if err != nil {
 		return err
 	}
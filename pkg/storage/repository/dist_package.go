@@ -47,6 +47,10 @@ const (
 	DistLinkTablePrefixHomebrew                         = "homebrew"
 	DistLinkTablePrefixNix                              = "nix"
 	DistLinkTablePrefixUbuntu                           = "ubuntu"
+	DistLinkTablePrefixRocky                            = "rocky"
+	DistLinkTablePrefixAlma                             = "alma"
+	DistLinkTablePrefixOpenEuler                        = "openeuler"
+	DistLinkTablePrefixOpenSUSE                         = "opensuse"
 )
 
 type DistPackage struct {
@@ -55,6 +59,8 @@ type DistPackage struct {
 	Description *string
 	Version     *string
 	GitLink     *string
+	// 1 if the package is installed by default, see the collectors
+	DefaultInstall *int
 }
 
 type distPackageRepository struct {
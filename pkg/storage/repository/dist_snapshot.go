@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/samber/lo"
+)
+
+const (
+	DistSnapshotTableName        = "distribution_snapshots"
+	DistSnapshotPackageTableName = "distribution_snapshot_packages"
+)
+
+// DistSnapshotRepository keeps a snapshot of the packages of a distribution
+// for every run of its collector, unlike DistPackageRepository and
+// DistDependencyRepository which keep the latest values.
+type DistSnapshotRepository interface {
+	/** QUERY **/
+
+	GetByID(id int64) (*DistSnapshot, error)
+	// GetLatest returns the latest snapshot of the distribution taken at or
+	// before asOf, nil if there is none.
+	GetLatest(distType DistType, asOf time.Time) (*DistSnapshot, error)
+	// QueryByType returns the snapshots of the distribution, oldest first.
+	QueryByType(distType DistType) (iter.Seq[*DistSnapshot], error)
+	QueryPackages(snapshotID int64) (iter.Seq[*DistSnapshotPackage], error)
+	// QueryAdded returns the packages of the snapshot to missing from the
+	// snapshot from, sorted by name.
+	QueryAdded(from, to int64) (iter.Seq[*DistSnapshotPackage], error)
+	// QueryRemoved returns the packages of the snapshot from missing from
+	// the snapshot to, sorted by name.
+	QueryRemoved(from, to int64) (iter.Seq[*DistSnapshotPackage], error)
+	// QueryDependentHistory returns the dependents of the link in every
+	// snapshot holding a package of it, sorted by distribution and time.
+	QueryDependentHistory(gitLink string) (iter.Seq[*DistDependentHistory], error)
+
+	/** INSERT **/
+
+	// Create inserts the snapshot, setting its ID and create time, and its
+	// packages in one transaction.
+	Create(snapshot *DistSnapshot, packages []*DistSnapshotPackage) error
+}
+
+type distSnapshotRepository struct {
+	ctx storage.AppDatabaseContext
+}
+
+var _ DistSnapshotRepository = (*distSnapshotRepository)(nil)
+
+type DistSnapshot struct {
+	ID   *int64 `generated:"true"`
+	Type *DistType
+	// Locations of the indexes read, one per line
+	Source       *string
+	PackageCount *int
+	CreateTime   *time.Time
+}
+
+// DistSnapshotPackage is a package as it was in a snapshot, its metrics are
+// those of its source package.
+type DistSnapshotPackage struct {
+	SnapshotID     *int64 `column:"snapshot_id"`
+	Package        *string
+	SourcePackage  *string
+	Version        *string
+	GitLink        *string
+	DependsCount   *int
+	PageRank       *float64
+	Impact         *float64
+	DefaultInstall *bool
+}
+
+// DistDependentHistory are the dependents of a link in a snapshot, summed
+// over its source packages like in DistDependency.
+type DistDependentHistory struct {
+	SnapshotID   *int64 `column:"snapshot_id"`
+	Type         *DistType
+	CreateTime   *time.Time
+	GitLink      *string
+	DependsCount *int
+	PageRank     *float64
+	// Number of packages of the link in the snapshot
+	PackageCount *int
+}
+
+func NewDistSnapshotRepository(appDb storage.AppDatabaseContext) DistSnapshotRepository {
+	return &distSnapshotRepository{ctx: appDb}
+}
+
+// GetByID implements DistSnapshotRepository.
+func (r *distSnapshotRepository) GetByID(id int64) (*DistSnapshot, error) {
+	return sqlutil.QueryCommonFirst[DistSnapshot](r.ctx, DistSnapshotTableName, "WHERE id = $1", id)
+}
+
+// GetLatest implements DistSnapshotRepository.
+func (r *distSnapshotRepository) GetLatest(distType DistType, asOf time.Time) (*DistSnapshot, error) {
+	return sqlutil.QueryCommonFirst[DistSnapshot](r.ctx, DistSnapshotTableName,
+		"WHERE type = $1 AND create_time <= $2 ORDER BY create_time DESC, id DESC", distType, asOf)
+}
+
+// QueryByType implements DistSnapshotRepository.
+func (r *distSnapshotRepository) QueryByType(distType DistType) (iter.Seq[*DistSnapshot], error) {
+	return sqlutil.QueryCommon[DistSnapshot](r.ctx, DistSnapshotTableName,
+		"WHERE type = $1 ORDER BY create_time, id", distType)
+}
+
+// QueryPackages implements DistSnapshotRepository.
+func (r *distSnapshotRepository) QueryPackages(snapshotID int64) (iter.Seq[*DistSnapshotPackage], error) {
+	return sqlutil.QueryCommon[DistSnapshotPackage](r.ctx, DistSnapshotPackageTableName,
+		"WHERE snapshot_id = $1 ORDER BY package", snapshotID)
+}
+
+// QueryAdded implements DistSnapshotRepository.
+func (r *distSnapshotRepository) QueryAdded(from, to int64) (iter.Seq[*DistSnapshotPackage], error) {
+	return sqlutil.QueryCommon[DistSnapshotPackage](r.ctx, DistSnapshotPackageTableName+" p",
+		`WHERE p.snapshot_id = $2 AND NOT EXISTS (
+			SELECT 1 FROM distribution_snapshot_packages o WHERE o.snapshot_id = $1 AND o.package = p.package)
+		ORDER BY p.package`, from, to)
+}
+
+// QueryRemoved implements DistSnapshotRepository.
+func (r *distSnapshotRepository) QueryRemoved(from, to int64) (iter.Seq[*DistSnapshotPackage], error) {
+	return r.QueryAdded(to, from)
+}
+
+// QueryDependentHistory implements DistSnapshotRepository.
+func (r *distSnapshotRepository) QueryDependentHistory(gitLink string) (iter.Seq[*DistDependentHistory], error) {
+	// binaries of a source share its values, they count once
+	return sqlutil.Query[DistDependentHistory](r.ctx, `SELECT s.id AS snapshot_id, s.type, s.create_time, $1::text AS git_link,
+		SUM(p.depends_count) FILTER (WHERE p.first) AS depends_count,
+		SUM(p.page_rank) FILTER (WHERE p.first) AS page_rank,
+		COUNT(*) AS package_count
+		FROM distribution_snapshots s JOIN (
+			SELECT snapshot_id, depends_count, page_rank,
+			row_number() OVER (PARTITION BY snapshot_id, source_package ORDER BY package) = 1 AS first
+			FROM distribution_snapshot_packages WHERE git_link = $1) p ON p.snapshot_id = s.id
+		GROUP BY s.id
+		ORDER BY s.type, s.create_time, s.id`, gitLink)
+}
+
+// Create implements DistSnapshotRepository.
+func (r *distSnapshotRepository) Create(snapshot *DistSnapshot, packages []*DistSnapshotPackage) error {
+	if snapshot.Type == nil {
+		return ErrInvalidInput
+	}
+
+	snapshot.ID = nil
+	snapshot.CreateTime = lo.ToPtr(time.Now())
+	if snapshot.PackageCount == nil {
+		snapshot.PackageCount = lo.ToPtr(len(packages))
+	}
+	// the packages go with the snapshot
+	return storage.WithTransaction(r.ctx, func(tx storage.AppDatabaseContext) error {
+		if err := sqlutil.Insert(tx, DistSnapshotTableName, snapshot); err != nil {
+			return err
+		}
+		if len(packages) == 0 {
+			return nil
+		}
+		for _, p := range packages {
+			p.SnapshotID = snapshot.ID
+		}
+		return sqlutil.BatchInsert(tx, DistSnapshotPackageTableName, packages)
+	})
+}
@@ -14,6 +14,16 @@ type GitMetricsRepository interface {
 	/** QUERY **/
 	Query() (iter.Seq[*GitMetric], error)
 	QueryByLink(link string) (*GitMetric, error)
+	// QueryAsOf returns the latest metrics of each link updated at or
+	// before asOf.
+	QueryAsOf(asOf time.Time) (iter.Seq[*GitMetric], error)
+	// QueryByLinks returns the latest metrics of each of the links updated
+	// at or before asOf.
+	QueryByLinks(links []string, asOf time.Time) (iter.Seq[*GitMetric], error)
+	// QueryHistoryByLinks returns the metrics of the links updated after
+	// since and at or before until, and the latest metrics of each link
+	// updated at or before since, sorted by link and update time.
+	QueryHistoryByLinks(links []string, since, until time.Time) (iter.Seq[*GitMetric], error)
 
 	/** INSERT/UPDATE **/
 	// NOTE: update_time will be updated automatically
@@ -84,6 +94,37 @@ func (g *gitmetricsRepository) Query() (iter.Seq[*GitMetric], error) {
 	return sqlutil.QueryCommon[GitMetric](g.ctx, subQuery, "")
 }
 
+// QueryAsOf implements GitMetricsRepository.
+func (g *gitmetricsRepository) QueryAsOf(asOf time.Time) (iter.Seq[*GitMetric], error) {
+	subQuery := fmt.Sprintf(`(SELECT DISTINCT ON (git_link)
+	 *
+	FROM %s
+	WHERE update_time IS NULL OR update_time <= $1
+	ORDER BY git_link, id DESC)`, GitMetricTableName)
+	return sqlutil.QueryCommon[GitMetric](g.ctx, subQuery, "", asOf)
+}
+
+// QueryByLinks implements GitMetricsRepository.
+func (g *gitmetricsRepository) QueryByLinks(links []string, asOf time.Time) (iter.Seq[*GitMetric], error) {
+	subQuery := fmt.Sprintf(`(SELECT DISTINCT ON (git_link)
+	 *
+	FROM %s
+	WHERE git_link = ANY($1) AND (update_time IS NULL OR update_time <= $2)
+	ORDER BY git_link, id DESC)`, GitMetricTableName)
+	return sqlutil.QueryCommon[GitMetric](g.ctx, subQuery, "", pq.Array(links), asOf)
+}
+
+// QueryHistoryByLinks implements GitMetricsRepository.
+func (g *gitmetricsRepository) QueryHistoryByLinks(links []string, since, until time.Time) (iter.Seq[*GitMetric], error) {
+	subQuery := fmt.Sprintf(`((SELECT * FROM %[1]s
+	WHERE git_link = ANY($1) AND update_time > $2 AND update_time <= $3)
+	UNION ALL
+	(SELECT DISTINCT ON (git_link) * FROM %[1]s
+	WHERE git_link = ANY($1) AND update_time <= $2
+	ORDER BY git_link, id DESC))`, GitMetricTableName)
+	return sqlutil.QueryCommon[GitMetric](g.ctx, subQuery, "ORDER BY git_link, update_time, id", pq.Array(links), since, until)
+}
+
 // QueryByLink implements GitMetricsRepository.
 func (g *gitmetricsRepository) QueryByLink(link string) (*GitMetric, error) {
 	return sqlutil.QueryCommonFirst[GitMetric](g.ctx, GitMetricTableName, "WHERE git_link = $1 ORDER BY id DESC", link)
@@ -6,6 +6,7 @@ import (
 
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
 	"github.com/samber/lo"
 )
 
@@ -14,6 +15,16 @@ type LangEcoLinkRepository interface {
 	QueryByLink(link string) (iter.Seq[*LangEcosystem], error)
 	GetByLinkAndType(link string, typ LangEcosystemType) (*LangEcosystem, error)
 	Query() (iter.Seq[*LangEcosystem], error) // Get all LangEcosystem Information in order to calculate the score.
+	// QueryAsOf returns the latest row of each link and ecosystem updated at
+	// or before asOf.
+	QueryAsOf(asOf time.Time) (iter.Seq[*LangEcosystem], error)
+	// QueryByLinks returns the latest row updated at or before asOf of each
+	// of the links and ecosystem.
+	QueryByLinks(links []string, asOf time.Time) (iter.Seq[*LangEcosystem], error)
+	// QueryHistoryByLinks returns the rows of the links updated after since
+	// and at or before until, and the latest row of each link and ecosystem
+	// updated at or before since, sorted by link and update time.
+	QueryHistoryByLinks(links []string, since, until time.Time) (iter.Seq[*LangEcosystem], error)
 
 	/** INSERT/UPDATE **/
 	// NOTE: update_time will be updated automatically
@@ -66,6 +77,35 @@ func (l *langEcoLinkRepository) Query() (iter.Seq[*LangEcosystem], error) {
 		FROM lang_ecosystems ORDER BY git_link, type, id DESC`)
 }
 
+// QueryAsOf implements LangEcoLinkRepository.
+func (l *langEcoLinkRepository) QueryAsOf(asOf time.Time) (iter.Seq[*LangEcosystem], error) {
+	return sqlutil.Query[LangEcosystem](l.appDb, `SELECT DISTINCT ON (git_link, type)
+		id, git_link, type, lang_eco_impact, lang_eco_pagerank, dep_count, update_time
+		FROM lang_ecosystems WHERE update_time IS NULL OR update_time <= $1
+		ORDER BY git_link, type, id DESC`, asOf)
+}
+
+// QueryByLinks implements LangEcoLinkRepository.
+func (l *langEcoLinkRepository) QueryByLinks(links []string, asOf time.Time) (iter.Seq[*LangEcosystem], error) {
+	return sqlutil.Query[LangEcosystem](l.appDb, `SELECT DISTINCT ON (git_link, type)
+		id, git_link, type, lang_eco_impact, lang_eco_pagerank, dep_count, update_time
+		FROM lang_ecosystems WHERE git_link = ANY($1) AND (update_time IS NULL OR update_time <= $2)
+		ORDER BY git_link, type, id DESC`, pq.Array(links), asOf)
+}
+
+// QueryHistoryByLinks implements LangEcoLinkRepository.
+func (l *langEcoLinkRepository) QueryHistoryByLinks(links []string, since, until time.Time) (iter.Seq[*LangEcosystem], error) {
+	return sqlutil.Query[LangEcosystem](l.appDb, `SELECT * FROM (
+		(SELECT id, git_link, type, lang_eco_impact, lang_eco_pagerank, dep_count, update_time
+		FROM lang_ecosystems WHERE git_link = ANY($1) AND update_time > $2 AND update_time <= $3)
+		UNION ALL
+		(SELECT DISTINCT ON (git_link, type)
+		id, git_link, type, lang_eco_impact, lang_eco_pagerank, dep_count, update_time
+		FROM lang_ecosystems WHERE git_link = ANY($1) AND update_time <= $2
+		ORDER BY git_link, type, id DESC)) AS t
+		ORDER BY git_link, update_time, id`, pq.Array(links), since, until)
+}
+
 // BatchInsertOrUpdate implements LangEcoLinkRepository.
 func (l *langEcoLinkRepository) BatchInsertOrUpdate(data []*LangEcosystem) error {
 	for _, d := range data {
@@ -12,14 +12,20 @@ import (
 type ResultRepository interface {
 	/** QUERY **/
 	CountByLink(search string) (int, error)
-	QueryByLink(search string, skip int, take int) (iter.Seq[*Result], error)
-	CountHistoriesByLink(link string) (int, error)
-	QueryHistoriesByLink(link string, skip int, take int) (iter.Seq[*Result], error)
+	// QueryByLink returns the latest score in the series of the links
+	// matching search
+	QueryByLink(series string, search string, skip int, take int) (iter.Seq[*Result], error)
+	CountHistoriesByLink(series string, link string) (int, error)
+	QueryHistoriesByLink(series string, link string, skip int, take int) (iter.Seq[*Result], error)
 	GetByScoreID(scoreID int) (*Result, error)
 	QueryGitDetailsByScoreID(scoreID int) (iter.Seq[*ResultGitDetail], error)
 	QueryLangDetailsByScoreID(scoreID int) (iter.Seq[*ResultLangDetail], error)
 	QueryDistDetailsByScoreID(scoreID int) (iter.Seq[*ResultDistDetail], error)
-	QueryRankingCache(skip int, take int) (iter.Seq[*RankingResult], error)
+	QueryBreakdownByScoreID(scoreID int) (iter.Seq[*ScoreBreakdown], error)
+	QueryRankingCache(series string, skip int, take int) (iter.Seq[*RankingResult], error)
+	// QueryRankingCacheByConfidence only returns scores with a confidence of
+	// at least minConfidence
+	QueryRankingCacheByConfidence(series string, minConfidence float64, skip int, take int) (iter.Seq[*RankingResult], error)
 	MakeRankingCache() error
 }
 
@@ -31,6 +37,8 @@ type Result struct {
 	GitScore   **float64
 	Score      **float64
 	UpdateTime **time.Time
+	Confidence **float64
+	Series     **string
 }
 
 type RankingResult struct {
@@ -41,6 +49,8 @@ type RankingResult struct {
 	GitScore   **float64
 	Score      **float64
 	UpdateTime **time.Time
+	Confidence **float64
+	Series     **string
 	Ranking    *int
 }
 
@@ -68,6 +78,15 @@ type ResultDistDetail struct {
 	Impact     **float64
 	PageRank   **float64
 	UpdateTime **time.Time
+	// Weight of the distribution in the score, and the weighted impact and
+	// pagerank it added to the dist score
+	Weight               **float64
+	ImpactContribution   **float64
+	PageRankContribution **float64
+	// Whether a package of the link is installed by default
+	DefaultInstall **bool
+	// Kind of the dependencies: runtime, build, test or optional
+	Kind **string
 }
 
 type resultRepository struct {
@@ -75,8 +94,14 @@ type resultRepository struct {
 }
 
 // QueryRanking implements ResultRepository.
-func (r *resultRepository) QueryRankingCache(skip int, take int) (iter.Seq[*RankingResult], error) {
-	rows, err := sqlutil.Query[RankingResult](r.ctx, `select * from rankings_cache limit $1 offset $2`, take, skip)
+func (r *resultRepository) QueryRankingCache(series string, skip int, take int) (iter.Seq[*RankingResult], error) {
+	rows, err := sqlutil.Query[RankingResult](r.ctx, `select * from rankings_cache where series = $1 order by ranking limit $2 offset $3`, series, take, skip)
+	return rows, err
+}
+
+// QueryRankingCacheByConfidence implements ResultRepository.
+func (r *resultRepository) QueryRankingCacheByConfidence(series string, minConfidence float64, skip int, take int) (iter.Seq[*RankingResult], error) {
+	rows, err := sqlutil.Query[RankingResult](r.ctx, `select * from rankings_cache where series = $1 and confidence >= $2 order by ranking limit $3 offset $4`, series, minConfidence, take, skip)
 	return rows, err
 }
 
@@ -91,18 +116,20 @@ func (r *resultRepository) MakeRankingCache() error {
 }
 
 // QueryHistoriesByLink implements ResultRepository.
-func (r *resultRepository) QueryHistoriesByLink(link string, skip int, take int) (iter.Seq[*Result], error) {
+func (r *resultRepository) QueryHistoriesByLink(series string, link string, skip int, take int) (iter.Seq[*Result], error) {
 	rows, err := sqlutil.Query[Result](r.ctx, `select ag.git_link as git_link,
 		s.id as score_id,
 		s.dist_score as dist_score,
 		s.lang_score as lang_score,
 		s.git_score as git_score,
 		s.score as score,
-		s.update_time as update_time
+		s.update_time as update_time,
+		s.confidence as confidence,
+		s.series as series
 	from all_gitlinks_cache ag
-	left join scores s on ag.git_link = s.git_link
+	left join scores s on ag.git_link = s.git_link and s.series = $4
 	where ag.git_link = $1 order by s.id desc limit $2 offset $3
-	`, link, take, skip)
+	`, link, take, skip, series)
 	return rows, err
 }
 
@@ -115,8 +142,8 @@ func (r *resultRepository) CountByLink(search string) (int, error) {
 }
 
 // CountHistoriesByLink implements ResultRepository.
-func (r *resultRepository) CountHistoriesByLink(link string) (int, error) {
-	row := r.ctx.QueryRow(`select count(*) from scores where git_link = $1`, link)
+func (r *resultRepository) CountHistoriesByLink(series string, link string) (int, error) {
+	row := r.ctx.QueryRow(`select count(*) from scores where git_link = $1 and series = $2`, link, series)
 	var count int
 	err := row.Scan(&count)
 	return count, err
@@ -129,12 +156,23 @@ func (r *resultRepository) QueryDistDetailsByScoreID(scoreID int) (iter.Seq[*Res
 		dd.dep_count as count,
 		dd.dep_impact as impact,
 		dd.page_rank as page_rank,
-		dd.update_time as update_time
+		dd.update_time as update_time,
+		sd.weight as weight,
+		sd.weight * dd.dep_impact as impact_contribution,
+		sd.weight * dd.page_rank as page_rank_contribution,
+		dd.default_install as default_install,
+		dd.kind as kind
 	from scores_dist sd
 	left join distribution_dependencies dd on sd.distribution_dependencies_id = dd.id
 	where sd.score_id = $1`, scoreID)
 }
 
+// QueryBreakdownByScoreID implements ResultRepository.
+func (r *resultRepository) QueryBreakdownByScoreID(scoreID int) (iter.Seq[*ScoreBreakdown], error) {
+	return sqlutil.QueryCommon[ScoreBreakdown](r.ctx, ScoreBreakdownTableName,
+		`where score_id = $1 order by category, metric`, scoreID)
+}
+
 // QueryGitDetailsByScoreID implements ResultRepository.
 func (r *resultRepository) QueryGitDetailsByScoreID(scoreID int) (iter.Seq[*ResultGitDetail], error) {
 	return sqlutil.Query[ResultGitDetail](r.ctx, `select
@@ -164,7 +202,7 @@ func (r *resultRepository) QueryLangDetailsByScoreID(scoreID int) (iter.Seq[*Res
 }
 
 // QueryWithCountByLink implements ResultRepository.
-func (r *resultRepository) QueryByLink(search string, skip int, take int) (iter.Seq[*Result], error) {
+func (r *resultRepository) QueryByLink(series string, search string, skip int, take int) (iter.Seq[*Result], error) {
 	rows, err := sqlutil.Query[Result](r.ctx, `select * from (
 		select distinct on (ag.git_link)
 			ag.git_link as git_link,
@@ -173,13 +211,15 @@ func (r *resultRepository) QueryByLink(search string, skip int, take int) (iter.
 			s.lang_score as lang_score,
 			s.git_score as git_score,
 			s.score as score,
-			s.update_time as update_time
+			s.update_time as update_time,
+			s.confidence as confidence,
+			s.series as series
 		from all_gitlinks_cache ag
-		left join scores s on ag.git_link = s.git_link
+		left join scores s on ag.git_link = s.git_link and s.series = $4
 		where ag.git_link like $1
 		order by ag.git_link, s.id desc) as t
 	order by score desc nulls last
-	limit $2 offset $3`, "%"+search+"%", take, skip)
+	limit $2 offset $3`, "%"+search+"%", take, skip, series)
 	return rows, err
 }
 
@@ -192,7 +232,9 @@ func (r *resultRepository) GetByScoreID(scoreID int) (*Result, error) {
 		s.lang_score as lang_score,
 		s.git_score as git_score,
 		s.score as score,
-		s.update_time as update_time
+		s.update_time as update_time,
+		s.confidence as confidence,
+		s.series as series
 	from all_gitlinks_cache ag
 	left join scores s on ag.git_link = s.git_link
 	where s.id = $1
@@ -3,11 +3,13 @@ package repository
 import (
 	"fmt"
 	"iter"
+	"strings"
 	"time"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/logger"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
 	"github.com/samber/lo"
 )
 
@@ -16,7 +18,22 @@ type ScoreRepository interface {
 
 	Query() (iter.Seq[*Score], error)
 	GetByGitLink(distID int64) (*Score, error)
+	QueryByRound(series string, round int) (iter.Seq[*Score], error)
+	// GetRound returns the latest round of any series
 	GetRound() (int, error)
+	// GetRoundSummary returns the start time and the model hash of the
+	// round of the series, or nil if the round has no scores in the series.
+	GetRoundSummary(series string, round int) (*ScoreRound, error)
+	// QueryChangedLinks returns the git links whose git metrics, dist
+	// dependencies or lang ecosystems were updated after since.
+	QueryChangedLinks(since time.Time) (iter.Seq[string], error)
+	// QueryDistWeights returns the weights the distributions had in the
+	// round of the series, once per distinct weight of each distribution.
+	QueryDistWeights(series string, round int) (iter.Seq[*ScoreDistWeight], error)
+	// QueryMetricsByRound returns the breakdown rows of the scores of the
+	// round in the series, sorted by git link in byte order. Scores
+	// without breakdown have one row with a nil category.
+	QueryMetricsByRound(series string, round int) (iter.Seq[*ScoreMetric], error)
 
 	/** INSERT/UPDATE **/
 
@@ -25,7 +42,7 @@ type ScoreRepository interface {
 	// update_time will be updated automatically
 	//
 	// NOTE: This function only observe thd id field in DistDependencies,
-	//       LangEcosystems, GitMetrics
+	//       LangEcosystems, GitMetrics. Breakdown is inserted as is.
 	InsertOrUpdate(score *Score) error
 	// When inserting, make sure all score is properly calculated
 	// Any data will not be copied from old data
@@ -34,6 +51,11 @@ type ScoreRepository interface {
 	// NOTE: This function only observe thd id field in DistDependencies,
 	//       LangEcosystems, GitMetrics
 	BatchInsertOrUpdate(scores []*Score) error
+	// CarryForward copies the scores of the series in fromRound, with their
+	// breakdown and inputs, into toRound, their confidence multiplied by
+	// confidenceDecay. Links in exclude are not copied. It returns the
+	// number of copied scores.
+	CarryForward(series string, fromRound, toRound int, exclude []string, confidenceDecay float64) (int64, error)
 }
 
 type Score struct {
@@ -48,12 +70,61 @@ type Score struct {
 	Score            *float64
 	UpdateTime       *time.Time
 	Round            *int
+	// Name, version and content hash of the scoring model used in the round
+	ModelName    *string
+	ModelVersion *string
+	ModelHash    *string
+	Breakdown    []*ScoreBreakdown `ignore:"true"`
+	// Weight of each of DistDependencies in DistScore, in the same order
+	DistWeights []float64 `ignore:"true"`
+	// Share of the inputs present and fresh, in [0, 1]
+	Confidence *float64
+	// Score series, "default" if nil
+	Series *string
+}
+
+// ScoreBreakdown is the contribution of a metric to the score. Metric is
+// empty for the contribution of a whole category.
+type ScoreBreakdown struct {
+	ScoreID      *int64
+	Category     *string
+	Metric       *string
+	Value        *float64
+	Normalized   *float64
+	Weight       *float64
+	Contribution *float64
+}
+
+// ScoreMetric is a breakdown row of a score with the link and the score
+type ScoreMetric struct {
+	GitLink      *string
+	Score        *float64
+	Category     **string
+	Metric       **string
+	Value        **float64
+	Contribution **float64
+}
+
+// ScoreRound summarizes a round of scores
+type ScoreRound struct {
+	Round int
+	// update time of the first score inserted in the round
+	StartTime time.Time
+	// empty if the round was calculated before models were recorded
+	ModelHash string
+}
+
+// ScoreDistWeight is the weight of a distribution in a round
+type ScoreDistWeight struct {
+	Type   *DistType
+	Weight *float64
 }
 
 const ScoreTableName = "scores"
 const ScoreDistTableName = "scores_dist"
 const ScoreLangTableName = "scores_lang"
 const ScoreGitTableName = "scores_git"
+const ScoreBreakdownTableName = "scores_breakdown"
 
 var _ ScoreRepository = (*scoreRepository)(nil)
 
@@ -161,12 +232,16 @@ func (s *scoreRepository) InsertOrUpdate(score *Score) error {
 	id := *pid
 
 	// Insert DistDependencies
-	for _, dist := range score.DistDependencies {
+	for i, dist := range score.DistDependencies {
 		cid := dist.ID
 		if cid == nil {
 			continue
 		}
-		_, err := s.ctx.Exec(`INSERT INTO `+ScoreDistTableName+` (score_id, distribution_dependencies_id) VALUES ($1, $2)`, id, *cid)
+		var weight *float64
+		if i < len(score.DistWeights) {
+			weight = &score.DistWeights[i]
+		}
+		_, err := s.ctx.Exec(`INSERT INTO `+ScoreDistTableName+` (score_id, distribution_dependencies_id, weight) VALUES ($1, $2, $3)`, id, *cid, weight)
 		if err != nil {
 			return err
 		}
@@ -196,6 +271,16 @@ func (s *scoreRepository) InsertOrUpdate(score *Score) error {
 		}
 	}
 
+	// Insert Breakdown
+	if len(score.Breakdown) > 0 {
+		for _, b := range score.Breakdown {
+			b.ScoreID = &id
+		}
+		if err := sqlutil.BatchInsert(s.ctx, ScoreBreakdownTableName, score.Breakdown); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -205,6 +290,11 @@ func (s *scoreRepository) Query() (iter.Seq[*Score], error) {
 	return sqlutil.QueryCommon[Score](s.ctx, subQuery, "")
 }
 
+// QueryByRound implements ScoreRepository.
+func (s *scoreRepository) QueryByRound(series string, round int) (iter.Seq[*Score], error) {
+	return sqlutil.QueryCommon[Score](s.ctx, ScoreTableName, "WHERE series = $1 AND round = $2", series, round)
+}
+
 // GetRound implements ScoreRepository.
 func (s *scoreRepository) GetRound() (int, error) {
 	var result int
@@ -213,6 +303,96 @@ func (s *scoreRepository) GetRound() (int, error) {
 	return result, err
 }
 
+// GetRoundSummary implements ScoreRepository.
+func (s *scoreRepository) GetRoundSummary(series string, round int) (*ScoreRound, error) {
+	var startTime *time.Time
+	var modelHash *string
+	row := s.ctx.QueryRow(`SELECT MIN(update_time), MAX(model_hash) FROM `+ScoreTableName+` WHERE series = $1 AND round = $2`, series, round)
+	if err := row.Scan(&startTime, &modelHash); err != nil {
+		return nil, err
+	}
+	if startTime == nil {
+		return nil, nil
+	}
+	return &ScoreRound{
+		Round:     round,
+		StartTime: *startTime,
+		ModelHash: lo.FromPtr(modelHash),
+	}, nil
+}
+
+// QueryChangedLinks implements ScoreRepository.
+func (s *scoreRepository) QueryChangedLinks(since time.Time) (iter.Seq[string], error) {
+	return gitlinksQuery(s.ctx, `SELECT git_link FROM `+GitMetricTableName+` WHERE update_time > $1
+		UNION SELECT git_link FROM `+DistDependencyTableName+` WHERE update_time > $1
+		UNION SELECT git_link FROM `+LangEcosystemTableName+` WHERE update_time > $1`, since)
+}
+
+// QueryDistWeights implements ScoreRepository.
+func (s *scoreRepository) QueryDistWeights(series string, round int) (iter.Seq[*ScoreDistWeight], error) {
+	return sqlutil.Query[ScoreDistWeight](s.ctx, `SELECT DISTINCT d.type AS type, t.weight AS weight
+	FROM `+ScoreTableName+` s
+	JOIN `+ScoreDistTableName+` t ON t.score_id = s.id
+	JOIN `+DistDependencyTableName+` d ON d.id = t.distribution_dependencies_id
+	WHERE s.series = $1 AND s.round = $2`, series, round)
+}
+
+// QueryMetricsByRound implements ScoreRepository.
+func (s *scoreRepository) QueryMetricsByRound(series string, round int) (iter.Seq[*ScoreMetric], error) {
+	return sqlutil.Query[ScoreMetric](s.ctx, `SELECT s.git_link AS git_link,
+		s.score AS score,
+		b.category AS category,
+		b.metric AS metric,
+		b.value AS value,
+		b.contribution AS contribution
+	FROM `+ScoreTableName+` s
+	LEFT JOIN `+ScoreBreakdownTableName+` b ON b.score_id = s.id
+	WHERE s.series = $1 AND s.round = $2
+	ORDER BY s.git_link COLLATE "C", s.id`, series, round)
+}
+
+// CarryForward implements ScoreRepository.
+func (s *scoreRepository) CarryForward(series string, fromRound, toRound int, exclude []string, confidenceDecay float64) (int64, error) {
+	if exclude == nil {
+		exclude = []string{}
+	}
+
+	result, err := s.ctx.Exec(`INSERT INTO `+ScoreTableName+`
+		(git_link, dist_score, lang_score, git_score, score, update_time, round, model_name, model_version, model_hash, confidence, series)
+		SELECT git_link, dist_score, lang_score, git_score, score, now(), $2, model_name, model_version, model_hash, confidence * $5, series
+		FROM `+ScoreTableName+` WHERE round = $1 AND NOT git_link = ANY($3) AND series = $4`,
+		fromRound, toRound, pq.Array(exclude), series, confidenceDecay)
+	if err != nil {
+		return 0, err
+	}
+	cnt, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	// old and new scores are matched by git link, there is one score per
+	// link in a round of a series
+	copied := `FROM ` + ScoreTableName + ` o
+		JOIN ` + ScoreTableName + ` n ON n.git_link = o.git_link AND n.round = $2 AND n.series = o.series
+		JOIN %s t ON t.score_id = o.id
+		WHERE o.round = $1 AND NOT o.git_link = ANY($3) AND o.series = $4`
+	for table, columns := range map[string]string{
+		ScoreDistTableName:      "distribution_dependencies_id, weight",
+		ScoreLangTableName:      "lang_ecosystems_id",
+		ScoreGitTableName:       "git_metrics_id",
+		ScoreBreakdownTableName: "category, metric, value, normalized, weight, contribution",
+	} {
+		selected := "t." + strings.ReplaceAll(columns, ", ", ", t.")
+		_, err := s.ctx.Exec(`INSERT INTO `+table+` (score_id, `+columns+`)
+			SELECT n.id, `+selected+` `+fmt.Sprintf(copied, table),
+			fromRound, toRound, pq.Array(exclude), series)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return cnt, nil
+}
+
 func NewScoreRepository(appDb storage.AppDatabaseContext) ScoreRepository {
 	return &scoreRepository{
 		ctx: appDb,